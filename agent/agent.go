@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
@@ -13,9 +14,11 @@ import (
 	"github.com/mackerelio/golib/logging"
 	mackerel "github.com/mackerelio/mackerel-client-go"
 
+	"github.com/mackerelio/mackerel-container-agent/api"
 	"github.com/mackerelio/mackerel-container-agent/check"
 	"github.com/mackerelio/mackerel-container-agent/config"
 	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/otlp"
 	"github.com/mackerelio/mackerel-container-agent/spec"
 )
 
@@ -35,13 +38,20 @@ type agent struct {
 	version, revision string
 }
 
+type startResult struct {
+	retire func(config.RetireOnExit)
+	err    error
+}
+
 func (a *agent) Run(_ []string) error {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGHUP)
-	retires := make([]func(), 0, 1)
+	var policy config.RetireOnExit
+	retires := make([]func(config.RetireOnExit), 0, 1)
 	defer func() {
+		// the policy of the latest config is applied to the hosts of the reloaded runs as well
 		for _, retire := range retires {
-			retire()
+			retire(policy)
 		}
 	}()
 	confLoader, err := createConfLoader()
@@ -55,23 +65,27 @@ func (a *agent) Run(_ []string) error {
 		if err != nil {
 			return err
 		}
-		errCh := make(chan error)
+		policy = conf.RetireOnExit
+		resultCh := make(chan startResult, 1)
 		go func() {
 			retire, err := a.start(ctx, conf)
-			if retire != nil {
-				retires = append(retires, retire)
-			}
-			errCh <- err
+			resultCh <- startResult{retire, err}
 		}()
 		confCh := confLoader.Start(ctx)
 		select {
 		case sig := <-sigCh:
 			logger.Infof("reload config: signal = %s", sig)
-			cancel()
 		case <-confCh:
-			cancel()
-		case err := <-errCh:
-			return err
+		case res := <-resultCh:
+			if res.retire != nil {
+				retires = append(retires, res.retire)
+			}
+			return res.err
+		}
+		// wait for the current run to stop before starting the next one
+		cancel()
+		if res := <-resultCh; res.retire != nil {
+			retires = append(retires, res.retire)
 		}
 	}
 }
@@ -90,22 +104,27 @@ func createConfLoader() (*config.Loader, error) {
 	return config.NewLoader(os.Getenv("MACKEREL_AGENT_CONFIG"), pollingDuration), nil
 }
 
-func (a *agent) start(ctx context.Context, conf *config.Config) (func(), error) {
+func (a *agent) start(ctx context.Context, conf *config.Config) (func(config.RetireOnExit), error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	client := mackerel.NewClient(conf.Apikey)
+	mackerelClient := mackerel.NewClient(conf.Apikey)
 	if conf.Apibase != "" {
 		baseURL, err := url.Parse(conf.Apibase)
 		if err != nil {
 			return nil, err
 		}
-		client.BaseURL = baseURL
+		mackerelClient.BaseURL = baseURL
 	}
-	client.UserAgent = spec.BuildUserAgent(a.version, a.revision)
+	mackerelClient.UserAgent = spec.BuildUserAgent(a.version, a.revision)
+	// wrap the transport keeping the timeout of the client, which may be shared
+	httpClient := *mackerelClient.HTTPClient
+	httpClient.Transport = api.NewTransport(httpClient.Transport)
+	mackerelClient.HTTPClient = &httpClient
 	if conf.ReadinessProbe != nil && conf.ReadinessProbe.HTTP != nil {
-		conf.ReadinessProbe.HTTP.UserAgent = client.UserAgent
+		conf.ReadinessProbe.HTTP.UserAgent = mackerelClient.UserAgent
 	}
+	client := api.NewBreakerClient(mackerelClient, api.NewBreaker())
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
@@ -124,7 +143,7 @@ func (a *agent) start(ctx context.Context, conf *config.Config) (func(), error)
 		}
 	}()
 
-	pform, err := NewPlatform(ctx, conf.IgnoreContainer.Regexp)
+	pform, err := NewPlatform(ctx, conf)
 	if err != nil {
 		return nil, err
 	}
@@ -136,14 +155,69 @@ func (a *agent) start(ctx context.Context, conf *config.Config) (func(), error)
 
 	metricGenerators := pform.GetMetricGenerators()
 	for _, mp := range conf.MetricPlugins {
+		if mp.Mode == config.PluginModeStream {
+			g := metric.NewStreamPluginGenerator(mp)
+			g.Start(ctx)
+			defer g.Shutdown()
+			metricGenerators = append(metricGenerators, g)
+			continue
+		}
 		metricGenerators = append(metricGenerators, metric.NewPluginGenerator(mp))
 	}
-	metricManager := metric.NewManager(metricGenerators, client)
+	for _, lm := range conf.LogMetrics {
+		g := metric.NewLogGenerator(lm)
+		g.Start(ctx)
+		defer g.Shutdown()
+		metricGenerators = append(metricGenerators, metric.NewServiceGenerator(lm.Service, g))
+	}
+	for _, hm := range conf.HTTPMetrics {
+		g, err := metric.NewHTTPGenerator(hm)
+		if err != nil {
+			return nil, err
+		}
+		metricGenerators = append(metricGenerators, metric.NewServiceGenerator(hm.Service, g))
+	}
+	if conf.OTLPReceiver != nil {
+		receiver := otlp.NewReceiver(conf.OTLPReceiver)
+		if err := receiver.Start(); err != nil {
+			return nil, err
+		}
+		defer receiver.Shutdown()
+		metricGenerators = append(metricGenerators, receiver)
+	}
+
+	var outputs []metric.Output
+	for _, o := range conf.Outputs {
+		output, err := metric.NewOutput(o)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	var derivedMetrics []*metric.DerivedMetric
+	for _, m := range conf.DerivedMetrics {
+		derivedMetric, err := metric.NewDerivedMetric(m)
+		if err != nil {
+			return nil, err
+		}
+		derivedMetrics = append(derivedMetrics, derivedMetric)
+	}
+	metricManager := metric.NewManager(metricGenerators, client).
+		WithOutputs(outputs).
+		WithDerivedMetrics(derivedMetrics).
+		WithFilters(conf.MetricFilters).
+		WithAlignment(time.Duration(conf.MetricsJitterSeconds) * time.Second)
 
-	var checkGenerators []check.Generator
+	checkGenerators := pform.GetCheckGenerators()
 	for _, cp := range conf.CheckPlugins {
 		checkGenerators = append(checkGenerators, check.NewPluginGenerator(cp))
 	}
+	for _, lc := range conf.LogChecks {
+		checkGenerators = append(checkGenerators, check.NewLogGenerator(lc, filepath.Join(conf.Root, "logcheck")))
+	}
+	for _, cc := range conf.CertChecks {
+		checkGenerators = append(checkGenerators, check.NewCertGenerator(cc))
+	}
 	checkManager := check.NewManager(checkGenerators, client)
 
 	specGenerators := pform.GetSpecGenerators()
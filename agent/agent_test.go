@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -67,15 +68,24 @@ func createMockSpecGenerators() []spec.Generator {
 	}
 }
 
-type mockPlatform struct{}
+type mockPlatform struct {
+	stopping      bool
+	stoppingCalls atomic.Int32
+}
 
 func (p *mockPlatform) GetMetricGenerators() []metric.Generator             { return nil }
 func (p *mockPlatform) GetSpecGenerators() []spec.Generator                 { return nil }
+func (p *mockPlatform) GetCheckGenerators() []check.Generator               { return nil }
 func (p *mockPlatform) GetCustomIdentifier(context.Context) (string, error) { return "", nil }
 func (p *mockPlatform) StatusRunning(context.Context) bool                  { return true }
+func (p *mockPlatform) StatusStopping(context.Context) bool {
+	p.stoppingCalls.Add(1)
+	return p.stopping
+}
 
 func init() {
 	metricsInterval = 200 * time.Millisecond
+	graphDefsInterval = 300 * time.Millisecond
 	checkInterval = 200 * time.Millisecond
 	specInterval = 500 * time.Millisecond
 	specInitialInterval = 600 * time.Millisecond
@@ -256,12 +266,126 @@ func TestAgentRun_Retire(t *testing.T) {
 	if err != nil {
 		t.Errorf("err should be nil but got: %+v", err)
 	}
-	retire()
+	retire(config.RetireOnExitAlways)
 	if !retired {
 		t.Errorf("host should be retired")
 	}
 }
 
+func TestAgentRun_RetireOnExit(t *testing.T) {
+	tests := []struct {
+		policy   config.RetireOnExit
+		stopping bool
+		expect   bool
+	}{
+		{"", false, true},
+		{config.RetireOnExitAlways, false, true},
+		{config.RetireOnExitNever, true, false},
+		{config.RetireOnExitStopping, true, true},
+		{config.RetireOnExitStopping, false, false},
+	}
+
+	for _, tc := range tests {
+		dir := t.TempDir()
+		conf := &config.Config{Root: dir}
+		hostID := "abcde"
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var retired bool
+		client := api.NewMockClient(
+			api.MockCreateHost(func(param *mackerel.CreateHostParam) (string, error) {
+				return hostID, nil
+			}),
+			api.MockFindHost(func(id string) (*mackerel.Host, error) {
+				return &mackerel.Host{ID: id}, nil
+			}),
+			api.MockRetireHost(func(id string) error {
+				retired = true
+				return nil
+			}),
+		)
+		metricManager := metric.NewManager(createMockMetricGenerators(), client)
+		checkManager := check.NewManager(createMockCheckGenerators(), client)
+		specManager := spec.NewManager(createMockSpecGenerators(), client)
+
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			cancel()
+		}()
+		retire, err := run(ctx, client, metricManager, checkManager, specManager, &mockPlatform{stopping: tc.stopping}, conf)
+		if err != nil {
+			t.Errorf("err should be nil but got: %+v", err)
+		}
+		retire(tc.policy)
+		if retired != tc.expect {
+			t.Errorf("host should be retired = %t with retireOnExit = %q and stopping = %t", tc.expect, tc.policy, tc.stopping)
+		}
+		cancel()
+	}
+}
+
+func TestAgentRun_RetireOnExit_StatusStopping(t *testing.T) {
+	conf := &config.Config{
+		Root:                 t.TempDir(),
+		HostStatusOnStopping: mackerel.HostStatusMaintenance,
+		RetireOnExit:         config.RetireOnExitStopping,
+	}
+	hostID := "abcde"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var retired bool
+	var postedStatuses []string
+	client := api.NewMockClient(
+		api.MockCreateHost(func(param *mackerel.CreateHostParam) (string, error) {
+			return hostID, nil
+		}),
+		api.MockFindHost(func(id string) (*mackerel.Host, error) {
+			return &mackerel.Host{ID: id}, nil
+		}),
+		api.MockUpdateHostStatus(func(id string, status string) error {
+			postedStatuses = append(postedStatuses, status)
+			return nil
+		}),
+		api.MockRetireHost(func(id string) error {
+			retired = true
+			return nil
+		}),
+	)
+	metricManager := metric.NewManager(createMockMetricGenerators(), client)
+	checkManager := check.NewManager(createMockCheckGenerators(), client)
+	specManager := spec.NewManager(createMockSpecGenerators(), client)
+	pform := &mockPlatform{}
+	retire, err := run(ctx, client, metricManager, checkManager, specManager, pform, conf)
+	if err != nil {
+		t.Errorf("err should be nil but got: %+v", err)
+	}
+
+	// the platform starts stopping after the last poll
+	pform.stopping = true
+	pform.stoppingCalls.Store(0)
+	retire(conf.RetireOnExit)
+	if calls := pform.stoppingCalls.Load(); calls != 1 {
+		t.Errorf("stopping status should be queried once on exit but got %d", calls)
+	}
+	if expected := []string{"maintenance"}; !reflect.DeepEqual(postedStatuses, expected) {
+		t.Errorf("posted host statuses should be %v but got: %v", expected, postedStatuses)
+	}
+	if !retired {
+		t.Errorf("host should be retired")
+	}
+}
+
+func TestNewPlatform_RetireOnExitStopping(t *testing.T) {
+	t.Setenv("MACKEREL_CONTAINER_PLATFORM", "none")
+	if _, err := NewPlatform(context.Background(), &config.Config{RetireOnExit: config.RetireOnExitStopping}); err == nil {
+		t.Errorf("should raise error for retireOnExit: stopping on none platform")
+	}
+	if _, err := NewPlatform(context.Background(), &config.Config{RetireOnExit: config.RetireOnExitAlways}); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+}
+
 func TestAgentRun_Retire_Retry(t *testing.T) {
 	dir := t.TempDir()
 	conf := &config.Config{Root: dir}
@@ -302,7 +426,7 @@ func TestAgentRun_Retire_Retry(t *testing.T) {
 	if err != nil {
 		t.Errorf("err should be nil but got: %+v", err)
 	}
-	retire()
+	retire(config.RetireOnExitAlways)
 	if !retired {
 		t.Errorf("host should be retired")
 	}
@@ -339,7 +463,7 @@ func TestAgentRun_Retire_HostIDError(t *testing.T) {
 	if err != nil {
 		t.Errorf("err should be nil but got: %+v", err)
 	}
-	retire() // fails because host id is not resolved
+	retire(config.RetireOnExitAlways) // fails because host id is not resolved
 	if retired {
 		t.Errorf("host should not be retired")
 	}
@@ -558,6 +682,7 @@ type mockPlatformStatusRunning struct {
 
 func (p *mockPlatformStatusRunning) GetMetricGenerators() []metric.Generator { return nil }
 func (p *mockPlatformStatusRunning) GetSpecGenerators() []spec.Generator     { return nil }
+func (p *mockPlatformStatusRunning) GetCheckGenerators() []check.Generator   { return nil }
 func (p *mockPlatformStatusRunning) GetCustomIdentifier(context.Context) (string, error) {
 	return "", nil
 }
@@ -568,6 +693,9 @@ func (p *mockPlatformStatusRunning) StatusRunning(context.Context) bool {
 	}
 	return true
 }
+func (p *mockPlatformStatusRunning) StatusStopping(context.Context) bool {
+	return false
+}
 func (p *mockPlatformStatusRunning) Status() string {
 	if p.count > 0 {
 		return "PENDNIG"
@@ -717,3 +845,95 @@ func TestAgentRun_HostStatusOnStart(t *testing.T) {
 		t.Errorf("posted host status should be %q but got: %q", expected, postedStatus)
 	}
 }
+
+func TestAgentRun_HostStatusOnStopping(t *testing.T) {
+	tests := []struct {
+		stopping bool
+		expect   []string
+	}{
+		{true, []string{"maintenance"}},
+		{false, nil},
+	}
+
+	for _, tc := range tests {
+		dir := t.TempDir()
+		conf := &config.Config{
+			Root:                 dir,
+			HostStatusOnStopping: mackerel.HostStatusMaintenance,
+			RetireOnExit:         config.RetireOnExitNever,
+		}
+		hostID := "abcde"
+		var postedStatuses []string
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		client := api.NewMockClient(
+			api.MockCreateHost(func(param *mackerel.CreateHostParam) (string, error) {
+				return hostID, nil
+			}),
+			api.MockFindHost(func(id string) (*mackerel.Host, error) {
+				return &mackerel.Host{ID: id, Status: mackerel.HostStatusWorking}, nil
+			}),
+			api.MockUpdateHostStatus(func(id string, status string) error {
+				if id != hostID {
+					return errors.New("invalid hostID")
+				}
+				postedStatuses = append(postedStatuses, status)
+				return nil
+			}),
+		)
+		metricManager := metric.NewManager(createMockMetricGenerators(), client)
+		checkManager := check.NewManager(createMockCheckGenerators(), client)
+		specManager := spec.NewManager(createMockSpecGenerators(), client)
+		retire, err := run(ctx, client, metricManager, checkManager, specManager, &mockPlatform{stopping: tc.stopping}, conf)
+		if err != nil {
+			t.Errorf("err should be nil but got: %+v", err)
+		}
+		retire(conf.RetireOnExit) // should not update the host status again
+		if !reflect.DeepEqual(postedStatuses, tc.expect) {
+			t.Errorf("posted host statuses should be %v but got: %v", tc.expect, postedStatuses)
+		}
+		cancel()
+	}
+}
+
+func TestStoppingWatcherCheck(t *testing.T) {
+	defer func(d time.Duration) { hostStatusRetryInterval = d }(hostStatusRetryInterval)
+	hostStatusRetryInterval = 10 * time.Millisecond
+
+	var calls int
+	client := api.NewMockClient(
+		api.MockUpdateHostStatus(func(id string, status string) error {
+			if calls++; calls < hostStatusRetryCount {
+				return errors.New("temporary error")
+			}
+			return nil
+		}),
+	)
+	w := newStoppingWatcher(client, &mockPlatform{stopping: true}, mackerel.HostStatusMaintenance)
+	if !w.check(context.Background(), "abcde") {
+		t.Errorf("host status should be updated after retries")
+	}
+	if calls != hostStatusRetryCount {
+		t.Errorf("host status should be updated %d times but got %d", hostStatusRetryCount, calls)
+	}
+
+	// give up on timeout even when the API does not respond
+	block := make(chan struct{})
+	defer close(block)
+	client = api.NewMockClient(
+		api.MockUpdateHostStatus(func(id string, status string) error {
+			<-block
+			return nil
+		}),
+	)
+	w = newStoppingWatcher(client, &mockPlatform{stopping: true}, mackerel.HostStatusMaintenance)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if w.check(ctx, "abcde") {
+		t.Errorf("host status should not be updated on timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("check should return on timeout but took %s", elapsed)
+	}
+}
@@ -0,0 +1,6 @@
+package agent
+
+import "time"
+
+// hostIDStoreTimeout is the timeout of each request to the remote host id stores
+var hostIDStoreTimeout = 10 * time.Second
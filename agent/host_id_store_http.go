@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// hostIDHTTPStore stores the host id to the generic HTTP endpoint with GET, PUT and DELETE methods.
+// The credentials in the url are sent with basic authentication.
+type hostIDHTTPStore struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newHostIDHTTPStore(location, key string) (*hostIDHTTPStore, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid host id store location: %q", location)
+	}
+	u = u.JoinPath(key)
+	return &hostIDHTTPStore{
+		url:        u.String(),
+		httpClient: &http.Client{Timeout: hostIDStoreTimeout},
+	}, nil
+}
+
+func (r *hostIDHTTPStore) load() (string, bool, error) {
+	resp, err := r.do(http.MethodGet, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close() // nolint
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", true, fmt.Errorf("host id not found in %s", redactURL(r.url))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, redactURL(r.url), body)
+	}
+	hostID := strings.TrimSpace(string(body))
+	if hostID == "" {
+		return "", false, fmt.Errorf("host id found in %s but the content is empty", redactURL(r.url))
+	}
+	return hostID, false, nil
+}
+
+func (r *hostIDHTTPStore) save(id string) error {
+	resp, err := r.do(http.MethodPut, []byte(id))
+	if err != nil {
+		return err
+	}
+	return checkStatus(resp, r.url)
+}
+
+func (r *hostIDHTTPStore) remove() error {
+	resp, err := r.do(http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close() // nolint
+		return nil
+	}
+	return checkStatus(resp, r.url)
+}
+
+func (r *hostIDHTTPStore) do(method string, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hostIDStoreTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// read the body before the context is canceled
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close() // nolint
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+func checkStatus(resp *http.Response, u string) error {
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, redactURL(u), body)
+	}
+	return nil
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	kubernetesTypes "k8s.io/api/core/v1"
+)
+
+const (
+	hostIDAnnotation = "mackerel.io/host-id"
+
+	serviceAccountCACertificateFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	serviceAccountTokenFile         = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// hostIDKubernetesStore stores the host id to the annotation of the pod via Kubernetes API.
+// The service account of the pod requires the permission to get and patch the pod.
+type hostIDKubernetesStore struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+func newHostIDKubernetesStore() (*hostIDKubernetesStore, error) {
+	host, err := getEnvValue("KUBERNETES_SERVICE_HOST")
+	if err != nil {
+		return nil, err
+	}
+	port, err := getEnvValue("KUBERNETES_SERVICE_PORT")
+	if err != nil {
+		port = "443"
+	}
+	namespace, err := getEnvValue("MACKEREL_KUBERNETES_NAMESPACE")
+	if err != nil {
+		return nil, err
+	}
+	podName, err := getEnvValue("MACKEREL_KUBERNETES_POD_NAME")
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := os.ReadFile(serviceAccountCACertificateFile)
+	if err != nil {
+		return nil, err
+	}
+	token, err := os.ReadFile(serviceAccountTokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(caCert)
+	u := &url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(host, port),
+		Path:   path.Join("/api/v1/namespaces", namespace, "pods", podName),
+	}
+	return &hostIDKubernetesStore{
+		url:   u.String(),
+		token: string(bytes.TrimSpace(token)),
+		httpClient: &http.Client{
+			Timeout: hostIDStoreTimeout,
+			Transport: &http.Transport{
+				Proxy:           nil,
+				TLSClientConfig: &tls.Config{RootCAs: certPool},
+			},
+		},
+	}, nil
+}
+
+func (r *hostIDKubernetesStore) load() (string, bool, error) {
+	var pod kubernetesTypes.Pod
+	if err := r.do(http.MethodGet, nil, &pod); err != nil {
+		return "", false, err
+	}
+	hostID, ok := pod.Annotations[hostIDAnnotation]
+	if !ok {
+		return "", true, fmt.Errorf("annotation %s not found in pod %s/%s", hostIDAnnotation, pod.Namespace, pod.Name)
+	}
+	if hostID == "" {
+		return "", false, fmt.Errorf("annotation %s found in pod %s/%s but the content is empty", hostIDAnnotation, pod.Namespace, pod.Name)
+	}
+	return hostID, false, nil
+}
+
+func (r *hostIDKubernetesStore) save(id string) error {
+	return r.patch(&id)
+}
+
+func (r *hostIDKubernetesStore) remove() error {
+	return r.patch(nil)
+}
+
+func (r *hostIDKubernetesStore) patch(id *string) error {
+	// null removes the annotation in JSON merge patch
+	body, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]*string{hostIDAnnotation: id},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return r.do(http.MethodPatch, body, nil)
+}
+
+func (r *hostIDKubernetesStore) do(method string, body []byte, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hostIDStoreTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	if method == http.MethodPatch {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, r.url, body)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
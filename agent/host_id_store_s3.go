@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// defaultS3Region is used to look up the bucket region when the region is not configured
+const defaultS3Region = "us-east-1"
+
+type s3ObjectAPI interface {
+	GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(context.Context, *s3.DeleteObjectInput, ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// hostIDS3Store stores the host id to the S3 object keyed by the custom identifier.
+type hostIDS3Store struct {
+	client s3ObjectAPI
+	bucket string
+	key    string
+}
+
+// newHostIDS3Store creates the store of the bucket. The region is resolved by
+// the default config of AWS SDK and the bucket location unless it is specified.
+func newHostIDS3Store(ctx context.Context, location, region, key string) (*hostIDS3Store, error) {
+	bucket, key, err := parseS3Location(location, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []func(*awsConfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsConfig.WithRegion(region))
+	}
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			if o.Region == "" {
+				// any region of the partition can look up the bucket region
+				o.Region = defaultS3Region
+			}
+		})
+		cfg.Region, err = manager.GetBucketRegion(ctx, client, bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bucket region for %s: %w", bucket, err)
+		}
+	}
+
+	return &hostIDS3Store{
+		client: s3.NewFromConfig(cfg),
+		bucket: bucket,
+		key:    key,
+	}, nil
+}
+
+// parseS3Location returns the bucket and the object key of the location.
+// The key is joined to the prefix as is since the SDK encodes the object key.
+func parseS3Location(location, key string) (string, string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid host id store location: %q", location)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return u.Host, prefix + key, nil
+}
+
+func (r *hostIDS3Store) load() (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hostIDStoreTimeout)
+	defer cancel()
+	out, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.key),
+	})
+	if err != nil {
+		var nsk *s3Types.NoSuchKey
+		return "", errors.As(err, &nsk), fmt.Errorf("failed to get host id from %s: %w", r.location(), err)
+	}
+	defer out.Body.Close() // nolint
+	content, err := io.ReadAll(out.Body)
+	if err != nil {
+		return "", false, err
+	}
+	hostID := strings.TrimSpace(string(content))
+	if hostID == "" {
+		return "", false, fmt.Errorf("host id found in %s but the content is empty", r.location())
+	}
+	return hostID, false, nil
+}
+
+func (r *hostIDS3Store) save(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hostIDStoreTimeout)
+	defer cancel()
+	_, err := r.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.bucket),
+		Key:         aws.String(r.key),
+		Body:        bytes.NewReader([]byte(id)),
+		ContentType: aws.String("text/plain"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put host id to %s: %w", r.location(), err)
+	}
+	return nil
+}
+
+func (r *hostIDS3Store) remove() error {
+	ctx, cancel := context.WithTimeout(context.Background(), hostIDStoreTimeout)
+	defer cancel()
+	_, err := r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete host id from %s: %w", r.location(), err)
+	}
+	return nil
+}
+
+func (r *hostIDS3Store) location() string {
+	return "s3://" + r.bucket + "/" + r.key
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHostIDHTTPStore(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		store = map[string]string{}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			id, ok := store[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(id)) // nolint
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			store[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			if _, ok := store[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(store, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	s, err := newHostIDHTTPStore("http://user:pass@"+ts.Listener.Addr().String()+"/host-ids", "task-arn")
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	if _, notExist, err := s.load(); err == nil || !notExist {
+		t.Errorf("load() should return notExist error: %v", err)
+	}
+	if err := s.save("3yAYEDLXKL5"); err != nil {
+		t.Errorf("save() should not raise error: %v", err)
+	}
+	if got := store["/host-ids/task-arn"]; got != "3yAYEDLXKL5" {
+		t.Errorf("saved host id should be %q but got %q", "3yAYEDLXKL5", got)
+	}
+	hostID, _, err := s.load()
+	if err != nil {
+		t.Errorf("load() should not raise error: %v", err)
+	}
+	if hostID != "3yAYEDLXKL5" {
+		t.Errorf("host id should be %q but got %q", "3yAYEDLXKL5", hostID)
+	}
+	if err := s.remove(); err != nil {
+		t.Errorf("remove() should not raise error: %v", err)
+	}
+	if err := s.remove(); err != nil {
+		t.Errorf("remove() should ignore not found: %v", err)
+	}
+
+	s, err = newHostIDHTTPStore("http://"+ts.Listener.Addr().String()+"/host-ids", "task-arn")
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if _, notExist, err := s.load(); err == nil || notExist {
+		t.Errorf("load() should raise error on unauthorized: %v", err)
+	}
+
+	if _, err := newHostIDHTTPStore("ftp://example.com/host-ids", "task-arn"); err == nil {
+		t.Errorf("should raise error for invalid scheme")
+	}
+}
+
+func TestParseS3Location(t *testing.T) {
+	testCases := []struct {
+		name     string
+		location string
+		key      string
+		bucket   string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "bucket",
+			location: "s3://bucket",
+			key:      "task-arn",
+			bucket:   "bucket",
+			expected: "task-arn",
+		},
+		{
+			name:     "prefix",
+			location: "s3://bucket/a/b",
+			key:      "task-arn",
+			bucket:   "bucket",
+			expected: "a/b/task-arn",
+		},
+		{
+			name:     "prefix with trailing slash",
+			location: "s3://bucket/a/b/",
+			key:      "arn:aws:ecs:ap-northeast-1:123456789012:task/cluster/abc.ecs",
+			bucket:   "bucket",
+			expected: "a/b/arn:aws:ecs:ap-northeast-1:123456789012:task/cluster/abc.ecs",
+		},
+		{
+			name:     "invalid scheme",
+			location: "https://bucket/a/b",
+			key:      "task-arn",
+			err:      true,
+		},
+		{
+			name:     "no bucket",
+			location: "s3:///a/b",
+			key:      "task-arn",
+			err:      true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket, key, err := parseS3Location(tc.location, tc.key)
+			if tc.err {
+				if err == nil {
+					t.Errorf("should raise error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if bucket != tc.bucket {
+				t.Errorf("bucket should be %q but got %q", tc.bucket, bucket)
+			}
+			if key != tc.expected {
+				t.Errorf("key should be %q but got %q", tc.expected, key)
+			}
+		})
+	}
+}
+
+func TestHostIDKubernetesStore(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		annotations = map[string]string{}
+	)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Path != "/api/v1/namespaces/default/pods/myapp" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			var patch struct {
+				Metadata struct {
+					Annotations map[string]*string `json:"annotations"`
+				} `json:"metadata"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for k, v := range patch.Metadata.Annotations {
+				if v == nil {
+					delete(annotations, k)
+				} else {
+					annotations[k] = *v
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{ // nolint
+			"metadata": map[string]any{
+				"name":        "myapp",
+				"namespace":   "default",
+				"annotations": annotations,
+			},
+		})
+	}))
+	defer ts.Close()
+
+	s := &hostIDKubernetesStore{
+		url:        ts.URL + "/api/v1/namespaces/default/pods/myapp",
+		token:      "token",
+		httpClient: ts.Client(),
+	}
+
+	if _, notExist, err := s.load(); err == nil || !notExist {
+		t.Errorf("load() should return notExist error: %v", err)
+	}
+	if err := s.save("3yAYEDLXKL5"); err != nil {
+		t.Errorf("save() should not raise error: %v", err)
+	}
+	hostID, _, err := s.load()
+	if err != nil {
+		t.Errorf("load() should not raise error: %v", err)
+	}
+	if hostID != "3yAYEDLXKL5" {
+		t.Errorf("host id should be %q but got %q", "3yAYEDLXKL5", hostID)
+	}
+	if err := s.remove(); err != nil {
+		t.Errorf("remove() should not raise error: %v", err)
+	}
+	if _, ok := annotations[hostIDAnnotation]; ok {
+		t.Errorf("annotation should be removed")
+	}
+
+	s.token = "invalid"
+	if _, notExist, err := s.load(); err == nil || notExist {
+		t.Errorf("load() should raise error on forbidden: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/mackerelio/mackerel-container-agent/api"
 	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/platform"
 )
 
 type hostIDStore interface {
@@ -24,21 +26,46 @@ type hostResolver struct {
 	hostIDStore hostIDStore
 }
 
-func newHostResolver(client api.Client, hostIdStore config.HostIDStore, root string) *hostResolver {
+func newHostResolver(ctx context.Context, client api.Client, pform platform.Platform, conf *config.Config) (*hostResolver, error) {
 	var store hostIDStore
-	switch hostIdStore {
+	switch conf.HostIDStore {
 	case config.HostIDStoreMemory:
 		store = &hostIDMemoryStore{}
+	case config.HostIDStoreKubernetes:
+		s, err := newHostIDKubernetesStore()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes host id store: %w", err)
+		}
+		store = s
+	case config.HostIDStoreS3, config.HostIDStoreHTTP:
+		if conf.HostIDStoreLocation == "" {
+			return nil, fmt.Errorf("hostIdStoreLocation is required for %s host id store", conf.HostIDStore)
+		}
+		key, err := pform.GetCustomIdentifier(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get custom identifier: %w", err)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("%s host id store is not supported on this platform", conf.HostIDStore)
+		}
+		if conf.HostIDStore == config.HostIDStoreS3 {
+			store, err = newHostIDS3Store(ctx, conf.HostIDStoreLocation, conf.HostIDStoreRegion, key)
+		} else {
+			store, err = newHostIDHTTPStore(conf.HostIDStoreLocation, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s host id store: %w", conf.HostIDStore, err)
+		}
 	case config.HostIDStoreFile:
 		fallthrough
 	default:
-		store = &hostIDFileStore{path: filepath.Join(root, "id")}
+		store = &hostIDFileStore{path: filepath.Join(conf.Root, "id")}
 	}
 
 	return &hostResolver{
 		client:      client,
 		hostIDStore: store,
-	}
+	}, nil
 }
 
 func (r *hostResolver) getHost(hostParam *mackerel.CreateHostParam) (*mackerel.Host, bool, error) {
@@ -102,13 +129,7 @@ func (r *hostResolver) getLocalHostID() (string, bool, error) {
 }
 
 func retryFromError(err error) bool {
-	if err == nil {
-		return false
-	}
-	if err, ok := err.(*mackerel.APIError); ok {
-		return err.StatusCode >= 500
-	}
-	return true
+	return api.IsRetryable(err)
 }
 
 func (r *hostResolver) saveHostID(id string) error {
@@ -4,22 +4,35 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"regexp"
+	"strings"
 
+	"github.com/mackerelio/mackerel-container-agent/config"
 	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/cgroup"
+	"github.com/mackerelio/mackerel-container-agent/platform/docker"
+	"github.com/mackerelio/mackerel-container-agent/platform/docker/engine"
 	"github.com/mackerelio/mackerel-container-agent/platform/ecs"
 	"github.com/mackerelio/mackerel-container-agent/platform/kubernetes"
 	"github.com/mackerelio/mackerel-container-agent/platform/kubernetes/kubelet"
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad"
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad/agentapi"
 	"github.com/mackerelio/mackerel-container-agent/platform/none"
+	"github.com/mackerelio/mackerel-container-agent/platform/podman"
+	"github.com/mackerelio/mackerel-container-agent/platform/podman/libpod"
 )
 
 // NewPlatform creates a new container platform
-func NewPlatform(ctx context.Context, ignoreContainer *regexp.Regexp) (platform.Platform, error) {
+func NewPlatform(ctx context.Context, conf *config.Config) (platform.Platform, error) {
+	ignoreContainer := conf.IgnoreContainer.Regexp
 	p, err := getEnvValue("MACKEREL_CONTAINER_PLATFORM")
 	if err != nil {
 		return nil, err
 	}
 
+	if conf.RetireOnExit == config.RetireOnExitStopping && !supportsStatusStopping(platform.Type(p)) {
+		return nil, fmt.Errorf("retireOnExit: %s is not supported on %q platform, which cannot report the stopping status", conf.RetireOnExit, p)
+	}
+
 	switch platform.Type(p) {
 
 	case platform.ECSAwsvpc, platform.ECSv3:
@@ -73,7 +86,7 @@ func NewPlatform(ctx context.Context, ignoreContainer *regexp.Regexp) (platform.
 		if err != nil {
 			return nil, err
 		}
-		return kubernetes.NewKubernetesPlatform(host, port, useReadOnlyPort, insecureTLS, namespace, podName, ignoreContainer)
+		return kubernetes.NewKubernetesPlatform(host, port, useReadOnlyPort, insecureTLS, namespace, podName, ignoreContainer, conf.PodReadinessCheck)
 
 	case platform.EKSOnFargate:
 		host, err := getEnvValue("KUBERNETES_SERVICE_HOST")
@@ -96,7 +109,69 @@ func NewPlatform(ctx context.Context, ignoreContainer *regexp.Regexp) (platform.
 		if err != nil {
 			return nil, err
 		}
-		return kubernetes.NewEKSOnFargatePlatform(host, port, namespace, podName, nodeName, ignoreContainer)
+		return kubernetes.NewEKSOnFargatePlatform(host, port, namespace, podName, nodeName, ignoreContainer, conf.PodReadinessCheck)
+
+	case platform.Docker:
+		socketPath, err := getEnvValue("MACKEREL_DOCKER_SOCKET")
+		if err != nil {
+			socketPath = engine.DefaultSocketPath
+		}
+		containerID, err := getEnvValue("MACKEREL_DOCKER_CONTAINER_ID")
+		if err != nil {
+			// Docker sets the container id to the hostname by default
+			containerID, err = os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+		}
+		var labels []string
+		if v, err := getEnvValue("MACKEREL_DOCKER_LABELS"); err == nil {
+			for l := range strings.SplitSeq(v, ",") {
+				if l = strings.TrimSpace(l); l != "" {
+					labels = append(labels, l)
+				}
+			}
+		}
+		return docker.NewDockerPlatform(ctx, socketPath, containerID, labels, ignoreContainer)
+
+	case platform.Podman:
+		socketPath, err := getEnvValue("MACKEREL_PODMAN_SOCKET")
+		if err != nil {
+			socketPath = libpod.DefaultSocketPath
+		}
+		podName, err := getEnvValue("MACKEREL_PODMAN_POD_NAME")
+		if err != nil {
+			// Podman sets the pod name to the hostname of the containers in the pod by default
+			podName, err = os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return podman.NewPodmanPlatform(socketPath, podName, ignoreContainer)
+
+	case platform.Cgroup:
+		path, err := getEnvValue("MACKEREL_CGROUP_PATH")
+		if err != nil {
+			path = cgroup.DefaultPath
+		}
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		return cgroup.NewCgroupPlatform(path, hostname, os.LookupEnv)
+
+	case platform.Nomad:
+		allocID, err := getEnvValue("NOMAD_ALLOC_ID")
+		if err != nil {
+			return nil, err
+		}
+		address, err := getEnvValue("NOMAD_ADDR")
+		if err != nil {
+			address = agentapi.DefaultAddress
+		}
+		token, _ := getEnvValue("NOMAD_TOKEN")
+		datacenter, _ := getEnvValue("NOMAD_DC")
+		return nomad.NewNomadPlatform(address, allocID, token, datacenter, ignoreContainer)
 
 	// for testing & debugging on local machine
 	case platform.None:
@@ -6,8 +6,12 @@ import (
 	"github.com/Songmu/retry"
 
 	"github.com/mackerelio/mackerel-container-agent/api"
+	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/platform"
 )
 
+var statusStoppingTimeout = 3 * time.Second
+
 func retire(client api.Client, hostResolver *hostResolver) error {
 	hostID, notExist, err := hostResolver.getLocalHostID()
 	if err != nil {
@@ -25,3 +29,26 @@ func retire(client api.Client, hostResolver *hostResolver) error {
 	}
 	return hostResolver.removeHostID()
 }
+
+// shouldRetire reports whether the host should be retired on exit
+func shouldRetire(policy config.RetireOnExit, stopping bool) bool {
+	switch policy {
+	case config.RetireOnExitNever:
+		return false
+	case config.RetireOnExitStopping:
+		return stopping
+	default:
+		return true
+	}
+}
+
+// supportsStatusStopping reports whether the platform can report the task or
+// pod is being stopped
+func supportsStatusStopping(t platform.Type) bool {
+	switch t {
+	case platform.Docker, platform.Podman, platform.Cgroup, platform.None:
+		return false
+	default:
+		return true
+	}
+}
@@ -18,11 +18,13 @@ import (
 
 var (
 	metricsInterval            = time.Minute
+	graphDefsInterval          = 10 * time.Minute
 	checkInterval              = time.Minute
 	specInterval               = time.Hour
 	specInitialInterval        = 5 * time.Minute
 	waitStatusRunningInterval  = 3 * time.Second
 	hostIDInitialRetryInterval = 1 * time.Second
+	stoppingWatchInterval      = 10 * time.Second
 )
 
 func run(
@@ -33,11 +35,15 @@ func run(
 	specManager *spec.Manager,
 	pform platform.Platform,
 	conf *config.Config,
-) (func(), error) {
+) (func(config.RetireOnExit), error) {
 	specManager.SetChecks(checkManager.Configs())
 	eg, ctx := errgroup.WithContext(ctx)
 
-	hostResolver := newHostResolver(client, conf.HostIDStore, conf.Root)
+	hostResolver, err := newHostResolver(ctx, client, pform, conf)
+	if err != nil {
+		return nil, err
+	}
+	watcher := newStoppingWatcher(client, pform, conf.HostStatusOnStopping)
 	eg.Go(func() error {
 		var duration time.Duration
 	loop:
@@ -104,6 +110,7 @@ func run(
 				metricManager.SetHostID(host.ID)
 				checkManager.SetHostID(host.ID)
 				specManager.SetHostID(host.ID)
+				watcher.run(ctx, host.ID, stoppingWatchInterval)
 				return nil
 			case <-ctx.Done():
 				return nil
@@ -115,6 +122,10 @@ func run(
 		return metricManager.Run(ctx, metricsInterval)
 	})
 
+	eg.Go(func() error {
+		return metricManager.RunGraphDefs(ctx, graphDefsInterval)
+	})
+
 	eg.Go(func() error {
 		return checkManager.Run(ctx, checkInterval)
 	})
@@ -123,7 +134,25 @@ func run(
 		return specManager.Run(ctx, specInitialInterval, specInterval)
 	})
 
-	return func() {
+	return func(policy config.RetireOnExit) {
+		// the platform may start stopping after the last poll, so query it
+		// once for both the host status and the retirement
+		var stopping bool
+		if policy == config.RetireOnExitStopping || !watcher.done() {
+			// the context of the agent is already canceled on exit
+			ctx, cancel := context.WithTimeout(context.Background(), statusStoppingTimeout)
+			stopping = pform.StatusStopping(ctx)
+			cancel()
+		}
+		if hostID, _, err := hostResolver.getLocalHostID(); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), hostStatusUpdateTimeout)
+			watcher.update(ctx, hostID, stopping)
+			cancel()
+		}
+		if !shouldRetire(policy, stopping) {
+			logger.Infof("skip retiring the host: retireOnExit = %s", policy)
+			return
+		}
 		if err := retire(client, hostResolver); err != nil {
 			logger.Warningf("failed to retire: %s", err)
 		}
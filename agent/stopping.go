@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/mackerelio/mackerel-container-agent/api"
+	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/platform"
+)
+
+var (
+	hostStatusRetryCount    = 3
+	hostStatusRetryInterval = time.Second
+	// hostStatusUpdateTimeout covers the retries of updating the host status
+	hostStatusUpdateTimeout = 10 * time.Second
+)
+
+// stoppingWatcher updates the host status when the platform reports the task or pod is being stopped
+type stoppingWatcher struct {
+	client  api.Client
+	pform   platform.Platform
+	status  config.HostStatus
+	updated atomic.Bool
+}
+
+func newStoppingWatcher(client api.Client, pform platform.Platform, status config.HostStatus) *stoppingWatcher {
+	return &stoppingWatcher{
+		client: client,
+		pform:  pform,
+		status: status,
+	}
+}
+
+// run polls the platform status until the host status is updated
+func (w *stoppingWatcher) run(ctx context.Context, hostID string, interval time.Duration) {
+	if w.status == "" {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if w.check(ctx, hostID) {
+			return
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check updates the host status if the platform is stopping and reports whether it is updated
+func (w *stoppingWatcher) check(ctx context.Context, hostID string) bool {
+	if w.done() {
+		return true
+	}
+	sctx, cancel := context.WithTimeout(ctx, statusStoppingTimeout)
+	stopping := w.pform.StatusStopping(sctx)
+	cancel()
+	return w.update(ctx, hostID, stopping)
+}
+
+// done reports whether the host status needs no more update
+func (w *stoppingWatcher) done() bool {
+	return w.status == "" || w.updated.Load()
+}
+
+// update updates the host status by the stopping status queried by the caller
+func (w *stoppingWatcher) update(ctx context.Context, hostID string, stopping bool) bool {
+	if w.done() {
+		return true
+	}
+	if !stopping {
+		return false
+	}
+	logger.Infof("the platform is stopping: update host status to %s", w.status)
+	if err := w.updateHostStatus(ctx, hostID); err != nil {
+		logger.Warningf("failed to update host status on stopping: %s", err)
+		return false
+	}
+	w.updated.Store(true)
+	return true
+}
+
+// updateHostStatus updates the host status with the retries until the context is done
+func (w *stoppingWatcher) updateHostStatus(ctx context.Context, hostID string) error {
+	var err error
+	for i := range hostStatusRetryCount {
+		if i > 0 {
+			select {
+			case <-time.After(hostStatusRetryInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		// the client does not take the context, so give up waiting for the response on cancel
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- w.client.UpdateHostStatus(hostID, string(w.status))
+		}()
+		select {
+		case err = <-errCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
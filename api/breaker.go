@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+var logger = logging.GetLogger("api")
+
+const (
+	breakerFailureThreshold = 3
+	breakerMinBackoff       = time.Minute
+	breakerMaxBackoff       = 10 * time.Minute
+)
+
+// ErrCircuitOpen is returned without calling the API while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// PermanentError represents the error which does not recover by retrying, like invalid API key
+type PermanentError struct {
+	Err error
+}
+
+func (err *PermanentError) Error() string {
+	return fmt.Sprintf("permanent API error: %s", err.Err)
+}
+
+func (err *PermanentError) Unwrap() error {
+	return err.Err
+}
+
+// IsPermanent reports whether the error does not recover by retrying
+func IsPermanent(err error) bool {
+	var permanentErr *PermanentError
+	if errors.As(err, &permanentErr) {
+		return true
+	}
+	var apiErr *mackerel.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// IsRetryable reports whether the request should be retried later.
+// The server errors, rate limits and network errors are retryable.
+func IsRetryable(err error) bool {
+	if err == nil || IsPermanent(err) {
+		return false
+	}
+	var apiErr *mackerel.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// BreakerState represents the state of the circuit breaker
+type BreakerState string
+
+// BreakerState values
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+	BreakerStopped  BreakerState = "stopped"
+)
+
+// BreakerStatus represents the snapshot of the circuit breaker
+type BreakerStatus struct {
+	State     BreakerState
+	Failures  int
+	RetryAt   time.Time
+	LastError error
+}
+
+// Breaker is a circuit breaker of Mackerel API shared by the senders and the host resolver.
+// It opens after the consecutive retryable failures and backs off exponentially,
+// and stops on the permanent errors.
+type Breaker struct {
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	backoff   time.Duration
+	retryAt   time.Time
+	probing   bool
+	lastError error
+	now       func() time.Time
+}
+
+// NewBreaker creates a new Breaker
+func NewBreaker() *Breaker {
+	return &Breaker{state: BreakerClosed, now: time.Now}
+}
+
+// Status returns the current status of the circuit breaker
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStatus{
+		State:     b.state,
+		Failures:  b.failures,
+		RetryAt:   b.retryAt,
+		LastError: b.lastError,
+	}
+}
+
+// Allow reports whether the request can be sent. The caller must call Done
+// with the result of the request when Allow returns nil.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerStopped:
+		return &PermanentError{Err: b.lastError}
+	case BreakerOpen:
+		if b.now().Before(b.retryAt) {
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		// allow only one request to probe the recovery
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Done records the result of the request
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if b.state == BreakerStopped {
+		return
+	}
+	if IsPermanent(err) {
+		b.lastError = err
+		b.setState(BreakerStopped)
+		logger.Criticalf("stop calling Mackerel API due to the permanent error; check the API key and its permission: %s", err)
+		return
+	}
+	if !IsRetryable(err) {
+		b.failures, b.backoff, b.retryAt, b.lastError = 0, 0, time.Time{}, nil
+		b.setState(BreakerClosed)
+		return
+	}
+
+	b.failures++
+	b.lastError = err
+	var wait time.Duration
+	var rateLimitErr *RateLimitError
+	var apiErr *mackerel.APIError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		wait = rateLimitErr.RetryAfter
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests:
+	case b.state == BreakerClosed && b.failures < breakerFailureThreshold:
+		return
+	}
+	if wait == 0 {
+		b.backoff = min(max(2*b.backoff, breakerMinBackoff), breakerMaxBackoff)
+		wait = b.backoff
+	}
+	b.retryAt = b.now().Add(wait)
+	b.setState(BreakerOpen)
+	logger.Warningf("postpone calling Mackerel API until %s after %d failures: %s", b.retryAt.Format(time.RFC3339), b.failures, err)
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	if b.state != state {
+		logger.Infof("circuit breaker state changed: %s -> %s", b.state, state)
+		b.state = state
+	}
+}
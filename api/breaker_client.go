@@ -0,0 +1,100 @@
+package api
+
+import mackerel "github.com/mackerelio/mackerel-client-go"
+
+// BreakerClient is a Client which calls Mackerel API through the circuit breaker.
+// RetireHost is called regardless of the breaker state, since it is the last call on exit.
+type BreakerClient struct {
+	client  Client
+	breaker *Breaker
+}
+
+// NewBreakerClient creates a new BreakerClient
+func NewBreakerClient(client Client, breaker *Breaker) *BreakerClient {
+	return &BreakerClient{client: client, breaker: breaker}
+}
+
+func call[T any](b *Breaker, f func() (T, error)) (T, error) {
+	if err := b.Allow(); err != nil {
+		var zero T
+		return zero, err
+	}
+	res, err := f()
+	b.Done(err)
+	return res, err
+}
+
+func callErr(b *Breaker, f func() error) error {
+	_, err := call(b, func() (struct{}, error) {
+		return struct{}{}, f()
+	})
+	return err
+}
+
+// FindHost ...
+func (c *BreakerClient) FindHost(id string) (*mackerel.Host, error) {
+	return call(c.breaker, func() (*mackerel.Host, error) {
+		return c.client.FindHost(id)
+	})
+}
+
+// FindHosts ...
+func (c *BreakerClient) FindHosts(param *mackerel.FindHostsParam) ([]*mackerel.Host, error) {
+	return call(c.breaker, func() ([]*mackerel.Host, error) {
+		return c.client.FindHosts(param)
+	})
+}
+
+// CreateHost ...
+func (c *BreakerClient) CreateHost(param *mackerel.CreateHostParam) (string, error) {
+	return call(c.breaker, func() (string, error) {
+		return c.client.CreateHost(param)
+	})
+}
+
+// UpdateHost ...
+func (c *BreakerClient) UpdateHost(hostID string, param *mackerel.UpdateHostParam) (string, error) {
+	return call(c.breaker, func() (string, error) {
+		return c.client.UpdateHost(hostID, param)
+	})
+}
+
+// UpdateHostStatus ...
+func (c *BreakerClient) UpdateHostStatus(hostID string, status string) error {
+	return callErr(c.breaker, func() error {
+		return c.client.UpdateHostStatus(hostID, status)
+	})
+}
+
+// RetireHost ...
+func (c *BreakerClient) RetireHost(id string) error {
+	return c.client.RetireHost(id)
+}
+
+// PostHostMetricValuesByHostID ...
+func (c *BreakerClient) PostHostMetricValuesByHostID(hostID string, metricValues []*mackerel.MetricValue) error {
+	return callErr(c.breaker, func() error {
+		return c.client.PostHostMetricValuesByHostID(hostID, metricValues)
+	})
+}
+
+// PostServiceMetricValues ...
+func (c *BreakerClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
+	return callErr(c.breaker, func() error {
+		return c.client.PostServiceMetricValues(serviceName, metricValues)
+	})
+}
+
+// CreateGraphDefs ...
+func (c *BreakerClient) CreateGraphDefs(graphDefs []*mackerel.GraphDefsParam) error {
+	return callErr(c.breaker, func() error {
+		return c.client.CreateGraphDefs(graphDefs)
+	})
+}
+
+// PostCheckReports ...
+func (c *BreakerClient) PostCheckReports(reports *mackerel.CheckReports) error {
+	return callErr(c.breaker, func() error {
+		return c.client.PostCheckReports(reports)
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+		permanent bool
+	}{
+		{nil, false, false},
+		{errors.New("connection refused"), true, false},
+		{&mackerel.APIError{StatusCode: 500}, true, false},
+		{&mackerel.APIError{StatusCode: 503}, true, false},
+		{&mackerel.APIError{StatusCode: 429}, true, false},
+		{&RateLimitError{RetryAfter: time.Minute}, true, false},
+		{ErrCircuitOpen, true, false},
+		{&mackerel.APIError{StatusCode: 400}, false, false},
+		{&mackerel.APIError{StatusCode: 404}, false, false},
+		{&mackerel.APIError{StatusCode: 401}, false, true},
+		{&mackerel.APIError{StatusCode: 403}, false, true},
+		{&PermanentError{Err: errors.New("invalid api key")}, false, true},
+	}
+	for _, tc := range tests {
+		if got := IsRetryable(tc.err); got != tc.retryable {
+			t.Errorf("IsRetryable(%v) should be %t but got %t", tc.err, tc.retryable, got)
+		}
+		if got := IsPermanent(tc.err); got != tc.permanent {
+			t.Errorf("IsPermanent(%v) should be %t but got %t", tc.err, tc.permanent, got)
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker()
+	b.now = func() time.Time { return now }
+
+	call := func(err error) error {
+		if err := b.Allow(); err != nil {
+			return err
+		}
+		b.Done(err)
+		return err
+	}
+	serverErr := &mackerel.APIError{StatusCode: 503}
+
+	// open after the consecutive failures
+	for range breakerFailureThreshold - 1 {
+		call(serverErr) // nolint
+		if st := b.Status(); st.State != BreakerClosed {
+			t.Fatalf("state should be %s but got %s", BreakerClosed, st.State)
+		}
+	}
+	call(serverErr) // nolint
+	st := b.Status()
+	if st.State != BreakerOpen || st.Failures != breakerFailureThreshold || !st.RetryAt.Equal(now.Add(breakerMinBackoff)) {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if err := call(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("should not call while open but got %v", err)
+	}
+
+	// probe only one request after the backoff, and back off exponentially on failure
+	now = now.Add(breakerMinBackoff)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("should allow the probe: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("should allow only one probe but got %v", err)
+	}
+	b.Done(serverErr)
+	if st := b.Status(); st.State != BreakerOpen || !st.RetryAt.Equal(now.Add(2*breakerMinBackoff)) {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	// close on success
+	now = now.Add(2 * breakerMinBackoff)
+	if err := call(nil); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if st := b.Status(); st.State != BreakerClosed || st.Failures != 0 {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	// open immediately on rate limit
+	call(&RateLimitError{RetryAfter: 90 * time.Second}) // nolint
+	if st := b.Status(); st.State != BreakerOpen || !st.RetryAt.Equal(now.Add(90*time.Second)) {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	now = now.Add(90 * time.Second)
+	call(&mackerel.APIError{StatusCode: 400}) // nolint
+	if st := b.Status(); st.State != BreakerClosed {
+		t.Errorf("should close on non-retryable error: %+v", st)
+	}
+
+	// stop on the permanent error
+	call(&mackerel.APIError{StatusCode: 401}) // nolint
+	if st := b.Status(); st.State != BreakerStopped {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	now = now.Add(time.Hour)
+	if err := call(nil); !IsPermanent(err) {
+		t.Errorf("should raise permanent error but got %v", err)
+	}
+}
@@ -11,6 +11,7 @@ type Client interface {
 	UpdateHostStatus(hostID string, status string) error
 	RetireHost(id string) error
 	PostHostMetricValuesByHostID(hostID string, metricValues []*mackerel.MetricValue) error
+	PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error
 	CreateGraphDefs([]*mackerel.GraphDefsParam) error
 	PostCheckReports(reports *mackerel.CheckReports) error
 }
@@ -1,6 +1,10 @@
 package api
 
-import mackerel "github.com/mackerelio/mackerel-client-go"
+import (
+	"sync"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
 
 // MockClient represents a mock client of Mackerel API
 type MockClient struct {
@@ -11,10 +15,13 @@ type MockClient struct {
 	updateHostStatusCallback             func(hostID string, status string) error
 	retireHostCallback                   func(id string) error
 	postHostMetricValuesByHostIDCallback func(hostID string, metricValues []*mackerel.MetricValue) error
+	postServiceMetricValuesCallback      func(serviceName string, metricValues []*mackerel.MetricValue) error
 	createGraphDefsCallback              func(graphDefs []*mackerel.GraphDefsParam) error
 	postCheckReportsCallback             func(reports *mackerel.CheckReports) error
 	metricValues                         map[string][]*mackerel.MetricValue
+	serviceMetricValues                  map[string][]*mackerel.MetricValue
 	graphDefs                            []*mackerel.GraphDefsParam
+	mu                                   sync.Mutex
 }
 
 // MockClientOption represents an option of mock client of Mackerel API
@@ -23,7 +30,8 @@ type MockClientOption func(*MockClient)
 // NewMockClient creates a new mock client of Mackerel API
 func NewMockClient(opts ...MockClientOption) *MockClient {
 	client := &MockClient{
-		metricValues: make(map[string][]*mackerel.MetricValue),
+		metricValues:        make(map[string][]*mackerel.MetricValue),
+		serviceMetricValues: make(map[string][]*mackerel.MetricValue),
 	}
 	for _, opt := range opts {
 		client.ApplyOption(opt)
@@ -137,6 +145,8 @@ func (c *MockClient) PostHostMetricValuesByHostID(hostID string, metricValues []
 	if c.postHostMetricValuesByHostIDCallback != nil {
 		return c.postHostMetricValuesByHostIDCallback(hostID, metricValues)
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if _, ok := c.metricValues[hostID]; ok {
 		c.metricValues[hostID] = append(c.metricValues[hostID], metricValues...)
 	} else {
@@ -152,11 +162,31 @@ func MockPostHostMetricValuesByHostID(callback func(hostID string, metricValues
 	}
 }
 
+// PostServiceMetricValues ...
+func (c *MockClient) PostServiceMetricValues(serviceName string, metricValues []*mackerel.MetricValue) error {
+	if c.postServiceMetricValuesCallback != nil {
+		return c.postServiceMetricValuesCallback(serviceName, metricValues)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serviceMetricValues[serviceName] = append(c.serviceMetricValues[serviceName], metricValues...)
+	return nil
+}
+
+// MockPostServiceMetricValues returns an option to set the callback of PostServiceMetricValues
+func MockPostServiceMetricValues(callback func(serviceName string, metricValues []*mackerel.MetricValue) error) MockClientOption {
+	return func(c *MockClient) {
+		c.postServiceMetricValuesCallback = callback
+	}
+}
+
 // CreateGraphDefs ...
 func (c *MockClient) CreateGraphDefs(graphDefs []*mackerel.GraphDefsParam) error {
 	if c.createGraphDefsCallback != nil {
 		return c.createGraphDefsCallback(graphDefs)
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.graphDefs = append(c.graphDefs, graphDefs...)
 	return nil
 }
@@ -185,10 +215,21 @@ func MockPostCheckReports(callback func(reports *mackerel.CheckReports) error) M
 
 // PostedMetricValues returns the posted metric values
 func (c *MockClient) PostedMetricValues() map[string][]*mackerel.MetricValue {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.metricValues
 }
 
+// PostedServiceMetricValues returns the posted service metric values
+func (c *MockClient) PostedServiceMetricValues() map[string][]*mackerel.MetricValue {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serviceMetricValues
+}
+
 // PostedGraphDefs returns the posted graph definitions
 func (c *MockClient) PostedGraphDefs() []*mackerel.GraphDefsParam {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.graphDefs
 }
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gzipMinSize is the minimum size of the request body to be compressed
+const gzipMinSize = 1024
+
+// RateLimitError represents the 429 response of Mackerel API with Retry-After header
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (err *RateLimitError) Error() string {
+	return fmt.Sprintf("API request rate limited: retry after %s", err.RetryAfter)
+}
+
+// Transport compresses the request body of posting metric values with gzip,
+// and turns the 429 response with Retry-After header into *RateLimitError.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport creates a new Transport
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/tsdb") &&
+		req.Body != nil && req.Header.Get("Content-Encoding") == "" {
+		var err error
+		if req, err = compressRequest(req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			io.Copy(io.Discard, resp.Body) // nolint
+			resp.Body.Close()              // nolint
+			return nil, &RateLimitError{RetryAfter: d}
+		}
+	}
+	return resp, nil
+}
+
+func compressRequest(req *http.Request) (*http.Request, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close() // nolint
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	if len(body) < gzipMinSize {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return req, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	return req, nil
+}
+
+func parseRetryAfter(s string, now time.Time) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(s); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/tsdb":
+			body := r.Body
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				zr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				body = zr
+			}
+			b, _ := io.ReadAll(body)
+			w.Header().Set("X-Encoding", r.Header.Get("Content-Encoding"))
+			w.Write(b) // nolint
+		case "/api/v0/limited":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	tests := []struct {
+		body     string
+		encoding string
+	}{
+		{`[{"name":"foo"}]`, ""},
+		{"[" + strings.Repeat(`{"name":"foo"},`, 100) + "{}]", "gzip"},
+	}
+	for _, tc := range tests {
+		resp, err := client.Post(ts.URL+"/api/v0/tsdb", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close() // nolint
+		if string(b) != tc.body {
+			t.Errorf("body should be %q but got %q", tc.body, b)
+		}
+		if got := resp.Header.Get("X-Encoding"); got != tc.encoding {
+			t.Errorf("encoding should be %q but got %q", tc.encoding, got)
+		}
+	}
+
+	_, err := client.Get(ts.URL + "/api/v0/limited")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("should raise RateLimitError but got %v", err)
+	}
+	if rateLimitErr.RetryAfter != 30*time.Second {
+		t.Errorf("retry after should be 30s but got %s", rateLimitErr.RetryAfter)
+	}
+
+	resp, err := client.Get(ts.URL + "/api/v0/unknown")
+	if err != nil {
+		t.Fatalf("should not raise error without Retry-After: %v", err)
+	}
+	resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status code should be %d but got %d", http.StatusTooManyRequests, resp.StatusCode)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		expect time.Duration
+		ok     bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Thu, 01 Oct 2026 00:00:45 GMT", 45 * time.Second, true},
+		{"Wed, 30 Sep 2026 23:59:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tc := range tests {
+		got, ok := parseRetryAfter(tc.value, now)
+		if got != tc.expect || ok != tc.ok {
+			t.Errorf("parseRetryAfter(%q) should be (%s, %t) but got (%s, %t)", tc.value, tc.expect, tc.ok, got, ok)
+		}
+	}
+}
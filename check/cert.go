@@ -0,0 +1,154 @@
+package check
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+const (
+	defaultCertWarningDays  = 30
+	defaultCertCriticalDays = 14
+	defaultTimeoutCert      = 10 * time.Second
+)
+
+var checkStatusSeverity = map[mackerel.CheckStatus]int{
+	mackerel.CheckStatusOK:       0,
+	mackerel.CheckStatusUnknown:  1,
+	mackerel.CheckStatusWarning:  2,
+	mackerel.CheckStatusCritical: 3,
+}
+
+type certGenerator struct {
+	config.CertCheck
+	targets    []string
+	rootCAs    *x509.CertPool // nil to use the system roots
+	lastResult *Result
+}
+
+// NewCertGenerator creates a new check generator which reports the days until
+// the certificates of the targets expire. The status is the worst one of the
+// targets, and it is critical when the certificate chain is not verified.
+func NewCertGenerator(c *config.CertCheck) Generator {
+	g := &certGenerator{CertCheck: *c, targets: c.Targets}
+	if len(g.targets) == 0 {
+		g.targets = []string{net.JoinHostPort(c.Host, c.Port)}
+	}
+	if g.WarningDays == 0 {
+		g.WarningDays = max(defaultCertWarningDays, g.CriticalDays)
+	}
+	if g.CriticalDays == 0 {
+		g.CriticalDays = min(defaultCertCriticalDays, g.WarningDays)
+	}
+	return g
+}
+
+// Config gets check generator config
+func (g *certGenerator) Config() mackerel.CheckConfig {
+	return mackerel.CheckConfig{Name: g.Name, Memo: g.Memo}
+}
+
+// Generate generates check report
+func (g *certGenerator) Generate(ctx context.Context) (*Result, error) {
+	now := time.Now()
+	newResult := g.check(ctx, now)
+
+	lastResult := g.lastResult
+	g.lastResult = newResult
+	if lastResult == nil {
+		return newResult, nil
+	}
+	if lastResult.status == mackerel.CheckStatusOK && newResult.status == mackerel.CheckStatusOK {
+		// do not report ok -> ok
+		return nil, nil
+	}
+	return newResult, nil
+}
+
+func (g *certGenerator) check(ctx context.Context, now time.Time) *Result {
+	statuses := make([]mackerel.CheckStatus, len(g.targets))
+	messages := make([]string, len(g.targets))
+	var wg sync.WaitGroup
+	for i, addr := range g.targets {
+		wg.Go(func() {
+			statuses[i], messages[i] = g.checkTarget(ctx, addr, now)
+		})
+	}
+	wg.Wait()
+
+	status := mackerel.CheckStatusOK
+	for _, s := range statuses {
+		if checkStatusSeverity[s] > checkStatusSeverity[status] {
+			status = s
+		}
+	}
+	return NewResult(g.Name, strings.Join(messages, "\n"), status, now)
+}
+
+func (g *certGenerator) checkTarget(ctx context.Context, addr string, now time.Time) (mackerel.CheckStatus, string) {
+	serverName := g.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(addr)
+	}
+	timeout := time.Duration(g.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = defaultTimeoutCert
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	d := &tls.Dialer{Config: &tls.Config{
+		ServerName:         serverName,
+		RootCAs:            g.rootCAs,
+		InsecureSkipVerify: g.InsecureSkipVerify, // nolint
+	}}
+	var certs []*x509.Certificate
+	var verifyErr error
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		// report the expiry of the certificate which is not verified
+		var e *tls.CertificateVerificationError
+		if !errors.As(err, &e) {
+			logger.Warningf("cert check %s (%s): %s", g.Name, addr, err)
+			return mackerel.CheckStatusUnknown, err.Error()
+		}
+		certs, verifyErr = e.UnverifiedCertificates, e.Err
+	} else {
+		defer conn.Close() // nolint
+		certs = conn.(*tls.Conn).ConnectionState().PeerCertificates
+	}
+	if len(certs) == 0 {
+		return mackerel.CheckStatusUnknown, fmt.Sprintf("no certificate presented by %s", addr)
+	}
+
+	cert := certs[0]
+	days := int(cert.NotAfter.Sub(now).Hours() / 24)
+	var message string
+	if cert.NotAfter.Before(now) {
+		message = fmt.Sprintf("certificate of %s (%s) expired at %s", addr, serverName, cert.NotAfter.Format(time.RFC3339))
+	} else {
+		message = fmt.Sprintf("certificate of %s (%s) expires in %d days at %s", addr, serverName, days, cert.NotAfter.Format(time.RFC3339))
+	}
+	message += fmt.Sprintf("\nsubject: %s\nissuer: %s", cert.Subject, cert.Issuer)
+	if verifyErr != nil {
+		message += fmt.Sprintf("\nfailed to verify: %s", verifyErr)
+	}
+
+	status := mackerel.CheckStatusOK
+	if verifyErr != nil || cert.NotAfter.Before(now) || days < g.CriticalDays {
+		status = mackerel.CheckStatusCritical
+	} else if days < g.WarningDays {
+		status = mackerel.CheckStatusWarning
+	}
+	return status, message
+}
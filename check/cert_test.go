@@ -0,0 +1,247 @@
+package check
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func newCertificate(t *testing.T, name string, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func newTLSServer(t *testing.T, certs map[string]tls.Certificate) (string, string) {
+	t.Helper()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert := certs[hello.ServerName]
+			return &cert, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() }) // nolint
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()           // nolint
+				conn.(*tls.Conn).Handshake() // nolint
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	return host, port
+}
+
+func newCertPool(t *testing.T, certs map[string]tls.Certificate) *x509.CertPool {
+	t.Helper()
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		c, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.AddCert(c)
+	}
+	return pool
+}
+
+func TestCertGenerator(t *testing.T) {
+	now := time.Now()
+	certs := map[string]tls.Certificate{
+		"ok.example.com":       newCertificate(t, "ok.example.com", now.AddDate(0, 0, 90)),
+		"warning.example.com":  newCertificate(t, "warning.example.com", now.AddDate(0, 0, 20)),
+		"critical.example.com": newCertificate(t, "critical.example.com", now.AddDate(0, 0, 3)),
+		"expired.example.com":  newCertificate(t, "expired.example.com", now.AddDate(0, 0, -1)),
+	}
+	host, port := newTLSServer(t, certs)
+	rootCAs := newCertPool(t, certs)
+
+	testCases := []struct {
+		serverName string
+		status     mackerel.CheckStatus
+		message    string
+	}{
+		{"ok.example.com", mackerel.CheckStatusOK, "expires in 89 days"},
+		{"warning.example.com", mackerel.CheckStatusWarning, "expires in 19 days"},
+		{"critical.example.com", mackerel.CheckStatusCritical, "expires in 2 days"},
+		{"expired.example.com", mackerel.CheckStatusCritical, "expired at"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.serverName, func(t *testing.T) {
+			g := NewCertGenerator(&config.CertCheck{
+				Name:       "cert",
+				Host:       host,
+				Port:       port,
+				ServerName: tc.serverName,
+			})
+			g.(*certGenerator).rootCAs = rootCAs
+			result, err := g.Generate(context.Background())
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if result.status != tc.status {
+				t.Errorf("status should be %v but got: %v", tc.status, result.status)
+			}
+			if !strings.Contains(result.message, tc.message) {
+				t.Errorf("message should contain %q but got: %q", tc.message, result.message)
+			}
+			if expected := "subject: CN=" + tc.serverName + "\nissuer: CN=" + tc.serverName; !strings.Contains(result.message, expected) {
+				t.Errorf("message should contain %q but got: %q", expected, result.message)
+			}
+		})
+	}
+}
+
+func TestCertGenerator_Verify(t *testing.T) {
+	now := time.Now()
+	host, port := newTLSServer(t, map[string]tls.Certificate{
+		"ok.example.com": newCertificate(t, "ok.example.com", now.AddDate(0, 0, 90)),
+	})
+
+	testCases := []struct {
+		name               string
+		insecureSkipVerify bool
+		status             mackerel.CheckStatus
+		message            string
+	}{
+		{"verify", false, mackerel.CheckStatusCritical, "failed to verify: x509: certificate signed by unknown authority"},
+		{"insecure skip verify", true, mackerel.CheckStatusOK, "expires in 89 days"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewCertGenerator(&config.CertCheck{
+				Name:               "cert",
+				Host:               host,
+				Port:               port,
+				ServerName:         "ok.example.com",
+				InsecureSkipVerify: tc.insecureSkipVerify,
+			})
+			result, err := g.Generate(context.Background())
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if result.status != tc.status {
+				t.Errorf("status should be %v but got: %v", tc.status, result.status)
+			}
+			if !strings.Contains(result.message, tc.message) {
+				t.Errorf("message should contain %q but got: %q", tc.message, result.message)
+			}
+		})
+	}
+}
+
+func TestCertGenerator_Targets(t *testing.T) {
+	now := time.Now()
+	okCerts := map[string]tls.Certificate{
+		"app.example.com": newCertificate(t, "app.example.com", now.AddDate(0, 0, 90)),
+	}
+	warningCerts := map[string]tls.Certificate{
+		"app.example.com": newCertificate(t, "app.example.com", now.AddDate(0, 0, 20)),
+	}
+	okHost, okPort := newTLSServer(t, okCerts)
+	warningHost, warningPort := newTLSServer(t, warningCerts)
+	rootCAs := newCertPool(t, okCerts)
+	for _, cert := range warningCerts {
+		c, _ := x509.ParseCertificate(cert.Certificate[0])
+		rootCAs.AddCert(c)
+	}
+
+	okAddr, warningAddr := net.JoinHostPort(okHost, okPort), net.JoinHostPort(warningHost, warningPort)
+	g := NewCertGenerator(&config.CertCheck{
+		Name:       "cert",
+		Targets:    []string{okAddr, warningAddr},
+		ServerName: "app.example.com",
+	})
+	g.(*certGenerator).rootCAs = rootCAs
+	result, err := g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if expected := mackerel.CheckStatusWarning; result.status != expected {
+		t.Errorf("status should be %v but got: %v", expected, result.status)
+	}
+	for _, expected := range []string{
+		"certificate of " + okAddr + " (app.example.com) expires in 89 days",
+		"certificate of " + warningAddr + " (app.example.com) expires in 19 days",
+	} {
+		if !strings.Contains(result.message, expected) {
+			t.Errorf("message should contain %q but got: %q", expected, result.message)
+		}
+	}
+}
+
+func TestCertGenerator_Unknown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close() // nolint
+
+	g := NewCertGenerator(&config.CertCheck{Name: "cert", Host: host, Port: port, TimeoutSeconds: 1})
+	result, err := g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if expected := mackerel.CheckStatusUnknown; result.status != expected {
+		t.Errorf("status should be %v but got: %v", expected, result.status)
+	}
+}
+
+func TestNewCertGenerator(t *testing.T) {
+	testCases := []struct {
+		warningDays, criticalDays int
+		expectWarning             int
+		expectCritical            int
+	}{
+		{0, 0, 30, 14},
+		{60, 0, 60, 14},
+		{10, 0, 10, 10},
+		{0, 7, 30, 7},
+		{0, 45, 45, 45},
+	}
+	for _, tc := range testCases {
+		g := NewCertGenerator(&config.CertCheck{
+			WarningDays: tc.warningDays, CriticalDays: tc.criticalDays,
+		}).(*certGenerator)
+		if g.WarningDays != tc.expectWarning || g.CriticalDays != tc.expectCritical {
+			t.Errorf("days should be %d and %d but got: %d and %d",
+				tc.expectWarning, tc.expectCritical, g.WarningDays, g.CriticalDays)
+		}
+	}
+}
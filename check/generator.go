@@ -20,6 +20,16 @@ func NewResult(name string, message string, status mackerel.CheckStatus, occurre
 	return &Result{name, message, status, occurredAt}
 }
 
+// Message returns the message of the result
+func (r *Result) Message() string {
+	return r.message
+}
+
+// Status returns the status of the result
+func (r *Result) Status() mackerel.CheckStatus {
+	return r.status
+}
+
 // Generator interface generate check plugin result
 type Generator interface {
 	Generate(context.Context) (*Result, error)
@@ -0,0 +1,311 @@
+package check
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+const (
+	logCheckMaxLines      = 10
+	logCheckMaxMessageLen = 1024
+)
+
+type logGenerator struct {
+	config.LogCheck
+	statePath  string
+	state      *logState
+	lastResult *Result
+}
+
+// logState is the offsets of the log files persisted across the restarts
+type logState struct {
+	Files map[string]*logFileState `json:"files"`
+}
+
+type logFileState struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// NewLogGenerator creates a new check generator which tails the log files and
+// reports the lines matching the pattern. The offsets of the files are saved
+// in stateDir, and the files are read from the end on the first check.
+func NewLogGenerator(c *config.LogCheck, stateDir string) Generator {
+	return &logGenerator{
+		LogCheck:  *c,
+		statePath: filepath.Join(stateDir, logStateFileName(c)),
+	}
+}
+
+// logStateFileName builds the file name of the state from the escaped name,
+// which never contains the path separators, and the hash of the name and the
+// file to distinguish the names escaped to the same one
+func logStateFileName(c *config.LogCheck) string {
+	h := fnv.New32a()
+	h.Write([]byte(c.Name + "\x00" + c.File)) // nolint
+	return fmt.Sprintf("%s-%08x.json", url.PathEscape(c.Name), h.Sum32())
+}
+
+// Config gets check generator config
+func (g *logGenerator) Config() mackerel.CheckConfig {
+	return mackerel.CheckConfig{Name: g.Name, Memo: g.Memo}
+}
+
+// Generate generates check report
+func (g *logGenerator) Generate(ctx context.Context) (*Result, error) {
+	now := time.Now()
+	newResult := g.check(ctx, now)
+
+	lastResult := g.lastResult
+	g.lastResult = newResult
+	if lastResult == nil {
+		return newResult, nil
+	}
+	if lastResult.status == mackerel.CheckStatusOK && newResult.status == mackerel.CheckStatusOK {
+		// do not report ok -> ok
+		return nil, nil
+	}
+	return newResult, nil
+}
+
+func (g *logGenerator) check(ctx context.Context, now time.Time) *Result {
+	initial := g.state == nil
+	if initial {
+		state, err := loadLogState(g.statePath)
+		if err != nil {
+			logger.Warningf("log check %s: failed to load state: %s", g.Name, err)
+		}
+		g.state = state
+		// read from the end on the first check without the saved state
+		initial = len(state.Files) == 0
+	}
+
+	files, err := filepath.Glob(g.File)
+	if err != nil {
+		return NewResult(g.Name, err.Error(), mackerel.CheckStatusUnknown, now)
+	}
+	sort.Strings(files)
+
+	// the offsets by the inodes to follow the files renamed by the rotation
+	offsets := make(map[uint64]int64, len(g.state.Files))
+	for _, st := range g.state.Files {
+		offsets[st.Inode] = st.Offset
+	}
+	matched := make(map[string]bool, len(files))
+	for _, file := range files {
+		matched[file] = true
+	}
+
+	var count int
+	var lines []string
+	var errs []string
+	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		n, ls, err := g.tail(file, initial, offsets, matched)
+		if err != nil {
+			logger.Warningf("log check %s: %s", g.Name, err)
+			errs = append(errs, err.Error())
+			continue
+		}
+		count += n
+		for _, l := range ls {
+			if len(lines) < logCheckMaxLines {
+				lines = append(lines, l)
+			}
+		}
+	}
+	for file := range g.state.Files {
+		if !matched[file] {
+			delete(g.state.Files, file)
+		}
+	}
+	if err := saveLogState(g.statePath, g.state); err != nil {
+		logger.Warningf("log check %s: failed to save state: %s", g.Name, err)
+	}
+
+	if len(errs) > 0 && count == 0 {
+		return NewResult(g.Name, truncateMessage(strings.Join(errs, "\n")), mackerel.CheckStatusUnknown, now)
+	}
+	status := mackerel.CheckStatusOK
+	if count > g.CriticalOver {
+		status = mackerel.CheckStatusCritical
+	} else if count > g.WarningOver {
+		status = mackerel.CheckStatusWarning
+	}
+	message := fmt.Sprintf("%d lines matched /%s/ in %s", count, g.Pattern.String(), g.File)
+	if len(lines) > 0 {
+		message += "\n" + strings.Join(lines, "\n")
+	}
+	return NewResult(g.Name, truncateMessage(message), status, now)
+}
+
+// tail reads the lines appended to the file since the last check. When the
+// file is rotated, the rest of the old file is read if it remains in the same
+// directory and is not matched by the pattern of the files. When the file is
+// truncated, it is read from the beginning.
+func (g *logGenerator) tail(file string, initial bool, offsets map[uint64]int64, matched map[string]bool) (int, []string, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return 0, nil, err
+	}
+	if fi.IsDir() {
+		return 0, nil, nil
+	}
+	inode := fileInode(fi)
+	st, ok := g.state.Files[file]
+	if !ok {
+		st = &logFileState{Inode: inode}
+		if offset, ok := offsets[inode]; ok {
+			st.Offset = offset
+		} else if initial {
+			st.Offset = fi.Size()
+		}
+		g.state.Files[file] = st
+	}
+
+	var count int
+	var lines []string
+	if st.Inode != inode {
+		if rotated := findFileByInode(filepath.Dir(file), st.Inode); rotated != "" && !matched[rotated] {
+			n, ls, _, err := g.read(rotated, st.Offset)
+			if err != nil {
+				logger.Warningf("log check %s: %s", g.Name, err)
+			}
+			count, lines = n, ls
+		}
+		st.Inode, st.Offset = inode, 0
+	} else if fi.Size() < st.Offset {
+		st.Offset = 0
+	}
+
+	n, ls, offset, err := g.read(file, st.Offset)
+	if err != nil {
+		return 0, nil, err
+	}
+	st.Offset = offset
+	return count + n, append(lines, ls...), nil
+}
+
+// read reads the complete lines from the offset, and returns the number of
+// matched lines, the first matched lines and the offset after the last line
+func (g *logGenerator) read(file string, offset int64) (int, []string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, nil, offset, err
+	}
+	defer f.Close() // nolint
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil, offset, err
+	}
+
+	var count int
+	var lines []string
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// leave the incomplete line for the next check
+			if err == io.EOF {
+				err = nil
+			}
+			return count, lines, offset, err
+		}
+		offset += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+		if !g.Pattern.MatchString(line) {
+			continue
+		}
+		if g.Exclude.Regexp != nil && g.Exclude.MatchString(line) {
+			continue
+		}
+		count++
+		if len(lines) < logCheckMaxLines {
+			lines = append(lines, line)
+		}
+	}
+}
+
+func fileInode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+func findFileByInode(dir string, inode uint64) string {
+	if inode == 0 {
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if fileInode(fi) == inode {
+			return filepath.Join(dir, e.Name())
+		}
+	}
+	return ""
+}
+
+func truncateMessage(message string) string {
+	if len(message) <= logCheckMaxMessageLen {
+		return message
+	}
+	return strings.ToValidUTF8(message[:logCheckMaxMessageLen-3], "") + "..."
+}
+
+func loadLogState(path string) (*logState, error) {
+	state := &logState{Files: make(map[string]*logFileState)}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return &logState{Files: make(map[string]*logFileState)}, err
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]*logFileState)
+	}
+	return state, nil
+}
+
+func saveLogState(path string, state *logState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// write to the temporary file and rename not to break the state
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
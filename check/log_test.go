@@ -0,0 +1,224 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func appendFile(t *testing.T, file, content string) {
+	t.Helper()
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	defer f.Close() // nolint
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+}
+
+func TestLogGenerator(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "log")
+	if err := os.Mkdir(logDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(logDir, "app.log")
+	appendFile(t, file, "ERROR before the agent starts\n")
+
+	conf := &config.LogCheck{
+		Name:         "app-error",
+		File:         filepath.Join(logDir, "*.log"),
+		Pattern:      config.Regexpwrapper{Regexp: regexp.MustCompile("ERROR")},
+		Exclude:      config.Regexpwrapper{Regexp: regexp.MustCompile("healthcheck")},
+		WarningOver:  0,
+		CriticalOver: 2,
+	}
+	stateDir := filepath.Join(dir, "state")
+	ctx := context.Background()
+
+	testCases := []struct {
+		name    string
+		prepare func()
+		status  mackerel.CheckStatus
+		lines   []string
+		report  bool
+	}{
+		{
+			name:   "read from the end",
+			status: mackerel.CheckStatusOK,
+			report: true,
+		},
+		{
+			name: "no matched lines",
+			prepare: func() {
+				appendFile(t, file, "INFO ok\nERROR healthcheck\n")
+			},
+			status: mackerel.CheckStatusOK,
+		},
+		{
+			name: "warning",
+			prepare: func() {
+				appendFile(t, file, "INFO ok\nERROR first\nERROR incomplete")
+			},
+			status: mackerel.CheckStatusWarning,
+			lines:  []string{"ERROR first"},
+			report: true,
+		},
+		{
+			name: "critical",
+			prepare: func() {
+				appendFile(t, file, " line\nERROR second\nERROR third\n")
+			},
+			status: mackerel.CheckStatusCritical,
+			lines:  []string{"ERROR incomplete line", "ERROR second", "ERROR third"},
+			report: true,
+		},
+		{
+			name: "rotated",
+			prepare: func() {
+				appendFile(t, file, "ERROR before rotation\n")
+				if err := os.Rename(file, file+".1"); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, file, "ERROR after rotation\n")
+			},
+			status: mackerel.CheckStatusWarning,
+			lines:  []string{"ERROR before rotation", "ERROR after rotation"},
+			report: true,
+		},
+		{
+			name: "truncated",
+			prepare: func() {
+				if err := os.Truncate(file, 0); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, file, "ERROR truncated\n")
+			},
+			status: mackerel.CheckStatusWarning,
+			lines:  []string{"ERROR truncated"},
+			report: true,
+		},
+		{
+			name: "new file",
+			prepare: func() {
+				appendFile(t, filepath.Join(logDir, "new.log"), "ERROR new\n")
+			},
+			status: mackerel.CheckStatusWarning,
+			lines:  []string{"ERROR new"},
+			report: true,
+		},
+		{
+			name:   "ok",
+			status: mackerel.CheckStatusOK,
+			report: true,
+		},
+	}
+
+	g := NewLogGenerator(conf, stateDir)
+	for _, tc := range testCases {
+		if tc.prepare != nil {
+			tc.prepare()
+		}
+		result, err := g.Generate(ctx)
+		if err != nil {
+			t.Fatalf("%s: should not raise error: %v", tc.name, err)
+		}
+		if !tc.report {
+			if result != nil {
+				t.Errorf("%s: should not report but got: %+v", tc.name, result)
+			}
+			continue
+		}
+		if result == nil {
+			t.Fatalf("%s: should report", tc.name)
+		}
+		if result.status != tc.status {
+			t.Errorf("%s: status should be %v but got: %v", tc.name, tc.status, result.status)
+		}
+		lines := strings.Split(result.message, "\n")[1:]
+		if strings.Join(lines, "\n") != strings.Join(tc.lines, "\n") {
+			t.Errorf("%s: matched lines should be %q but got: %q", tc.name, tc.lines, lines)
+		}
+	}
+
+	// resume from the saved offsets after restart
+	appendFile(t, file, "ERROR after restart\n")
+	g = NewLogGenerator(conf, stateDir)
+	result, err := g.Generate(ctx)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if expected := "1 lines matched /ERROR/ in " + conf.File + "\nERROR after restart"; result.message != expected {
+		t.Errorf("message should be %q but got: %q", expected, result.message)
+	}
+}
+
+func TestLogGenerator_NoFiles(t *testing.T) {
+	g := NewLogGenerator(&config.LogCheck{
+		Name:    "app-error",
+		File:    filepath.Join(t.TempDir(), "*.log"),
+		Pattern: config.Regexpwrapper{Regexp: regexp.MustCompile("ERROR")},
+	}, t.TempDir())
+	result, err := g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if expected := mackerel.CheckStatusOK; result.status != expected {
+		t.Errorf("status should be %v but got: %v", expected, result.status)
+	}
+}
+
+func TestLogGenerator_StatePath(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := filepath.Join(dir, "root", "logcheck")
+	file := filepath.Join(dir, "app.log")
+	appendFile(t, file, "ERROR\n")
+
+	names := []string{"../../foo", "a/b", "..", "app-error"}
+	for _, name := range names {
+		g := NewLogGenerator(&config.LogCheck{
+			Name:    name,
+			File:    file,
+			Pattern: config.Regexpwrapper{Regexp: regexp.MustCompile("ERROR")},
+		}, stateDir)
+		if _, err := g.Generate(context.Background()); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		statePath := g.(*logGenerator).statePath
+		if filepath.Dir(statePath) != stateDir {
+			t.Errorf("state of %q should be saved in %s but got: %s", name, stateDir, statePath)
+		}
+		if _, err := os.Stat(statePath); err != nil {
+			t.Errorf("state of %q should be saved: %v", name, err)
+		}
+	}
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(names) {
+		t.Errorf("state files should be created for each name but got: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "foo.json")); err == nil {
+		t.Errorf("state should not be saved outside the state directory")
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	message := truncateMessage(strings.Repeat("あ", 500))
+	if len(message) > logCheckMaxMessageLen {
+		t.Errorf("message should be truncated but got length %d", len(message))
+	}
+	if !strings.HasSuffix(message, "...") || !strings.HasPrefix(message, "あ") {
+		t.Errorf("unexpected message: %q", message)
+	}
+}
@@ -1,6 +1,7 @@
 package check
 
 import (
+	"errors"
 	"sync"
 
 	mackerel "github.com/mackerelio/mackerel-client-go"
@@ -47,13 +48,18 @@ func (s *sender) post(reports []*mackerel.CheckReport) error {
 	if err == nil {
 		n := copy(s.pendingReports, s.pendingReports[postIndex+1:])
 		s.pendingReports = s.pendingReports[:n]
-	} else {
+	} else if errors.Is(err, api.ErrCircuitOpen) {
+		logger.Debugf("postponed posting check monitoring reports: %s", err)
+	} else if !api.IsPermanent(err) {
 		logger.Warningf("failed to post check monitoring reports but will retry posting: %s", err)
 	}
 	if len(s.pendingReports) > maxPendingReports {
 		n := copy(s.pendingReports, s.pendingReports[len(s.pendingReports)-maxPendingReports:])
 		s.pendingReports = s.pendingReports[:n]
 	}
+	if api.IsPermanent(err) {
+		return err
+	}
 	return nil
 }
 
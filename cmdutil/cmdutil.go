@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"os"
 	"os/exec"
+	"syscall"
 	"time"
 
 	"github.com/Songmu/timeout"
@@ -19,10 +21,7 @@ var (
 
 // RunCommand executes command with context
 func RunCommand(ctx context.Context, command Command, user string, env []string, timeoutDuration time.Duration) (string, string, int, error) {
-	args := command.ToArgs()
-	if user != "" {
-		args = append([]string{"sudo", "-Eu", user}, args...)
-	}
+	args := commandArgs(command, user)
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Env = append(os.Environ(), env...)
 	outbuf, errbuf := new(bytes.Buffer), new(bytes.Buffer)
@@ -49,3 +48,37 @@ func RunCommand(ctx context.Context, command Command, user string, env []string,
 	}
 	return outbuf.String(), errbuf.String(), exitCode, err
 }
+
+// StartCommand starts the long-running command and returns the pipes of stdout and stderr.
+// The command is terminated when the context is done, and killed if it does not exit in time.
+func StartCommand(ctx context.Context, command Command, user string, env []string) (*exec.Cmd, io.ReadCloser, io.ReadCloser, error) {
+	args := commandArgs(command, user)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	// terminate the process group since the shell may not propagate the signal
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = timeoutKillAfter
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, err
+	}
+	return cmd, stdout, stderr, nil
+}
+
+func commandArgs(command Command, user string) []string {
+	args := command.ToArgs()
+	if user != "" {
+		args = append([]string{"sudo", "-Eu", user}, args...)
+	}
+	return args
+}
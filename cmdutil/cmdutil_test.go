@@ -2,6 +2,7 @@ package cmdutil
 
 import (
 	"context"
+	"io"
 	"strings"
 	"syscall"
 	"testing"
@@ -82,3 +83,28 @@ func TestRunCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestStartCommand(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, stdout, stderr, err := StartCommand(ctx, CommandString("echo bar >&2; echo $FOO; sleep 10"), "", []string{"FOO=foo"})
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(stdout, buf); err != nil || string(buf) != "foo\n" {
+		t.Errorf("invalid stdout (out: %q, err: %v)", buf, err)
+	}
+
+	start := time.Now()
+	cancel()
+	errbuf, err := io.ReadAll(stderr)
+	if err != nil || string(errbuf) != "bar\n" {
+		t.Errorf("invalid stderr (out: %q, err: %v)", errbuf, err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("should raise error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command should be terminated but took %s", elapsed)
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// CertCheck represents the check which dials the host and port, or the list
+// of host:port targets with TLS, and reports the days until the certificates
+// expire. The certificate chains are verified unless insecureSkipVerify is set.
+type CertCheck struct {
+	Name               string   `yaml:"name"`
+	Host               string   `yaml:"host"`
+	Port               string   `yaml:"port"`
+	Targets            []string `yaml:"targets"`
+	ServerName         string   `yaml:"serverName"`
+	InsecureSkipVerify bool     `yaml:"insecureSkipVerify"`
+	WarningDays        int      `yaml:"warningDays"`
+	CriticalDays       int      `yaml:"criticalDays"`
+	TimeoutSeconds     int      `yaml:"timeoutSeconds"`
+	Memo               string   `yaml:"memo"`
+}
+
+func (c *CertCheck) validate() error {
+	if c.Name == "" {
+		return errors.New("specify name of cert check")
+	}
+	if len(c.Targets) > 0 {
+		if c.Host != "" || c.Port != "" {
+			return fmt.Errorf("specify either host and port or targets of cert check %s", c.Name)
+		}
+		for _, target := range c.Targets {
+			if host, port, err := net.SplitHostPort(target); err != nil || host == "" || port == "" {
+				return fmt.Errorf("invalid target of cert check %s: %q", c.Name, target)
+			}
+		}
+	} else {
+		if c.Host == "" {
+			return fmt.Errorf("specify host of cert check %s", c.Name)
+		}
+		if c.Port == "" {
+			return fmt.Errorf("specify port of cert check %s", c.Name)
+		}
+	}
+	if c.WarningDays < 0 || c.CriticalDays < 0 {
+		return fmt.Errorf("warningDays and criticalDays of cert check %s should not be negative", c.Name)
+	}
+	if c.WarningDays > 0 && c.CriticalDays > c.WarningDays {
+		return fmt.Errorf("criticalDays of cert check %s should not be greater than warningDays", c.Name)
+	}
+	if c.TimeoutSeconds < 0 {
+		return errors.New("timeoutSeconds should be positive")
+	}
+	return nil
+}
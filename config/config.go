@@ -10,6 +10,7 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,24 +22,41 @@ import (
 )
 
 const (
-	timeout     = 3 * time.Second
-	defaultRoot = "/var/tmp/mackerel-container-agent"
+	timeout          = 3 * time.Second
+	defaultRoot      = "/var/tmp/mackerel-container-agent"
+	maxMetricsJitter = 60
 )
 
+var serviceNamePattern = regexp.MustCompile(`\A[a-zA-Z0-9][-_a-zA-Z0-9]{1,62}\z`)
+
 // Config represents agent configuration
 type Config struct {
-	Apibase           string        `yaml:"apibase"`
-	Apikey            string        `yaml:"apikey"`
-	Root              string        `yaml:"root"`
-	Roles             []string      `yaml:"roles"`
-	DisplayName       string        `yaml:"displayName"`
-	Memo              string        `yaml:"memo"`
-	IgnoreContainer   Regexpwrapper `yaml:"ignoreContainer"`
-	ReadinessProbe    *Probe        `yaml:"readinessProbe"`
-	HostStatusOnStart HostStatus    `yaml:"hostStatusOnStart"`
-	HostIDStore       HostIDStore   `yaml:"hostIdStore"`
-	MetricPlugins     []*MetricPlugin
-	CheckPlugins      []*CheckPlugin
+	Apibase              string             `yaml:"apibase"`
+	Apikey               string             `yaml:"apikey"`
+	Root                 string             `yaml:"root"`
+	Roles                []string           `yaml:"roles"`
+	DisplayName          string             `yaml:"displayName"`
+	Memo                 string             `yaml:"memo"`
+	IgnoreContainer      Regexpwrapper      `yaml:"ignoreContainer"`
+	ReadinessProbe       *Probe             `yaml:"readinessProbe"`
+	HostStatusOnStart    HostStatus         `yaml:"hostStatusOnStart"`
+	HostStatusOnStopping HostStatus         `yaml:"hostStatusOnStopping"`
+	HostIDStore          HostIDStore        `yaml:"hostIdStore"`
+	HostIDStoreLocation  string             `yaml:"hostIdStoreLocation"`
+	HostIDStoreRegion    string             `yaml:"hostIdStoreRegion"`
+	PodReadinessCheck    *PodReadinessCheck `yaml:"podReadinessCheck"`
+	RetireOnExit         RetireOnExit       `yaml:"retireOnExit"`
+	Outputs              []*Output          `yaml:"outputs"`
+	OTLPReceiver         *OTLPReceiver      `yaml:"otlpReceiver"`
+	MetricFilters        *MetricFilters     `yaml:"metricFilters"`
+	DerivedMetrics       []*DerivedMetric   `yaml:"derivedMetrics"`
+	MetricsJitterSeconds int                `yaml:"metricsJitterSeconds"`
+	HTTPMetrics          []*HTTPMetric      `yaml:"httpMetrics"`
+	LogMetrics           []*LogMetric       `yaml:"logMetrics"`
+	LogChecks            []*LogCheck        `yaml:"logChecks"`
+	CertChecks           []*CertCheck       `yaml:"certChecks"`
+	MetricPlugins        []*MetricPlugin
+	CheckPlugins         []*CheckPlugin
 }
 
 // Regexpwrapper wraps regexp.Regexp
@@ -73,21 +91,48 @@ func (s *HostStatus) UnmarshalText(text []byte) error {
 type HostIDStore string
 
 const (
-	HostIDStoreFile   HostIDStore = "file"
-	HostIDStoreMemory HostIDStore = "memory"
+	HostIDStoreFile       HostIDStore = "file"
+	HostIDStoreMemory     HostIDStore = "memory"
+	HostIDStoreKubernetes HostIDStore = "kubernetes"
+	HostIDStoreS3         HostIDStore = "s3"
+	HostIDStoreHTTP       HostIDStore = "http"
 )
 
 // UnmarshalText decodes HostIDStore string
 func (s *HostIDStore) UnmarshalText(text []byte) error {
 	storeType := string(text)
 	if storeType != string(HostIDStoreFile) &&
-		storeType != string(HostIDStoreMemory) {
+		storeType != string(HostIDStoreMemory) &&
+		storeType != string(HostIDStoreKubernetes) &&
+		storeType != string(HostIDStoreS3) &&
+		storeType != string(HostIDStoreHTTP) {
 		return fmt.Errorf("invalid HostIDStore: %q", storeType)
 	}
 	*s = HostIDStore(storeType)
 	return nil
 }
 
+// RetireOnExit represents the policy to retire the host on exit
+type RetireOnExit string
+
+const (
+	RetireOnExitAlways   RetireOnExit = "always"
+	RetireOnExitNever    RetireOnExit = "never"
+	RetireOnExitStopping RetireOnExit = "stopping"
+)
+
+// UnmarshalText decodes RetireOnExit string
+func (r *RetireOnExit) UnmarshalText(text []byte) error {
+	policy := string(text)
+	if policy != string(RetireOnExitAlways) &&
+		policy != string(RetireOnExitNever) &&
+		policy != string(RetireOnExitStopping) {
+		return fmt.Errorf("invalid retireOnExit: %q", policy)
+	}
+	*r = RetireOnExit(policy)
+	return nil
+}
+
 func parseConfig(data []byte) (*Config, error) {
 	var conf struct {
 		Config `yaml:",inline"`
@@ -97,6 +142,10 @@ func parseConfig(data []byte) (*Config, error) {
 			TimeoutSeconds int             `yaml:"timeoutSeconds"`
 			Env            Env             `yaml:"env"`
 			Memo           string          `yaml:"memo"`
+			Service        string          `yaml:"service"`
+			Format         PluginFormat    `yaml:"format"`
+			Mode           PluginMode      `yaml:"mode"`
+			Diff           DiffMetrics     `yaml:"diff"`
 		} `yaml:"plugin"`
 	}
 	err := yaml.Unmarshal(data, &conf)
@@ -107,9 +156,17 @@ func parseConfig(data []byte) (*Config, error) {
 		if plugin.Command.IsEmpty() {
 			return nil, errors.New("specify command of metric plugin")
 		}
+		if plugin.Service != "" && !serviceNamePattern.MatchString(plugin.Service) {
+			return nil, fmt.Errorf("invalid service of metric plugin %s: %q", name, plugin.Service)
+		}
+		if plugin.Mode == PluginModeStream && plugin.Format == PluginFormatJSON {
+			return nil, fmt.Errorf("json format is not supported by stream metric plugin %s", name)
+		}
 		conf.MetricPlugins = append(conf.MetricPlugins, &MetricPlugin{
 			Name: name, Command: plugin.Command, User: plugin.User, Env: plugin.Env,
 			Timeout: time.Duration(plugin.TimeoutSeconds) * time.Second,
+			Service: plugin.Service, Format: plugin.Format, Mode: plugin.Mode,
+			Diff: plugin.Diff,
 		})
 	}
 	for name, plugin := range conf.Plugin["checks"] {
@@ -131,11 +188,71 @@ func parseConfig(data []byte) (*Config, error) {
 		return conf.CheckPlugins[i].Name < conf.CheckPlugins[j].Name
 	})
 
+	if err := validateMetricsJitter(conf.MetricsJitterSeconds); err != nil {
+		return nil, err
+	}
 	if conf.ReadinessProbe != nil {
 		if err := conf.ReadinessProbe.validate(); err != nil {
 			return nil, err
 		}
 	}
+	if conf.PodReadinessCheck != nil {
+		if err := conf.PodReadinessCheck.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if conf.OTLPReceiver != nil {
+		if err := conf.OTLPReceiver.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if conf.MetricFilters != nil {
+		if err := conf.MetricFilters.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range conf.DerivedMetrics {
+		if err := m.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range conf.HTTPMetrics {
+		if err := m.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range conf.LogMetrics {
+		if err := m.validate(); err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[string]bool, len(conf.CheckPlugins)+len(conf.LogChecks)+len(conf.CertChecks))
+	for _, p := range conf.CheckPlugins {
+		names[p.Name] = true
+	}
+	for _, c := range conf.LogChecks {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate name of check: %s", c.Name)
+		}
+		names[c.Name] = true
+	}
+	for _, c := range conf.CertChecks {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate name of check: %s", c.Name)
+		}
+		names[c.Name] = true
+	}
+	for _, o := range conf.Outputs {
+		if err := o.validate(); err != nil {
+			return nil, err
+		}
+	}
 	return &conf.Config, nil
 }
 
@@ -196,6 +313,14 @@ func load(ctx context.Context, location string) (*Config, error) {
 		}
 	}
 
+	if conf.HostStatusOnStopping == "" {
+		if s := os.Getenv("MACKEREL_HOST_STATUS_ON_STOPPING"); s != "" {
+			if err := conf.HostStatusOnStopping.UnmarshalText([]byte(s)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if conf.HostIDStore == "" {
 		if s := os.Getenv("MACKEREL_HOST_ID_STORE"); s != "" {
 			if err := conf.HostIDStore.UnmarshalText([]byte(s)); err != nil {
@@ -204,9 +329,41 @@ func load(ctx context.Context, location string) (*Config, error) {
 		}
 	}
 
+	if conf.HostIDStoreLocation == "" {
+		conf.HostIDStoreLocation = os.Getenv("MACKEREL_HOST_ID_STORE_LOCATION")
+	}
+
+	if conf.RetireOnExit == "" {
+		if s := os.Getenv("MACKEREL_RETIRE_ON_EXIT"); s != "" {
+			if err := conf.RetireOnExit.UnmarshalText([]byte(s)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if conf.MetricsJitterSeconds == 0 {
+		if s := os.Getenv("MACKEREL_METRICS_JITTER_SECONDS"); s != "" {
+			jitter, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse MACKEREL_METRICS_JITTER_SECONDS: %w", err)
+			}
+			if err := validateMetricsJitter(jitter); err != nil {
+				return nil, err
+			}
+			conf.MetricsJitterSeconds = jitter
+		}
+	}
+
 	return conf, nil
 }
 
+func validateMetricsJitter(jitter int) error {
+	if jitter < 0 || jitter >= maxMetricsJitter {
+		return fmt.Errorf("metricsJitterSeconds should be between 0 and %d: %d", maxMetricsJitter-1, jitter)
+	}
+	return nil
+}
+
 func fetch(ctx context.Context, location string) ([]byte, error) {
 	u, err := url.Parse(location)
 	if err != nil {
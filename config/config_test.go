@@ -338,6 +338,25 @@ plugin:
         - 30
         - /usr/local/bin/sample-plugin.rb
 
+    queue:
+      command: mackerel-plugin-sqs
+      service: my-service
+
+    json:
+      command: /usr/local/bin/json-plugin
+      format: json
+
+    jmx:
+      command: /usr/local/bin/jmx-plugin
+      mode: stream
+      diff:
+        - jmx.gc.*.count
+        - jmx.requests
+
+    nginx:
+      command: mackerel-plugin-nginx
+      diff: true
+
   checks:
     procs:
       command: "check-procs --pattern=/usr/sbin/sshd --warning-under=1"
@@ -353,10 +372,31 @@ plugin:
 		Apikey:  "",
 		Root:    defaultRoot,
 		MetricPlugins: []*MetricPlugin{
+			&MetricPlugin{
+				Name:    "jmx",
+				Command: cmdutil.CommandString("/usr/local/bin/jmx-plugin"),
+				Mode:    PluginModeStream,
+				Diff:    DiffMetrics{Patterns: []string{"jmx.gc.*.count", "jmx.requests"}},
+			},
+			&MetricPlugin{
+				Name:    "json",
+				Command: cmdutil.CommandString("/usr/local/bin/json-plugin"),
+				Format:  PluginFormatJSON,
+			},
 			&MetricPlugin{
 				Name:    "mysql",
 				Command: cmdutil.CommandString("mackerel-plugin-mysql"),
 			},
+			&MetricPlugin{
+				Name:    "nginx",
+				Command: cmdutil.CommandString("mackerel-plugin-nginx"),
+				Diff:    DiffMetrics{All: true},
+			},
+			&MetricPlugin{
+				Name:    "queue",
+				Command: cmdutil.CommandString("mackerel-plugin-sqs"),
+				Service: "my-service",
+			},
 			&MetricPlugin{
 				Name:    "redis6379",
 				Command: cmdutil.CommandString("mackerel-plugin-redis -port=6379 -timeout=5 -metric-key-prefix=redis6379"),
@@ -394,6 +434,60 @@ plugin:
 	}
 }
 
+func TestMetricPluginInvalidService(t *testing.T) {
+	_, err := parseConfig([]byte(`
+plugin:
+  metrics:
+    queue:
+      command: mackerel-plugin-sqs
+      service: my service
+`))
+	if err == nil {
+		t.Errorf("should raise error")
+	}
+}
+
+func TestMetricPluginInvalidFormat(t *testing.T) {
+	_, err := parseConfig([]byte(`
+plugin:
+  metrics:
+    sample:
+      command: sample-plugin
+      format: yaml
+`))
+	if err == nil {
+		t.Errorf("should raise error")
+	}
+}
+
+func TestMetricPluginStreamJSON(t *testing.T) {
+	_, err := parseConfig([]byte(`
+plugin:
+  metrics:
+    sample:
+      command: sample-plugin
+      mode: stream
+      format: json
+`))
+	if err == nil {
+		t.Errorf("should raise error")
+	}
+}
+
+func TestMetricPluginInvalidDiff(t *testing.T) {
+	_, err := parseConfig([]byte(`
+plugin:
+  metrics:
+    sample:
+      command: sample-plugin
+      diff:
+        foo: true
+`))
+	if err == nil {
+		t.Errorf("should raise error")
+	}
+}
+
 func TestReadinessProbe(t *testing.T) {
 	proxy, _ := url.Parse("http://proxy.example.com:8080")
 
@@ -738,6 +832,1160 @@ hostStatusOnStart: working
 	}
 }
 
+func TestHostStatusOnStopping(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		env       string
+		expect    HostStatus
+		shouldErr bool
+	}{
+		{
+			name: "poweroff",
+			config: `
+hostStatusOnStopping: poweroff
+`,
+			expect: mackerel.HostStatusPoweroff,
+		},
+		{
+			name: "error",
+			config: `
+hostStatusOnStopping: unknown
+`,
+			shouldErr: true,
+		},
+		{
+			name:   "env",
+			env:    "maintenance",
+			expect: mackerel.HostStatusMaintenance,
+		},
+		{
+			name: "config with env",
+			config: `
+hostStatusOnStopping: poweroff
+`,
+			env:    "maintenance",
+			expect: mackerel.HostStatusPoweroff,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MACKEREL_HOST_STATUS_ON_STOPPING", tc.env)
+			file := newConfigFile(t, tc.config)
+
+			conf, err := load(context.Background(), file)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && conf.HostStatusOnStopping != tc.expect {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.HostStatusOnStopping)
+			}
+		})
+	}
+}
+
+func TestRetireOnExit(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		env       string
+		expect    RetireOnExit
+		shouldErr bool
+	}{
+		{
+			name:   "default",
+			expect: "",
+		},
+		{
+			name: "always",
+			config: `
+retireOnExit: always
+`,
+			expect: RetireOnExitAlways,
+		},
+		{
+			name: "never",
+			config: `
+retireOnExit: never
+`,
+			expect: RetireOnExitNever,
+		},
+		{
+			name: "stopping",
+			config: `
+retireOnExit: stopping
+`,
+			expect: RetireOnExitStopping,
+		},
+		{
+			name: "error",
+			config: `
+retireOnExit: unknown
+`,
+			shouldErr: true,
+		},
+		{
+			name:   "env",
+			env:    "stopping",
+			expect: RetireOnExitStopping,
+		},
+		{
+			name: "config with env",
+			config: `
+retireOnExit: never
+`,
+			env:    "stopping",
+			expect: RetireOnExitNever,
+		},
+		{
+			name:      "invalid env",
+			env:       "unknown",
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MACKEREL_RETIRE_ON_EXIT", tc.env)
+			file := newConfigFile(t, tc.config)
+
+			conf, err := load(context.Background(), file)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && conf.RetireOnExit != tc.expect {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.RetireOnExit)
+			}
+		})
+	}
+}
+
+func TestMetricsJitterSeconds(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		env       string
+		expect    int
+		shouldErr bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "config",
+			config: `
+metricsJitterSeconds: 30
+`,
+			expect: 30,
+		},
+		{
+			name: "too large",
+			config: `
+metricsJitterSeconds: 60
+`,
+			shouldErr: true,
+		},
+		{
+			name: "negative",
+			config: `
+metricsJitterSeconds: -1
+`,
+			shouldErr: true,
+		},
+		{
+			name:   "env",
+			env:    "15",
+			expect: 15,
+		},
+		{
+			name: "config with env",
+			config: `
+metricsJitterSeconds: 30
+`,
+			env:    "15",
+			expect: 30,
+		},
+		{
+			name:      "invalid env",
+			env:       "15s",
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MACKEREL_METRICS_JITTER_SECONDS", tc.env)
+			file := newConfigFile(t, tc.config)
+
+			conf, err := load(context.Background(), file)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && conf.MetricsJitterSeconds != tc.expect {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.MetricsJitterSeconds)
+			}
+		})
+	}
+}
+
+func TestHostIDStoreLocation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         string
+		env            string
+		expectStore    HostIDStore
+		expectLocation string
+		expectRegion   string
+		shouldErr      bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "s3",
+			config: `
+hostIdStore: s3
+hostIdStoreLocation: s3://mybucket/host-ids
+`,
+			expectStore:    HostIDStoreS3,
+			expectLocation: "s3://mybucket/host-ids",
+		},
+		{
+			name: "s3 with region",
+			config: `
+hostIdStore: s3
+hostIdStoreLocation: s3://mybucket/host-ids
+hostIdStoreRegion: cn-north-1
+`,
+			expectStore:    HostIDStoreS3,
+			expectLocation: "s3://mybucket/host-ids",
+			expectRegion:   "cn-north-1",
+		},
+		{
+			name: "kubernetes",
+			config: `
+hostIdStore: kubernetes
+`,
+			expectStore: HostIDStoreKubernetes,
+		},
+		{
+			name: "http with env",
+			config: `
+hostIdStore: http
+`,
+			env:            "https://example.com/host-ids",
+			expectStore:    HostIDStoreHTTP,
+			expectLocation: "https://example.com/host-ids",
+		},
+		{
+			name: "error",
+			config: `
+hostIdStore: unknown
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MACKEREL_HOST_ID_STORE_LOCATION", tc.env)
+			file := newConfigFile(t, tc.config)
+
+			conf, err := load(context.Background(), file)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf == nil {
+				return
+			}
+			if conf.HostIDStore != tc.expectStore {
+				t.Errorf("expect %#v, got %#v", tc.expectStore, conf.HostIDStore)
+			}
+			if conf.HostIDStoreLocation != tc.expectLocation {
+				t.Errorf("expect %#v, got %#v", tc.expectLocation, conf.HostIDStoreLocation)
+			}
+			if conf.HostIDStoreRegion != tc.expectRegion {
+				t.Errorf("expect %#v, got %#v", tc.expectRegion, conf.HostIDStoreRegion)
+			}
+		})
+	}
+}
+
+func TestPodReadinessCheck(t *testing.T) {
+	warningGracePeriod, criticalGracePeriod, zeroGracePeriod := 30, 120, 0
+	testCases := []struct {
+		name      string
+		config    string
+		expect    *PodReadinessCheck
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "default",
+			config: `
+podReadinessCheck: {}
+`,
+			expect: &PodReadinessCheck{},
+		},
+		{
+			name: "grace periods",
+			config: `
+podReadinessCheck:
+  name: readiness
+  memo: pod readiness
+  warningGracePeriodSeconds: 30
+  criticalGracePeriodSeconds: 120
+`,
+			expect: &PodReadinessCheck{
+				Name:                       "readiness",
+				Memo:                       "pod readiness",
+				WarningGracePeriodSeconds:  &warningGracePeriod,
+				CriticalGracePeriodSeconds: &criticalGracePeriod,
+			},
+		},
+		{
+			name: "zero grace period",
+			config: `
+podReadinessCheck:
+  warningGracePeriodSeconds: 0
+`,
+			expect: &PodReadinessCheck{
+				WarningGracePeriodSeconds: &zeroGracePeriod,
+			},
+		},
+		{
+			name: "negative grace period",
+			config: `
+podReadinessCheck:
+  criticalGracePeriodSeconds: -1
+`,
+			shouldErr: true,
+		},
+		{
+			name: "warning grace period greater than critical",
+			config: `
+podReadinessCheck:
+  warningGracePeriodSeconds: 300
+  criticalGracePeriodSeconds: 60
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.PodReadinessCheck, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.PodReadinessCheck)
+			}
+		})
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    []*Output
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "outputs",
+			config: `
+outputs:
+  - type: prometheusRemoteWrite
+    url: https://prometheus.example.com/api/v1/write
+    headers:
+      Authorization: Bearer xxx
+  - type: otlp
+    url: http://localhost:4318/v1/metrics
+  - type: jsonl
+    path: /var/log/metrics.jsonl
+  - type: stdout
+`,
+			expect: []*Output{
+				{
+					Type:    OutputTypePrometheusRemoteWrite,
+					URL:     "https://prometheus.example.com/api/v1/write",
+					Headers: map[string]string{"Authorization": "Bearer xxx"},
+				},
+				{Type: OutputTypeOTLP, URL: "http://localhost:4318/v1/metrics"},
+				{Type: OutputTypeJSONLines, Path: "/var/log/metrics.jsonl"},
+				{Type: OutputTypeStdout},
+			},
+		},
+		{
+			name: "invalid type",
+			config: `
+outputs:
+  - type: influxdb
+`,
+			shouldErr: true,
+		},
+		{
+			name: "no type",
+			config: `
+outputs:
+  - url: http://localhost:4318/v1/metrics
+`,
+			shouldErr: true,
+		},
+		{
+			name: "no url",
+			config: `
+outputs:
+  - type: otlp
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid url",
+			config: `
+outputs:
+  - type: prometheusRemoteWrite
+    url: localhost:9090
+`,
+			shouldErr: true,
+		},
+		{
+			name: "no path",
+			config: `
+outputs:
+  - type: jsonl
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.Outputs, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.Outputs)
+			}
+		})
+	}
+}
+
+func TestOTLPReceiver(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    *OTLPReceiver
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "default",
+			config: `
+otlpReceiver: {}
+`,
+			expect: &OTLPReceiver{HTTPAddress: "localhost:4318", GRPCAddress: "localhost:4317"},
+		},
+		{
+			name: "http only",
+			config: `
+otlpReceiver:
+  httpAddress: 127.0.0.1:14318
+`,
+			expect: &OTLPReceiver{HTTPAddress: "127.0.0.1:14318"},
+		},
+		{
+			name: "invalid address",
+			config: `
+otlpReceiver:
+  grpcAddress: localhost
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.OTLPReceiver, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.OTLPReceiver)
+			}
+		})
+	}
+}
+
+func TestMetricFilters(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    *MetricFilters
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "filters",
+			config: `
+metricFilters:
+  include:
+    - ^container\.
+    - ^custom\.
+  exclude:
+    - ^custom\.noisy\.
+  rename:
+    - from: ^custom\.redis6379\.(.+)$
+      to: custom.redis.$1
+`,
+			expect: &MetricFilters{
+				Include: []Regexpwrapper{
+					{regexp.MustCompile(`^container\.`)},
+					{regexp.MustCompile(`^custom\.`)},
+				},
+				Exclude: []Regexpwrapper{{regexp.MustCompile(`^custom\.noisy\.`)}},
+				Rename: []*MetricRename{
+					{From: Regexpwrapper{regexp.MustCompile(`^custom\.redis6379\.(.+)$`)}, To: "custom.redis.$1"},
+				},
+			},
+		},
+		{
+			name: "invalid pattern",
+			config: `
+metricFilters:
+  exclude:
+    - ^custom\.(
+`,
+			shouldErr: true,
+		},
+		{
+			name: "no rename template",
+			config: `
+metricFilters:
+  rename:
+    - from: ^custom\.foo\.
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.MetricFilters, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.MetricFilters)
+			}
+		})
+	}
+}
+
+func TestDerivedMetrics(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    []*DerivedMetric
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "derived metrics",
+			config: `
+derivedMetrics:
+  - name: custom.utilization.*
+    expression: container.cpu.*.usage / container.cpu.*.limit * 100
+    displayName: CPU utilization
+    unit: percentage
+  - name: custom.app.total
+    expression: custom.app.foo + custom.app.bar
+`,
+			expect: []*DerivedMetric{
+				{
+					Name:        "custom.utilization.*",
+					Expression:  "container.cpu.*.usage / container.cpu.*.limit * 100",
+					DisplayName: "CPU utilization",
+					Unit:        "percentage",
+				},
+				{Name: "custom.app.total", Expression: "custom.app.foo + custom.app.bar"},
+			},
+		},
+		{
+			name: "not custom metric",
+			config: `
+derivedMetrics:
+  - name: container.cpu.total
+    expression: container.cpu.app.usage
+`,
+			shouldErr: true,
+		},
+		{
+			name: "too short name",
+			config: `
+derivedMetrics:
+  - name: custom.total
+    expression: custom.app.foo
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid wildcard",
+			config: `
+derivedMetrics:
+  - name: custom.utilization.app*
+    expression: container.cpu.*.usage
+`,
+			shouldErr: true,
+		},
+		{
+			name: "no expression",
+			config: `
+derivedMetrics:
+  - name: custom.app.total
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.DerivedMetrics, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.DerivedMetrics)
+			}
+		})
+	}
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.example.com:8080")
+
+	testCases := []struct {
+		name      string
+		config    string
+		expect    []*HTTPMetric
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "http metrics",
+			config: `
+httpMetrics:
+  - name: app
+    url: http://localhost:8080/stats
+    headers:
+      - name: Authorization
+        value: Bearer xxx
+    proxy: http://proxy.example.com:8080
+    timeoutSeconds: 5
+    metrics:
+      - path: $.requests.total
+        name: requests.total
+        diff: true
+      - path: $.queues.*.depth
+        name: queue.*.depth
+`,
+			expect: []*HTTPMetric{
+				{
+					Name:           "app",
+					URL:            "http://localhost:8080/stats",
+					Headers:        []Header{{Name: "Authorization", Value: "Bearer xxx"}},
+					Proxy:          URLWrapper{proxy},
+					TimeoutSeconds: 5,
+					Metrics: []*HTTPMetricMapping{
+						{Path: "$.requests.total", Name: "requests.total", Diff: true},
+						{Path: "$.queues.*.depth", Name: "queue.*.depth"},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid name",
+			config: `
+httpMetrics:
+  - name: my.app
+    url: http://localhost:8080/stats
+    metrics:
+      - path: $.requests
+        name: requests
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid service",
+			config: `
+httpMetrics:
+  - name: app
+    url: http://localhost:8080/stats
+    service: my.service
+    metrics:
+      - path: $.requests
+        name: requests
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid url",
+			config: `
+httpMetrics:
+  - name: app
+    url: localhost:8080/stats
+    metrics:
+      - path: $.requests
+        name: requests
+`,
+			shouldErr: true,
+		},
+		{
+			name: "no metrics",
+			config: `
+httpMetrics:
+  - name: app
+    url: http://localhost:8080/stats
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid path",
+			config: `
+httpMetrics:
+  - name: app
+    url: http://localhost:8080/stats
+    metrics:
+      - path: requests
+        name: requests
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.HTTPMetrics, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.HTTPMetrics)
+			}
+		})
+	}
+}
+
+func TestLogMetrics(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    []*LogMetric
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "log metrics",
+			config: `
+logMetrics:
+  - name: nginx
+    file: /var/log/nginx/access.log
+    metrics:
+      - name: status_5xx
+        pattern: '" 5\d\d '
+      - name: request_time
+        pattern: 'request_time=([0-9.]+)'
+`,
+			expect: []*LogMetric{
+				{
+					Name: "nginx",
+					File: "/var/log/nginx/access.log",
+					Metrics: []*LogMetricPattern{
+						{Name: "status_5xx", Pattern: Regexpwrapper{regexp.MustCompile(`" 5\d\d `)}},
+						{Name: "request_time", Pattern: Regexpwrapper{regexp.MustCompile(`request_time=([0-9.]+)`)}},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid name",
+			config: `
+logMetrics:
+  - name: nginx.access
+    file: /var/log/nginx/access.log
+    metrics:
+      - name: status_5xx
+        pattern: '" 5\d\d '
+`,
+			shouldErr: true,
+		},
+		{
+			name: "service",
+			config: `
+logMetrics:
+  - name: nginx
+    file: /var/log/nginx/access.log
+    service: web
+    metrics:
+      - name: status_5xx
+        pattern: '" 5\d\d '
+`,
+			expect: []*LogMetric{
+				{
+					Name:    "nginx",
+					File:    "/var/log/nginx/access.log",
+					Service: "web",
+					Metrics: []*LogMetricPattern{
+						{Name: "status_5xx", Pattern: Regexpwrapper{regexp.MustCompile(`" 5\d\d `)}},
+					},
+				},
+			},
+		},
+		{
+			name: "no metrics",
+			config: `
+logMetrics:
+  - name: nginx
+    file: /var/log/nginx/access.log
+`,
+			shouldErr: true,
+		},
+		{
+			name: "too many groups",
+			config: `
+logMetrics:
+  - name: nginx
+    file: /var/log/nginx/access.log
+    metrics:
+      - name: request_time
+        pattern: '(request|upstream)_time=([0-9.]+)'
+`,
+			shouldErr: true,
+		},
+		{
+			name: "duplicate name",
+			config: `
+logMetrics:
+  - name: nginx
+    file: /var/log/nginx/access.log
+    metrics:
+      - name: status
+        pattern: '" 5\d\d '
+      - name: status
+        pattern: '" 4\d\d '
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.LogMetrics, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.LogMetrics)
+			}
+		})
+	}
+}
+
+func TestLogChecks(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    []*LogCheck
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "log checks",
+			config: `
+logChecks:
+  - name: app-error
+    file: /var/log/app/*.log
+    pattern: ERROR|FATAL
+    exclude: healthcheck
+    warningOver: 3
+    criticalOver: 10
+    memo: errors of app
+`,
+			expect: []*LogCheck{
+				{
+					Name:         "app-error",
+					File:         "/var/log/app/*.log",
+					Pattern:      Regexpwrapper{regexp.MustCompile("ERROR|FATAL")},
+					Exclude:      Regexpwrapper{regexp.MustCompile("healthcheck")},
+					WarningOver:  3,
+					CriticalOver: 10,
+					Memo:         "errors of app",
+				},
+			},
+		},
+		{
+			name: "no pattern",
+			config: `
+logChecks:
+  - name: app-error
+    file: /var/log/app.log
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid pattern",
+			config: `
+logChecks:
+  - name: app-error
+    file: /var/log/app.log
+    pattern: "ERROR("
+`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid file",
+			config: `
+logChecks:
+  - name: app-error
+    file: /var/log/[app.log
+    pattern: ERROR
+`,
+			shouldErr: true,
+		},
+		{
+			name: "negative threshold",
+			config: `
+logChecks:
+  - name: app-error
+    file: /var/log/app.log
+    pattern: ERROR
+    warningOver: -1
+`,
+			shouldErr: true,
+		},
+		{
+			name: "duplicate name",
+			config: `
+plugin:
+  checks:
+    app-error:
+      command: check-log --file /var/log/app.log --pattern ERROR
+logChecks:
+  - name: app-error
+    file: /var/log/app.log
+    pattern: ERROR
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.LogChecks, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.LogChecks)
+			}
+		})
+	}
+}
+
+func TestCertChecks(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expect    []*CertCheck
+		shouldErr bool
+	}{
+		{
+			name:   "not configured",
+			config: ``,
+		},
+		{
+			name: "cert checks",
+			config: `
+certChecks:
+  - name: app-cert
+    host: 10.0.0.1
+    port: 443
+    serverName: app.example.com
+    warningDays: 30
+    criticalDays: 7
+    timeoutSeconds: 5
+    memo: certificate of app
+`,
+			expect: []*CertCheck{
+				{
+					Name:           "app-cert",
+					Host:           "10.0.0.1",
+					Port:           "443",
+					ServerName:     "app.example.com",
+					WarningDays:    30,
+					CriticalDays:   7,
+					TimeoutSeconds: 5,
+					Memo:           "certificate of app",
+				},
+			},
+		},
+		{
+			name: "targets",
+			config: `
+certChecks:
+  - name: app-cert
+    targets:
+      - 10.0.0.1:443
+      - app.example.com:8443
+    insecureSkipVerify: true
+`,
+			expect: []*CertCheck{
+				{
+					Name:               "app-cert",
+					Targets:            []string{"10.0.0.1:443", "app.example.com:8443"},
+					InsecureSkipVerify: true,
+				},
+			},
+		},
+		{
+			name: "no port",
+			config: `
+certChecks:
+  - name: app-cert
+    host: app.example.com
+`,
+			shouldErr: true,
+		},
+		{
+			name: "both host and targets",
+			config: `
+certChecks:
+  - name: app-cert
+    host: app.example.com
+    port: 443
+    targets:
+      - app.example.com:443
+`,
+			shouldErr: true,
+		},
+		{
+			name: "target without port",
+			config: `
+certChecks:
+  - name: app-cert
+    targets:
+      - app.example.com
+`,
+			shouldErr: true,
+		},
+		{
+			name: "critical days greater than warning days",
+			config: `
+certChecks:
+  - name: app-cert
+    host: app.example.com
+    port: 443
+    warningDays: 7
+    criticalDays: 30
+`,
+			shouldErr: true,
+		},
+		{
+			name: "duplicate name",
+			config: `
+logChecks:
+  - name: app
+    file: /var/log/app.log
+    pattern: ERROR
+certChecks:
+  - name: app
+    host: app.example.com
+    port: 443
+`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := parseConfig([]byte(tc.config))
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatalf("should raise error: %v", err)
+			}
+			if conf != nil && !reflect.DeepEqual(conf.CertChecks, tc.expect) {
+				t.Errorf("expect %#v, got %#v", tc.expect, conf.CertChecks)
+			}
+		})
+	}
+}
+
 func newHTTPServer(t testing.TB, content string) *httptest.Server {
 	t.Helper()
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
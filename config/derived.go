@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DerivedMetric represents the metric computed from the collected metric values.
+// The name can contain a wildcard segment (*), which is bound to the wildcard of
+// the metric names in the expression, like container names.
+type DerivedMetric struct {
+	Name        string `yaml:"name"`
+	Expression  string `yaml:"expression"`
+	DisplayName string `yaml:"displayName"`
+	Unit        string `yaml:"unit"`
+}
+
+func (m *DerivedMetric) validate() error {
+	if m.Name == "" {
+		return errors.New("specify name of derived metric")
+	}
+	xs := strings.Split(m.Name, ".")
+	if len(xs) < 3 || xs[0] != "custom" {
+		return fmt.Errorf("name of derived metric should be custom.<graph>.<metric>: %q", m.Name)
+	}
+	var wildcards int
+	for _, x := range xs {
+		if x == "" || strings.Contains(x, "*") && x != "*" {
+			return fmt.Errorf("invalid name of derived metric: %q", m.Name)
+		}
+		if x == "*" {
+			wildcards++
+		}
+	}
+	if wildcards > 1 {
+		return fmt.Errorf("name of derived metric can contain one wildcard at most: %q", m.Name)
+	}
+	if m.Expression == "" {
+		return fmt.Errorf("specify expression of derived metric %s", m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var httpMetricNamePattern = regexp.MustCompile(`\A[-_a-zA-Z0-9]+\z`)
+
+// HTTPMetric represents the metric generator which scrapes the JSON endpoint
+type HTTPMetric struct {
+	Name           string               `yaml:"name"`
+	URL            string               `yaml:"url"`
+	Method         string               `yaml:"method"`
+	Headers        []Header             `yaml:"headers"`
+	Proxy          URLWrapper           `yaml:"proxy"`
+	TimeoutSeconds int                  `yaml:"timeoutSeconds"`
+	Metrics        []*HTTPMetricMapping `yaml:"metrics"`
+	Service        string               `yaml:"service"`
+}
+
+// HTTPMetricMapping represents the mapping from the JSONPath to the metric name.
+// The wildcards (*) in the name are replaced with the keys matched by the
+// wildcards in the path, so the name has as many wildcards as the path.
+type HTTPMetricMapping struct {
+	Path string `yaml:"path"`
+	Name string `yaml:"name"`
+	Diff bool   `yaml:"diff"`
+}
+
+func (m *HTTPMetric) validate() error {
+	if !httpMetricNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid name of http metric: %q", m.Name)
+	}
+	if m.URL == "" {
+		return fmt.Errorf("specify url of http metric %s", m.Name)
+	}
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url of http metric %s: %q", m.Name, m.URL)
+	}
+	if m.Service != "" && !serviceNamePattern.MatchString(m.Service) {
+		return fmt.Errorf("invalid service of http metric %s: %q", m.Name, m.Service)
+	}
+	if m.TimeoutSeconds < 0 {
+		return errors.New("timeoutSeconds should be positive")
+	}
+	if len(m.Metrics) == 0 {
+		return fmt.Errorf("specify metrics of http metric %s", m.Name)
+	}
+	for _, mm := range m.Metrics {
+		if !strings.HasPrefix(mm.Path, "$") {
+			return fmt.Errorf("path of http metric %s should start with $: %q", m.Name, mm.Path)
+		}
+		if mm.Name == "" {
+			return fmt.Errorf("specify name of http metric %s: %s", m.Name, mm.Path)
+		}
+	}
+	return nil
+}
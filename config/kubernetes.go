@@ -0,0 +1,26 @@
+package config
+
+import "errors"
+
+// PodReadinessCheck represents the readiness check of the pod and its containers on Kubernetes.
+// The grace periods are pointers to tell zero from the default.
+type PodReadinessCheck struct {
+	Name                       string `yaml:"name"`
+	Memo                       string `yaml:"memo"`
+	WarningGracePeriodSeconds  *int   `yaml:"warningGracePeriodSeconds"`
+	CriticalGracePeriodSeconds *int   `yaml:"criticalGracePeriodSeconds"`
+}
+
+func (c *PodReadinessCheck) validate() error {
+	if c.WarningGracePeriodSeconds != nil && *c.WarningGracePeriodSeconds < 0 {
+		return errors.New("warningGracePeriodSeconds should not be negative")
+	}
+	if c.CriticalGracePeriodSeconds != nil && *c.CriticalGracePeriodSeconds < 0 {
+		return errors.New("criticalGracePeriodSeconds should not be negative")
+	}
+	if c.WarningGracePeriodSeconds != nil && c.CriticalGracePeriodSeconds != nil &&
+		*c.WarningGracePeriodSeconds > *c.CriticalGracePeriodSeconds {
+		return errors.New("warningGracePeriodSeconds should not be greater than criticalGracePeriodSeconds")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// LogCheck represents the check which tails the log files and counts the
+// lines matching the pattern, like check-log of go-check-plugins
+type LogCheck struct {
+	Name         string        `yaml:"name"`
+	File         string        `yaml:"file"`
+	Pattern      Regexpwrapper `yaml:"pattern"`
+	Exclude      Regexpwrapper `yaml:"exclude"`
+	WarningOver  int           `yaml:"warningOver"`
+	CriticalOver int           `yaml:"criticalOver"`
+	Memo         string        `yaml:"memo"`
+}
+
+func (c *LogCheck) validate() error {
+	if c.Name == "" {
+		return errors.New("specify name of log check")
+	}
+	if c.File == "" {
+		return fmt.Errorf("specify file of log check %s", c.Name)
+	}
+	if _, err := filepath.Match(c.File, ""); err != nil {
+		return fmt.Errorf("invalid file of log check %s: %q", c.Name, c.File)
+	}
+	if c.Pattern.Regexp == nil {
+		return fmt.Errorf("specify pattern of log check %s", c.Name)
+	}
+	if c.WarningOver < 0 || c.CriticalOver < 0 {
+		return fmt.Errorf("warningOver and criticalOver of log check %s should not be negative", c.Name)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// LogMetric represents the metric generator which tails the log files, the
+// named pipe or stdin (-), and counts the lines matching the patterns
+type LogMetric struct {
+	Name    string              `yaml:"name"`
+	File    string              `yaml:"file"`
+	Metrics []*LogMetricPattern `yaml:"metrics"`
+	Service string              `yaml:"service"`
+}
+
+// LogMetricPattern represents the pattern of the log lines. The metric is the
+// number of the matched lines, or the p50, p95 and max of the numbers captured
+// by the group if the pattern has one.
+type LogMetricPattern struct {
+	Name    string        `yaml:"name"`
+	Pattern Regexpwrapper `yaml:"pattern"`
+}
+
+func (m *LogMetric) validate() error {
+	if !httpMetricNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid name of log metric: %q", m.Name)
+	}
+	if m.File == "" {
+		return fmt.Errorf("specify file of log metric %s", m.Name)
+	}
+	if _, err := filepath.Match(m.File, ""); err != nil {
+		return fmt.Errorf("invalid file of log metric %s: %q", m.Name, m.File)
+	}
+	if m.Service != "" && !serviceNamePattern.MatchString(m.Service) {
+		return fmt.Errorf("invalid service of log metric %s: %q", m.Name, m.Service)
+	}
+	if len(m.Metrics) == 0 {
+		return fmt.Errorf("specify metrics of log metric %s", m.Name)
+	}
+	names := make(map[string]bool, len(m.Metrics))
+	for _, p := range m.Metrics {
+		if !httpMetricNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("invalid name of log metric %s: %q", m.Name, p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate name of log metric %s: %q", m.Name, p.Name)
+		}
+		names[p.Name] = true
+		if p.Pattern.Regexp == nil {
+			return fmt.Errorf("specify pattern of log metric %s: %s", m.Name, p.Name)
+		}
+		if p.Pattern.NumSubexp() > 1 {
+			return fmt.Errorf("pattern of log metric %s should have at most one group: %s", m.Name, p.Name)
+		}
+	}
+	return nil
+}
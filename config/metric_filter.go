@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MetricFilters represents the rules to filter and rename the metrics
+type MetricFilters struct {
+	Include []Regexpwrapper `yaml:"include"`
+	Exclude []Regexpwrapper `yaml:"exclude"`
+	Rename  []*MetricRename `yaml:"rename"`
+}
+
+// MetricRename represents the rule to rename the metrics matching From to the
+// template To, which can refer to the capture groups like $1 or ${name}
+type MetricRename struct {
+	From Regexpwrapper `yaml:"from"`
+	To   string        `yaml:"to"`
+}
+
+func (f *MetricFilters) validate() error {
+	for _, r := range append(f.Include, f.Exclude...) {
+		if r.Regexp == nil {
+			return errors.New("specify pattern of metricFilters")
+		}
+	}
+	for _, r := range f.Rename {
+		if r.From.Regexp == nil {
+			return errors.New("specify from of metricFilters rename")
+		}
+		if r.To == "" {
+			return fmt.Errorf("specify to of metricFilters rename: %s", r.From)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	defaultOTLPHTTPAddress = "localhost:4318"
+	defaultOTLPGRPCAddress = "localhost:4317"
+)
+
+// OTLPReceiver represents the OTLP metrics receiver
+type OTLPReceiver struct {
+	HTTPAddress string `yaml:"httpAddress"`
+	GRPCAddress string `yaml:"grpcAddress"`
+}
+
+func (r *OTLPReceiver) validate() error {
+	if r.HTTPAddress == "" && r.GRPCAddress == "" {
+		r.HTTPAddress, r.GRPCAddress = defaultOTLPHTTPAddress, defaultOTLPGRPCAddress
+	}
+	for _, addr := range []string{r.HTTPAddress, r.GRPCAddress} {
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid address of otlpReceiver: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// OutputType represents the type of metric output
+type OutputType string
+
+const (
+	OutputTypePrometheusRemoteWrite OutputType = "prometheusRemoteWrite"
+	OutputTypeOTLP                  OutputType = "otlp"
+	OutputTypeJSONLines             OutputType = "jsonl"
+	OutputTypeStdout                OutputType = "stdout"
+)
+
+// UnmarshalText decodes OutputType string
+func (t *OutputType) UnmarshalText(text []byte) error {
+	typ := string(text)
+	if typ != string(OutputTypePrometheusRemoteWrite) &&
+		typ != string(OutputTypeOTLP) &&
+		typ != string(OutputTypeJSONLines) &&
+		typ != string(OutputTypeStdout) {
+		return fmt.Errorf("invalid output type: %q", typ)
+	}
+	*t = OutputType(typ)
+	return nil
+}
+
+// Output represents an additional output of metric values besides Mackerel
+type Output struct {
+	Type    OutputType        `yaml:"type"`
+	URL     string            `yaml:"url"`
+	Path    string            `yaml:"path"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+func (o *Output) validate() error {
+	switch o.Type {
+	case OutputTypePrometheusRemoteWrite, OutputTypeOTLP:
+		if o.URL == "" {
+			return fmt.Errorf("specify url of %s output", o.Type)
+		}
+		u, err := url.Parse(o.URL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid url of %s output: %q", o.Type, o.URL)
+		}
+	case OutputTypeJSONLines:
+		if o.Path == "" {
+			return errors.New("specify path of jsonl output")
+		}
+	case OutputTypeStdout:
+	default:
+		return errors.New("specify type of output")
+	}
+	return nil
+}
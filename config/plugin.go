@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/mackerelio/mackerel-container-agent/cmdutil"
@@ -13,6 +15,81 @@ type MetricPlugin struct {
 	User    string
 	Env     Env
 	Timeout time.Duration
+	Service string
+	Format  PluginFormat
+	Mode    PluginMode
+	Diff    DiffMetrics
+}
+
+// DiffMetrics represents the counter metrics of plugin, whose values are
+// posted as the differences per minute. It is either true for all the metrics
+// or the list of metric name patterns, where * matches a name segment.
+type DiffMetrics struct {
+	All      bool
+	Patterns []string
+}
+
+// UnmarshalYAML decodes the boolean or the list of metric name patterns
+func (d *DiffMetrics) UnmarshalYAML(unmarshal func(v any) error) error {
+	var all bool
+	if err := unmarshal(&all); err == nil {
+		d.All = all
+		return nil
+	}
+	var patterns []string
+	if err := unmarshal(&patterns); err != nil {
+		return errors.New("diff should be a boolean or a list of metric names")
+	}
+	for _, p := range patterns {
+		if p == "" {
+			return errors.New("empty metric name in diff")
+		}
+	}
+	d.Patterns = patterns
+	return nil
+}
+
+// IsEmpty reports whether no metrics are counters
+func (d DiffMetrics) IsEmpty() bool {
+	return !d.All && len(d.Patterns) == 0
+}
+
+// PluginFormat represents the output format of metric plugin
+type PluginFormat string
+
+const (
+	PluginFormatText PluginFormat = "text"
+	PluginFormatJSON PluginFormat = "json"
+)
+
+// UnmarshalText decodes PluginFormat string
+func (f *PluginFormat) UnmarshalText(text []byte) error {
+	format := string(text)
+	if format != string(PluginFormatText) &&
+		format != string(PluginFormatJSON) {
+		return fmt.Errorf("invalid plugin format: %q", format)
+	}
+	*f = PluginFormat(format)
+	return nil
+}
+
+// PluginMode represents how the metric plugin is executed
+type PluginMode string
+
+const (
+	PluginModeExec   PluginMode = "exec"
+	PluginModeStream PluginMode = "stream"
+)
+
+// UnmarshalText decodes PluginMode string
+func (m *PluginMode) UnmarshalText(text []byte) error {
+	mode := string(text)
+	if mode != string(PluginModeExec) &&
+		mode != string(PluginModeStream) {
+		return fmt.Errorf("invalid plugin mode: %q", mode)
+	}
+	*m = PluginMode(mode)
+	return nil
 }
 
 // CheckPlugin represents check plugin
@@ -9,7 +9,9 @@ import (
 )
 
 type collector struct {
-	generators []Generator
+	generators     []Generator
+	derivedMetrics []*DerivedMetric
+	filter         *filter
 }
 
 func newCollector(generators []Generator) *collector {
@@ -18,13 +20,13 @@ func newCollector(generators []Generator) *collector {
 	}
 }
 
-func (c *collector) collect(ctx context.Context) (Values, error) {
+func (c *collector) collect(ctx context.Context) (Values, Timestamps, error) {
 	var wg sync.WaitGroup
-	values := make(Values)
+	values, timestamps := make(Values), make(Timestamps)
 	mu := new(sync.Mutex)
 	for _, g := range c.generators {
 		wg.Go(func() {
-			vs, err := g.Generate(ctx)
+			vs, ts, err := generate(ctx, g)
 			if err != nil {
 				logger.Errorf("%s", err)
 				return
@@ -32,10 +34,22 @@ func (c *collector) collect(ctx context.Context) (Values, error) {
 			mu.Lock()
 			defer mu.Unlock()
 			maps.Copy(values, vs)
+			maps.Copy(timestamps, ts)
 		})
 	}
 	wg.Wait()
-	return values, nil
+	// derive the metrics before filtering to allow excluding the source metrics
+	deriveValues(c.derivedMetrics, values)
+	values, timestamps = c.filter.apply(values, timestamps)
+	return values, timestamps, nil
+}
+
+func generate(ctx context.Context, g Generator) (Values, Timestamps, error) {
+	if tg, ok := g.(TimestampGenerator); ok {
+		return tg.GenerateWithTimestamps(ctx)
+	}
+	values, err := g.Generate(ctx)
+	return values, nil, err
 }
 
 func (c *collector) collectGraphDefs(ctx context.Context) ([]*mackerel.GraphDefsParam, error) {
@@ -55,5 +69,6 @@ func (c *collector) collectGraphDefs(ctx context.Context) ([]*mackerel.GraphDefs
 		})
 	}
 	wg.Wait()
-	return graphDefs, nil
+	graphDefs = append(graphDefs, derivedGraphDefs(c.derivedMetrics)...)
+	return c.filter.applyGraphDefs(graphDefs), nil
 }
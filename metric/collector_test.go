@@ -9,7 +9,7 @@ import (
 func TestCollectorCollect(t *testing.T) {
 	ctx := context.Background()
 	c := newCollector(createMockGenerators())
-	values, err := c.collect(ctx)
+	values, timestamps, err := c.collect(ctx)
 
 	if err != nil {
 		t.Errorf("error should be nil but got: %+v", err)
@@ -28,4 +28,7 @@ func TestCollectorCollect(t *testing.T) {
 	if !reflect.DeepEqual(values, expectedValues) {
 		t.Errorf("values should be %+v but got: %+v", expectedValues, values)
 	}
+	if len(timestamps) != 0 {
+		t.Errorf("timestamps should be empty but got: %+v", timestamps)
+	}
 }
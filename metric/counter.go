@@ -0,0 +1,88 @@
+package metric
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+const counterStaleness = 10 * time.Minute
+
+// counter converts the counter values of plugin to the differences per
+// minute, as the diff option of mackerel-agent. The first value, the value
+// decreased by the counter reset and the value after the staleness are skipped.
+type counter struct {
+	all      bool
+	patterns []*regexp.Regexp
+	prev     map[string]counterValue
+	mu       sync.Mutex
+}
+
+type counterValue struct {
+	value float64
+	time  int64
+}
+
+func newCounter(conf config.DiffMetrics) *counter {
+	if conf.IsEmpty() {
+		return nil
+	}
+	c := &counter{all: conf.All, prev: make(map[string]counterValue)}
+	for _, p := range conf.Patterns {
+		c.patterns = append(c.patterns, regexp.MustCompile(
+			`\A`+strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, `[^.]+`)+`\z`,
+		))
+	}
+	return c
+}
+
+func (c *counter) match(name string) bool {
+	if c.all {
+		return true
+	}
+	for _, p := range c.patterns {
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply replaces the counter values in place. The names are matched without
+// the prefix of plugin metrics.
+func (c *counter) apply(values Values, timestamps Timestamps, prefix string, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, value := range values {
+		if !c.match(strings.TrimPrefix(name, prefix)) {
+			continue
+		}
+		t, ok := timestamps[name]
+		if !ok {
+			t = now.Unix()
+		}
+		prev, ok := c.prev[name]
+		if ok && t == prev.time {
+			// the plugin has not updated the value yet
+			delete(values, name)
+			continue
+		}
+		c.prev[name] = counterValue{value, t}
+		if !ok || t < prev.time || t-prev.time > int64(counterStaleness/time.Second) || value < prev.value {
+			delete(values, name)
+			continue
+		}
+		values[name] = (value - prev.value) * 60 / float64(t-prev.time)
+	}
+	for name, prev := range c.prev {
+		if prev.time < now.Add(-counterStaleness).Unix() {
+			delete(c.prev, name)
+		}
+	}
+}
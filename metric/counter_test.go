@@ -0,0 +1,89 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func TestCounterApply(t *testing.T) {
+	c := newCounter(config.DiffMetrics{Patterns: []string{"nginx.requests", "jvm.gc.*.count"}})
+	now := time.Unix(1700000000, 0)
+	testCases := []struct {
+		name       string
+		values     Values
+		timestamps Timestamps
+		now        time.Time
+		expected   Values
+	}{
+		{
+			name:     "first values",
+			values:   Values{"custom.nginx.requests": 100, "custom.jvm.gc.young.count": 10, "custom.nginx.connections": 5},
+			now:      now,
+			expected: Values{"custom.nginx.connections": 5},
+		},
+		{
+			name:     "differences per minute",
+			values:   Values{"custom.nginx.requests": 160, "custom.jvm.gc.young.count": 15, "custom.nginx.connections": 6},
+			now:      now.Add(30 * time.Second),
+			expected: Values{"custom.nginx.requests": 120, "custom.jvm.gc.young.count": 10, "custom.nginx.connections": 6},
+		},
+		{
+			name:       "plugin timestamps",
+			values:     Values{"custom.nginx.requests": 220, "custom.jvm.gc.young.count": 15},
+			timestamps: Timestamps{"custom.nginx.requests": now.Add(90 * time.Second).Unix()},
+			now:        now.Add(95 * time.Second),
+			expected:   Values{"custom.nginx.requests": 60, "custom.jvm.gc.young.count": 0},
+		},
+		{
+			name:       "not updated",
+			values:     Values{"custom.nginx.requests": 220},
+			timestamps: Timestamps{"custom.nginx.requests": now.Add(90 * time.Second).Unix()},
+			now:        now.Add(100 * time.Second),
+			expected:   Values{},
+		},
+		{
+			name:     "counter reset",
+			values:   Values{"custom.nginx.requests": 10},
+			now:      now.Add(150 * time.Second),
+			expected: Values{},
+		},
+		{
+			name:     "after reset",
+			values:   Values{"custom.nginx.requests": 40},
+			now:      now.Add(210 * time.Second),
+			expected: Values{"custom.nginx.requests": 30},
+		},
+		{
+			name:     "stale",
+			values:   Values{"custom.nginx.requests": 100},
+			now:      now.Add(210*time.Second + counterStaleness + time.Second),
+			expected: Values{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.apply(tc.values, tc.timestamps, pluginPrefix, tc.now)
+			if !reflect.DeepEqual(tc.values, tc.expected) {
+				t.Errorf("values should be %v but got %v", tc.expected, tc.values)
+			}
+		})
+	}
+}
+
+func TestCounterApply_All(t *testing.T) {
+	c := newCounter(config.DiffMetrics{All: true})
+	now := time.Unix(1700000000, 0)
+	c.apply(Values{"foo.bar": 1, "foo.baz": 2}, nil, "", now)
+	values := Values{"foo.bar": 3, "foo.baz": 2}
+	c.apply(values, nil, "", now.Add(time.Minute))
+	if expected := (Values{"foo.bar": 2, "foo.baz": 0}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+
+	if newCounter(config.DiffMetrics{}) != nil {
+		t.Errorf("counter should be nil without diff metrics")
+	}
+}
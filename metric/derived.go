@@ -0,0 +1,337 @@
+package metric
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+// DerivedMetric computes the metric values from the collected ones by the
+// expression. The expression supports +, -, *, /, parentheses, numbers and
+// metric names. Since the metric names can contain hyphens, put spaces around
+// the minus operator. A wildcard segment (*) in the metric names is bound to
+// the same name segment in the whole expression, and replaces the wildcard of
+// the derived metric name.
+type DerivedMetric struct {
+	name        string
+	expr        node
+	pattern     *regexp.Regexp
+	displayName string
+	unit        string
+}
+
+// NewDerivedMetric parses the expression of the derived metric
+func NewDerivedMetric(conf *config.DerivedMetric) (*DerivedMetric, error) {
+	expr, err := parseExpression(conf.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("derived metric %s: %w", conf.Name, err)
+	}
+	m := &DerivedMetric{
+		name:        conf.Name,
+		expr:        expr,
+		displayName: conf.DisplayName,
+		unit:        conf.Unit,
+	}
+	wildcard := strings.Contains(conf.Name, "*")
+	// the bindings are enumerated by the first wildcard name in sorted order,
+	// which is enough since all the names are required to evaluate
+	for _, name := range slices.Sorted(maps.Keys(metricNames(expr))) {
+		if !strings.Contains(name, "*") {
+			continue
+		}
+		if !wildcard {
+			return nil, fmt.Errorf("derived metric %s: specify wildcard in the name for %s", conf.Name, name)
+		}
+		if m.pattern == nil {
+			m.pattern = regexp.MustCompile(`\A` + strings.ReplaceAll(regexp.QuoteMeta(name), `\*`, `([^.]+)`) + `\z`)
+		}
+	}
+	if wildcard && m.pattern == nil {
+		return nil, fmt.Errorf("derived metric %s: specify wildcard in the expression", conf.Name)
+	}
+	return m, nil
+}
+
+// derive evaluates the expression over the values. The metric values which
+// are missing or result in NaN or infinity are skipped.
+func (m *DerivedMetric) derive(values Values) Values {
+	derived := make(Values)
+	if m.pattern == nil {
+		if v, ok := m.eval(values, ""); ok {
+			derived[m.name] = v
+		}
+		return derived
+	}
+	for name := range values {
+		xs := m.pattern.FindStringSubmatch(name)
+		if xs == nil {
+			continue
+		}
+		if v, ok := m.eval(values, xs[1]); ok {
+			derived[strings.Replace(m.name, "*", xs[1], 1)] = v
+		}
+	}
+	return derived
+}
+
+func (m *DerivedMetric) eval(values Values, binding string) (float64, bool) {
+	v, ok := m.expr.eval(func(name string) (float64, bool) {
+		v, ok := values[strings.ReplaceAll(name, "*", binding)]
+		return v, ok
+	})
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+func deriveValues(derivedMetrics []*DerivedMetric, values Values) {
+	// the derived metrics can refer to the former ones
+	for _, m := range derivedMetrics {
+		for name, value := range m.derive(values) {
+			values[name] = value
+		}
+	}
+}
+
+// derivedGraphDefs builds the graph definitions of the derived metrics. The
+// graph is named after the metric name without the last segment. The wildcard
+// in the graph name is replaced with # to create the graph for each binding.
+func derivedGraphDefs(derivedMetrics []*DerivedMetric) []*mackerel.GraphDefsParam {
+	var graphDefs []*mackerel.GraphDefsParam
+	graphs := make(map[string]*mackerel.GraphDefsParam)
+	for _, m := range derivedMetrics {
+		name := m.name
+		graphName := name[:strings.LastIndexByte(name, '.')]
+		if strings.Contains(graphName, "*") {
+			graphName = strings.ReplaceAll(graphName, "*", "#")
+			name = strings.ReplaceAll(name, "*", "#")
+		}
+		graphDef, ok := graphs[graphName]
+		if !ok {
+			graphDef = &mackerel.GraphDefsParam{
+				Name:        graphName,
+				DisplayName: m.displayName,
+				Unit:        m.unit,
+			}
+			if graphDef.Unit == "" {
+				graphDef.Unit = "float"
+			}
+			graphs[graphName] = graphDef
+			graphDefs = append(graphDefs, graphDef)
+		}
+		graphDef.Metrics = append(graphDef.Metrics, &mackerel.GraphDefsMetric{
+			Name:        name,
+			DisplayName: m.displayName,
+		})
+	}
+	return graphDefs
+}
+
+type node interface {
+	eval(lookup func(string) (float64, bool)) (float64, bool)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(func(string) (float64, bool)) (float64, bool) {
+	return float64(n), true
+}
+
+type metricNode string
+
+func (n metricNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	return lookup(string(n))
+}
+
+type negNode struct {
+	x node
+}
+
+func (n *negNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	x, ok := n.x.eval(lookup)
+	return -x, ok
+}
+
+type binaryNode struct {
+	op   byte
+	x, y node
+}
+
+func (n *binaryNode) eval(lookup func(string) (float64, bool)) (float64, bool) {
+	x, ok := n.x.eval(lookup)
+	if !ok {
+		return 0, false
+	}
+	y, ok := n.y.eval(lookup)
+	if !ok {
+		return 0, false
+	}
+	switch n.op {
+	case '+':
+		return x + y, true
+	case '-':
+		return x - y, true
+	case '*':
+		return x * y, true
+	default:
+		return x / y, true
+	}
+}
+
+func metricNames(n node) map[string]bool {
+	names := make(map[string]bool)
+	var walk func(node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case metricNode:
+			names[string(n)] = true
+		case *negNode:
+			walk(n.x)
+		case *binaryNode:
+			walk(n.x)
+			walk(n.y)
+		}
+	}
+	walk(n)
+	return names
+}
+
+type exprParser struct {
+	src string
+	pos int
+}
+
+func parseExpression(src string) (node, error) {
+	p := &exprParser{src: src}
+	n, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q in expression: %q", p.src[p.pos], src)
+	}
+	return n, nil
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *exprParser) consume(ops string) (byte, bool) {
+	p.skipSpaces()
+	if p.pos < len(p.src) && strings.IndexByte(ops, p.src[p.pos]) >= 0 {
+		p.pos++
+		return p.src[p.pos-1], true
+	}
+	return 0, false
+}
+
+func (p *exprParser) parseSum() (node, error) {
+	x, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.consume("+-")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseProduct() (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.consume("*/")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if _, ok := p.consume("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negNode{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (node, error) {
+	if _, ok := p.consume("("); ok {
+		x, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.consume(")"); !ok {
+			return nil, fmt.Errorf("missing ) in expression: %q", p.src)
+		}
+		return x, nil
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("unexpected end of expression: %q", p.src)
+	}
+	start := p.pos
+	switch c := p.src[p.pos]; {
+	case '0' <= c && c <= '9' || c == '.':
+		for p.pos < len(p.src) && ('0' <= p.src[p.pos] && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number in expression: %q", p.src[start:p.pos])
+		}
+		return numberNode(v), nil
+	case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		for p.pos < len(p.src) && p.isNameChar() {
+			p.pos++
+		}
+		return metricNode(p.src[start:p.pos]), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q in expression: %q", c, p.src)
+	}
+}
+
+// isNameChar reports whether the current character belongs to the metric name.
+// The asterisk is a wildcard only when it is a whole segment, otherwise it is
+// the multiplication operator.
+func (p *exprParser) isNameChar() bool {
+	switch c := p.src[p.pos]; {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '-':
+		return true
+	case c == '.':
+		return true
+	case c == '*':
+		return p.src[p.pos-1] == '.' && (p.pos+1 == len(p.src) || p.src[p.pos+1] == '.')
+	default:
+		return false
+	}
+}
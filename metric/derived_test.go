@@ -0,0 +1,187 @@
+package metric
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func TestParseExpression(t *testing.T) {
+	values := Values{
+		"container.cpu.app.usage":     50.0,
+		"container.cpu.app.limit":     200.0,
+		"container.cpu.my-app.usage":  30.0,
+		"custom.foo.bar":              3.0,
+		"custom.foo.baz":              4.0,
+		"container.memory.app-1.used": 10.0,
+	}
+	testCases := []struct {
+		expr     string
+		expected float64
+		ok       bool
+		err      bool
+	}{
+		{expr: "1 + 2 * 3", expected: 7, ok: true},
+		{expr: "(1 + 2) * 3", expected: 9, ok: true},
+		{expr: "-custom.foo.bar - -1", expected: -2, ok: true},
+		{expr: "10 / 4 - 1.5", expected: 1, ok: true},
+		{expr: "container.cpu.app.usage / container.cpu.app.limit * 100", expected: 25, ok: true},
+		{expr: "container.cpu.app.usage/container.cpu.app.limit*100", expected: 25, ok: true},
+		{expr: "container.cpu.my-app.usage + container.memory.app-1.used", expected: 40, ok: true},
+		{expr: "custom.foo.bar*custom.foo.baz", expected: 12, ok: true},
+		{expr: "custom.foo.bar + custom.foo.missing"},
+		{expr: "1 +", err: true},
+		{expr: "(1 + 2", err: true},
+		{expr: "1 2", err: true},
+		{expr: "1 % 2", err: true},
+		{expr: "1..2", err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := parseExpression(tc.expr)
+			if tc.err {
+				if err == nil {
+					t.Errorf("should raise error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			v, ok := expr.eval(func(name string) (float64, bool) {
+				v, ok := values[name]
+				return v, ok
+			})
+			if ok != tc.ok || v != tc.expected {
+				t.Errorf("expected (%v, %v) but got (%v, %v)", tc.expected, tc.ok, v, ok)
+			}
+		})
+	}
+}
+
+func TestDerivedMetric(t *testing.T) {
+	newDerivedMetric := func(name, expr string) *DerivedMetric {
+		m, err := NewDerivedMetric(&config.DerivedMetric{Name: name, Expression: expr})
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		return m
+	}
+	values := Values{
+		"container.cpu.app.usage":   50.0,
+		"container.cpu.app.limit":   200.0,
+		"container.cpu.nginx.usage": 10.0,
+		"container.cpu.nginx.limit": 100.0,
+		"container.cpu.init.usage":  10.0,
+		"container.cpu.init.limit":  0.0,
+		"container.cpu.side.usage":  10.0,
+	}
+	deriveValues([]*DerivedMetric{
+		newDerivedMetric("custom.utilization.*", "container.cpu.*.usage / container.cpu.*.limit * 100"),
+		newDerivedMetric("custom.app.total", "container.cpu.app.usage + container.cpu.nginx.usage"),
+		newDerivedMetric("custom.app.doubled", "custom.app.total * 2"),
+	}, values)
+
+	expected := Values{
+		"custom.utilization.app":   25.0,
+		"custom.utilization.nginx": 10.0,
+		"custom.app.total":         60.0,
+		"custom.app.doubled":       120.0,
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("%s should be %v but got %v", name, value, values[name])
+		}
+	}
+	for _, name := range []string{"custom.utilization.init", "custom.utilization.side"} {
+		if _, ok := values[name]; ok {
+			t.Errorf("%s should be skipped", name)
+		}
+	}
+}
+
+func TestNewDerivedMetric_Pattern(t *testing.T) {
+	conf := &config.DerivedMetric{
+		Name:       "custom.utilization.*",
+		Expression: "container.memory.*.usage + container.cpu.*.usage + container.network.*.rxBytes",
+	}
+	for range 10 {
+		m, err := NewDerivedMetric(conf)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		if expected := `\Acontainer\.cpu\.([^.]+)\.usage\z`; m.pattern.String() != expected {
+			t.Fatalf("pattern should be %s but got %s", expected, m.pattern)
+		}
+	}
+}
+
+func TestNewDerivedMetric_Error(t *testing.T) {
+	testCases := []struct {
+		name, expr string
+	}{
+		{"custom.app.total", "container.cpu.app.usage +"},
+		{"custom.app.total", "container.cpu.*.usage"},
+		{"custom.utilization.*", "container.cpu.app.usage"},
+	}
+	for _, tc := range testCases {
+		if _, err := NewDerivedMetric(&config.DerivedMetric{Name: tc.name, Expression: tc.expr}); err == nil {
+			t.Errorf("should raise error: %s = %s", tc.name, tc.expr)
+		}
+	}
+}
+
+func TestDerivedGraphDefs(t *testing.T) {
+	var derivedMetrics []*DerivedMetric
+	for _, conf := range []*config.DerivedMetric{
+		{Name: "custom.utilization.*", Expression: "container.cpu.*.usage", DisplayName: "Utilization", Unit: "percentage"},
+		{Name: "custom.app.total", Expression: "1", DisplayName: "Total"},
+		{Name: "custom.app.avg", Expression: "2", DisplayName: "Average"},
+		{Name: "custom.container.*.ratio", Expression: "container.cpu.*.usage"},
+	} {
+		m, err := NewDerivedMetric(conf)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		derivedMetrics = append(derivedMetrics, m)
+	}
+	expected := []*mackerel.GraphDefsParam{
+		{
+			Name:        "custom.utilization",
+			DisplayName: "Utilization",
+			Unit:        "percentage",
+			Metrics:     []*mackerel.GraphDefsMetric{{Name: "custom.utilization.*", DisplayName: "Utilization"}},
+		},
+		{
+			Name:        "custom.app",
+			DisplayName: "Total",
+			Unit:        "float",
+			Metrics: []*mackerel.GraphDefsMetric{
+				{Name: "custom.app.total", DisplayName: "Total"},
+				{Name: "custom.app.avg", DisplayName: "Average"},
+			},
+		},
+		{
+			Name:    "custom.container.#",
+			Unit:    "float",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.container.#.ratio"}},
+		},
+	}
+	if graphDefs := derivedGraphDefs(derivedMetrics); !reflect.DeepEqual(graphDefs, expected) {
+		t.Errorf("graph definitions should be %#v but got %#v", expected, graphDefs)
+	}
+
+	c := newCollector(nil)
+	c.derivedMetrics = derivedMetrics[:1]
+	graphDefs, err := c.collectGraphDefs(context.Background())
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if !reflect.DeepEqual(graphDefs, expected[:1]) {
+		t.Errorf("graph definitions should be %#v but got %#v", expected[:1], graphDefs)
+	}
+}
@@ -0,0 +1,102 @@
+package metric
+
+import (
+	"maps"
+	"slices"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+// filter drops and renames the metrics by metricFilters. A nil filter passes
+// all the metrics through.
+type filter struct {
+	*config.MetricFilters
+}
+
+func newFilter(conf *config.MetricFilters) *filter {
+	if conf == nil {
+		return nil
+	}
+	return &filter{conf}
+}
+
+// name returns the renamed metric name, and reports whether the metric is kept
+func (f *filter) name(name string) (string, bool) {
+	if f == nil {
+		return name, true
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return "", false
+	}
+	if matchAny(f.Exclude, name) {
+		return "", false
+	}
+	return f.rename(name), true
+}
+
+func (f *filter) rename(name string) string {
+	for _, r := range f.Rename {
+		if m := r.From.FindStringSubmatchIndex(name); m != nil {
+			return string(r.From.ExpandString(nil, r.To, name, m))
+		}
+	}
+	return name
+}
+
+func matchAny(rs []config.Regexpwrapper, name string) bool {
+	for _, r := range rs {
+		if r.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply drops and renames the metric values. When the metrics are renamed to
+// the same name, the first one in the order of the original names is kept.
+func (f *filter) apply(values Values, timestamps Timestamps) (Values, Timestamps) {
+	if f == nil {
+		return values, timestamps
+	}
+	filtered, filteredTimestamps := make(Values, len(values)), make(Timestamps, len(timestamps))
+	sources := make(map[string]string, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		newName, ok := f.name(name)
+		if !ok {
+			continue
+		}
+		if source, ok := sources[newName]; ok {
+			logger.Warningf("metric %s is dropped since %s is renamed to the same name %s", name, source, newName)
+			continue
+		}
+		sources[newName] = name
+		filtered[newName] = values[name]
+		if t, ok := timestamps[name]; ok {
+			filteredTimestamps[newName] = t
+		}
+	}
+	return filtered, filteredTimestamps
+}
+
+// applyGraphDefs renames the graph definitions. The metric names of graph
+// definitions are not filtered since they may contain the wildcards.
+func (f *filter) applyGraphDefs(graphDefs []*mackerel.GraphDefsParam) []*mackerel.GraphDefsParam {
+	if f == nil || len(f.Rename) == 0 {
+		return graphDefs
+	}
+	renamed := make([]*mackerel.GraphDefsParam, len(graphDefs))
+	for i, graphDef := range graphDefs {
+		g := *graphDef
+		g.Name = f.rename(g.Name)
+		g.Metrics = make([]*mackerel.GraphDefsMetric, len(graphDef.Metrics))
+		for j, metric := range graphDef.Metrics {
+			m := *metric
+			m.Name = f.rename(m.Name)
+			g.Metrics[j] = &m
+		}
+		renamed[i] = &g
+	}
+	return renamed
+}
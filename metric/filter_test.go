@@ -0,0 +1,126 @@
+package metric
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func newRegexps(patterns ...string) []config.Regexpwrapper {
+	rs := make([]config.Regexpwrapper, len(patterns))
+	for i, p := range patterns {
+		rs[i] = config.Regexpwrapper{Regexp: regexp.MustCompile(p)}
+	}
+	return rs
+}
+
+func TestFilterName(t *testing.T) {
+	f := newFilter(&config.MetricFilters{
+		Include: newRegexps(`^container\.`, `^custom\.`),
+		Exclude: newRegexps(`^custom\.noisy\.`, `\.debug$`),
+		Rename: []*config.MetricRename{
+			{From: newRegexps(`^custom\.redis6379\.(.+)$`)[0], To: "custom.redis.$1"},
+			{From: newRegexps(`^container\.(?P<kind>cpu|memory)\.(?P<name>[^.]+)\.(.+)$`)[0], To: "container.$kind.$3.${name}"},
+		},
+	})
+	testCases := []struct {
+		name, expected string
+		ok             bool
+	}{
+		{"container.cpu.app.usage", "container.cpu.usage.app", true},
+		{"container.memory.app.usage", "container.memory.usage.app", true},
+		{"container.interface.eth0.rxBytes", "container.interface.eth0.rxBytes", true},
+		{"custom.redis6379.keys", "custom.redis.keys", true},
+		{"custom.noisy.foo", "", false},
+		{"custom.foo.debug", "", false},
+		{"interface.eth0.rxBytes", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := f.name(tc.name)
+			if name != tc.expected || ok != tc.ok {
+				t.Errorf("expected (%q, %v) but got (%q, %v)", tc.expected, tc.ok, name, ok)
+			}
+		})
+	}
+
+	var nilFilter *filter
+	if name, ok := nilFilter.name("custom.foo"); name != "custom.foo" || !ok {
+		t.Errorf("nil filter should pass the metric but got (%q, %v)", name, ok)
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	f := newFilter(&config.MetricFilters{
+		Exclude: newRegexps(`^custom\.foo\.baz$`),
+		Rename: []*config.MetricRename{
+			{From: newRegexps(`^custom\.foo\.(.+)$`)[0], To: "custom.bar.$1"},
+		},
+	})
+	values, timestamps := f.apply(
+		Values{"custom.foo.bar": 1.0, "custom.foo.baz": 2.0, "loadavg5": 3.0},
+		Timestamps{"custom.foo.bar": 1700000000, "custom.foo.baz": 1700000000},
+	)
+	if expected := (Values{"custom.bar.bar": 1.0, "loadavg5": 3.0}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+	if expected := (Timestamps{"custom.bar.bar": 1700000000}); !reflect.DeepEqual(timestamps, expected) {
+		t.Errorf("timestamps should be %v but got %v", expected, timestamps)
+	}
+
+	graphDefs := []*mackerel.GraphDefsParam{
+		{
+			Name:    "custom.foo.graph",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.foo.graph.*"}},
+		},
+	}
+	expected := []*mackerel.GraphDefsParam{
+		{
+			Name:    "custom.bar.graph",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.bar.graph.*"}},
+		},
+	}
+	if got := f.applyGraphDefs(graphDefs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("graph definitions should be %v but got %v", expected, got)
+	}
+	if graphDefs[0].Name != "custom.foo.graph" {
+		t.Errorf("should not modify the original graph definitions")
+	}
+}
+
+func TestFilterApply_Collision(t *testing.T) {
+	f := newFilter(&config.MetricFilters{
+		Rename: []*config.MetricRename{
+			{From: newRegexps(`^custom\.(foo|bar)\.total$`)[0], To: "custom.total"},
+		},
+	})
+	for range 10 {
+		values, timestamps := f.apply(
+			Values{"custom.foo.total": 1.0, "custom.bar.total": 2.0, "custom.total": 3.0},
+			Timestamps{"custom.foo.total": 1700000000, "custom.bar.total": 1700000060},
+		)
+		if expected := (Values{"custom.total": 2.0}); !reflect.DeepEqual(values, expected) {
+			t.Fatalf("values should be %v but got %v", expected, values)
+		}
+		if expected := (Timestamps{"custom.total": 1700000060}); !reflect.DeepEqual(timestamps, expected) {
+			t.Fatalf("timestamps should be %v but got %v", expected, timestamps)
+		}
+	}
+}
+
+func TestCollectorCollect_Filter(t *testing.T) {
+	c := newCollector(createMockGenerators())
+	c.filter = newFilter(&config.MetricFilters{Include: newRegexps(`^custom\.qux\.a\.`)})
+	values, _, err := c.collect(context.Background())
+	if err != nil {
+		t.Errorf("error should be nil but got: %+v", err)
+	}
+	if expected := (Values{"custom.qux.a.bar": 12.39, "custom.qux.a.baz": 13.41}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %+v but got: %+v", expected, values)
+	}
+}
@@ -9,8 +9,18 @@ import (
 // Values represents metric values
 type Values map[string]float64
 
+// Timestamps represents the timestamps of metric values in epoch seconds
+type Timestamps map[string]int64
+
 // Generator interface generates metrics
 type Generator interface {
 	Generate(context.Context) (Values, error)
 	GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error)
 }
+
+// TimestampGenerator is a Generator which also reports the timestamps of the
+// metric values. The values without the timestamp are posted at the collected time.
+type TimestampGenerator interface {
+	Generator
+	GenerateWithTimestamps(context.Context) (Values, Timestamps, error)
+}
@@ -0,0 +1,254 @@
+package metric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+var defaultTimeoutHTTPMetric = 5 * time.Second
+
+type httpGenerator struct {
+	*config.HTTPMetric
+	mappings []*httpMapping
+	client   *http.Client
+	counter  *counter
+}
+
+type httpMapping struct {
+	path []jsonPathSegment
+	name string
+}
+
+// NewHTTPGenerator creates a new generator which scrapes the JSON endpoint
+// and maps the values by the JSONPath to the metrics custom.<name>.*
+func NewHTTPGenerator(conf *config.HTTPMetric) (Generator, error) {
+	var mappings []*httpMapping
+	var diff config.DiffMetrics
+	for _, m := range conf.Metrics {
+		path, err := parseJSONPath(m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("http metric %s: %w", conf.Name, err)
+		}
+		var wildcards int
+		for _, seg := range path {
+			if seg.wildcard {
+				wildcards++
+			}
+		}
+		// the values matched by the wildcards would be posted to the same name
+		if n := strings.Count(m.Name, "*"); n != wildcards {
+			return nil, fmt.Errorf("http metric %s: name %q should have %d wildcards as path %q", conf.Name, m.Name, wildcards, m.Path)
+		}
+		mappings = append(mappings, &httpMapping{path: path, name: m.Name})
+		if m.Diff {
+			diff.Patterns = append(diff.Patterns, m.Name)
+		}
+	}
+
+	dt := http.DefaultTransport.(*http.Transport)
+	tp := &http.Transport{
+		DialContext:           dt.DialContext,
+		MaxIdleConns:          dt.MaxIdleConns,
+		IdleConnTimeout:       dt.IdleConnTimeout,
+		TLSHandshakeTimeout:   dt.TLSHandshakeTimeout,
+		ExpectContinueTimeout: dt.ExpectContinueTimeout,
+		Proxy:                 http.ProxyURL(conf.Proxy.URL),
+	}
+	timeout := time.Duration(conf.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = defaultTimeoutHTTPMetric
+	}
+
+	return &httpGenerator{
+		HTTPMetric: conf,
+		mappings:   mappings,
+		client:     &http.Client{Timeout: timeout, Transport: tp},
+		counter:    newCounter(diff),
+	}, nil
+}
+
+// prefix returns the prefix of the metric names, which is not custom. for
+// the service metrics like the metric plugins
+func (g *httpGenerator) prefix() string {
+	if g.Service != "" {
+		return g.Name + "."
+	}
+	return pluginPrefix + g.Name + "."
+}
+
+// Generate generates metric values
+func (g *httpGenerator) Generate(ctx context.Context) (Values, error) {
+	method := strings.ToUpper(g.Method)
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range g.Headers {
+		if strings.ToLower(h.Name) == "host" {
+			req.Host = h.Value
+		} else {
+			req.Header.Add(h.Name, h.Value)
+		}
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http metric %s (%s %s): %w", g.Name, method, g.URL, err)
+	}
+	defer res.Body.Close() // nolint
+	if res.StatusCode < http.StatusOK || http.StatusMultipleChoices <= res.StatusCode {
+		io.Copy(io.Discard, res.Body) // nolint
+		return nil, fmt.Errorf("http metric %s (%s %s): %s", g.Name, method, g.URL, res.Status)
+	}
+	var body any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("http metric %s (%s %s): failed to decode response: %w", g.Name, method, g.URL, err)
+	}
+
+	values := make(Values)
+	prefix := g.prefix()
+	for _, m := range g.mappings {
+		walkJSONPath(body, m.path, nil, func(keys []string, v any) {
+			value, ok := jsonValue(v)
+			if !ok {
+				return
+			}
+			name := m.name
+			for _, key := range keys {
+				if !strings.Contains(name, "*") {
+					break
+				}
+				name = strings.Replace(name, "*", SanitizeMetricKey(key), 1)
+			}
+			values[prefix+name] = value
+		})
+	}
+	g.counter.apply(values, nil, prefix, time.Now())
+	return values, nil
+}
+
+// GetGraphDefs returns nothing since the graphs are created by the metric names
+func (g *httpGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+func jsonValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		value, err := strconv.ParseFloat(v, 64)
+		return value, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// jsonPathSegment is a segment of JSONPath; the object key, the array index or the wildcard
+type jsonPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the subset of JSONPath: $.key, $['key'], $[0], $.* and $[*]
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath should start with $: %q", path)
+	}
+	var segs []jsonPathSegment
+	s := path[1:]
+	for s != "" {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			key := s[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("empty key in JSONPath: %q", path)
+			}
+			segs = append(segs, jsonPathSegment{key: key, wildcard: key == "*"})
+			s = s[end+1:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in JSONPath: %q", path)
+			}
+			inner := s[1:end]
+			switch {
+			case inner == "*":
+				segs = append(segs, jsonPathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segs = append(segs, jsonPathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid index in JSONPath: %q", path)
+				}
+				segs = append(segs, jsonPathSegment{index: index, isIndex: true})
+			}
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in JSONPath: %q", s[0], path)
+		}
+	}
+	return segs, nil
+}
+
+// walkJSONPath calls the function with the values matched by the path and
+// the keys matched by the wildcards
+func walkJSONPath(v any, path []jsonPathSegment, keys []string, fn func([]string, any)) {
+	if len(path) == 0 {
+		fn(keys, v)
+		return
+	}
+	seg, rest := path[0], path[1:]
+	switch v := v.(type) {
+	case map[string]any:
+		if seg.wildcard {
+			names := make([]string, 0, len(v))
+			for name := range v {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				walkJSONPath(v[name], rest, append(keys[:len(keys):len(keys)], name), fn)
+			}
+		} else if x, ok := v[seg.key]; ok && !seg.isIndex {
+			walkJSONPath(x, rest, keys, fn)
+		}
+	case []any:
+		if seg.wildcard {
+			for i, x := range v {
+				walkJSONPath(x, rest, append(keys[:len(keys):len(keys)], strconv.Itoa(i)), fn)
+			}
+		} else if seg.isIndex && seg.index < len(v) {
+			walkJSONPath(v[seg.index], rest, keys, fn)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package metric
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	testCases := []struct {
+		path   string
+		expect []jsonPathSegment
+		err    bool
+	}{
+		{path: "$", expect: nil},
+		{path: "$.foo.bar", expect: []jsonPathSegment{{key: "foo"}, {key: "bar"}}},
+		{path: "$.foo[0].bar", expect: []jsonPathSegment{{key: "foo"}, {index: 0, isIndex: true}, {key: "bar"}}},
+		{path: "$['foo.bar'][*]", expect: []jsonPathSegment{{key: "foo.bar"}, {wildcard: true}}},
+		{path: `$["foo"].*`, expect: []jsonPathSegment{{key: "foo"}, {key: "*", wildcard: true}}},
+		{path: "foo", err: true},
+		{path: "$..foo", err: true},
+		{path: "$[foo]", err: true},
+		{path: "$[0", err: true},
+		{path: "$foo", err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			segs, err := parseJSONPath(tc.path)
+			if tc.err {
+				if err == nil {
+					t.Errorf("should raise error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if !reflect.DeepEqual(segs, tc.expect) {
+				t.Errorf("expected %#v but got %#v", tc.expect, segs)
+			}
+		})
+	}
+}
+
+func TestHTTPGenerator(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xxx" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests++
+		if requests == 1 {
+			w.Write([]byte(`{"requests":{"total":100},"healthy":true,"version":"v1","uptime":"12.5", "queues":{"default":{"depth":3},"mail.high":{"depth":5}},"workers":[{"busy":1},{"busy":0}]}`)) // nolint
+		} else {
+			w.Write([]byte(`{"requests":{"total":160},"healthy":false,"queues":{"default":{"depth":4}},"workers":[{"busy":2}]}`)) // nolint
+		}
+	}))
+	defer ts.Close()
+
+	g, err := NewHTTPGenerator(&config.HTTPMetric{
+		Name:    "app",
+		URL:     ts.URL + "/stats",
+		Headers: []config.Header{{Name: "Authorization", Value: "Bearer xxx"}},
+		Metrics: []*config.HTTPMetricMapping{
+			{Path: "$.requests.total", Name: "requests.total", Diff: true},
+			{Path: "$.healthy", Name: "status.healthy"},
+			{Path: "$.version", Name: "status.version"},
+			{Path: "$.uptime", Name: "status.uptime"},
+			{Path: "$.queues.*.depth", Name: "queue.*.depth"},
+			{Path: "$.workers[*].busy", Name: "workers.*.busy"},
+			{Path: "$.workers[0].busy", Name: "workers.first.busy"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	ctx := context.Background()
+	values, err := g.Generate(ctx)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	expected := Values{
+		"custom.app.status.healthy":        1,
+		"custom.app.status.uptime":         12.5,
+		"custom.app.queue.default.depth":   3,
+		"custom.app.queue.mail_high.depth": 5,
+		"custom.app.workers.0.busy":        1,
+		"custom.app.workers.1.busy":        0,
+		"custom.app.workers.first.busy":    1,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+
+	c := g.(*httpGenerator).counter
+	for name, prev := range c.prev {
+		c.prev[name] = counterValue{prev.value, prev.time - 60}
+	}
+	values, err = g.Generate(ctx)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if v := values["custom.app.requests.total"]; v != 60 {
+		t.Errorf("requests.total should be posted as the difference 60 but got %v", v)
+	}
+	if v := values["custom.app.status.healthy"]; v != 0 {
+		t.Errorf("status.healthy should be 0 but got %v", v)
+	}
+}
+
+func TestHTTPGenerator_Service(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"queue":{"depth":3}}`)) // nolint
+	}))
+	defer ts.Close()
+
+	conf := &config.HTTPMetric{
+		Name:    "app",
+		URL:     ts.URL,
+		Metrics: []*config.HTTPMetricMapping{{Path: "$.queue.depth", Name: "queue.depth"}},
+		Service: "service1",
+	}
+	hg, err := NewHTTPGenerator(conf)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	g := NewServiceGenerator(conf.Service, hg)
+	if _, serviceGenerators := splitGenerators([]Generator{g}); len(serviceGenerators["service1"]) != 1 {
+		t.Errorf("generator should target the service but got %v", serviceGenerators)
+	}
+	values, err := g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if expected := (Values{"app.queue.depth": 3}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+}
+
+func TestHTTPGenerator_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`<html></html>`)) // nolint
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/error", "/html"} {
+		g, err := NewHTTPGenerator(&config.HTTPMetric{
+			Name:    "app",
+			URL:     ts.URL + path,
+			Metrics: []*config.HTTPMetricMapping{{Path: "$.foo", Name: "foo"}},
+		})
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		if _, err := g.Generate(context.Background()); err == nil {
+			t.Errorf("should raise error: %s", path)
+		}
+	}
+
+	for _, m := range []*config.HTTPMetricMapping{
+		{Path: "$.foo.*", Name: "foo.*.*"},
+		{Path: "$.foo.*", Name: "foo.bar"},
+		{Path: "$.foo[*].*", Name: "foo.*.bar"},
+	} {
+		if _, err := NewHTTPGenerator(&config.HTTPMetric{
+			Name:    "app",
+			URL:     ts.URL,
+			Metrics: []*config.HTTPMetricMapping{m},
+		}); err == nil {
+			t.Errorf("should raise error: %s = %s", m.Name, m.Path)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package metric
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+var logMetricPollInterval = time.Second
+
+// LogGenerator tails the log files, the named pipes or stdin in background,
+// and generates the number of the lines matching the patterns, or the p50,
+// p95 and max of the numbers captured by the patterns in each interval.
+// The log files are followed across the rotation by the device and inode, and
+// the files found on start are read from the end.
+type LogGenerator struct {
+	*config.LogMetric
+	tails   map[string]*logTail
+	rotated map[fileID]*logTail
+	counts  Values
+	samples map[string][]float64
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type logTail struct {
+	file    *os.File
+	id      fileID
+	partial string
+	stale   bool
+	pipe    *os.File      // the named pipe read in background
+	done    chan struct{} // closed when the read of the pipe ends
+}
+
+// fileID identifies the file across the renames
+type fileID struct {
+	dev, ino uint64
+}
+
+// NewLogGenerator creates a new log generator
+func NewLogGenerator(conf *config.LogMetric) *LogGenerator {
+	return &LogGenerator{
+		LogMetric: conf,
+		tails:     make(map[string]*logTail),
+		rotated:   make(map[fileID]*logTail),
+		counts:    make(Values),
+		samples:   make(map[string][]float64),
+	}
+}
+
+// Start starts tailing the files in background
+func (g *LogGenerator) Start(ctx context.Context) {
+	ctx, g.cancel = context.WithCancel(ctx)
+	if g.File == "-" {
+		// the read from stdin cannot be canceled, so do not wait for it on shutdown
+		go func() {
+			g.scan(os.Stdin)
+		}()
+		return
+	}
+	g.poll(ctx, true)
+	g.wg.Go(func() {
+		defer g.closeTails()
+		t := time.NewTicker(logMetricPollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				g.poll(ctx, false)
+			}
+		}
+	})
+}
+
+// Shutdown stops tailing the files
+func (g *LogGenerator) Shutdown() {
+	if g.cancel == nil {
+		return
+	}
+	g.cancel()
+	g.wg.Wait()
+}
+
+func (g *LogGenerator) poll(ctx context.Context, initial bool) {
+	files, err := filepath.Glob(g.File)
+	if err != nil {
+		logger.Warningf("log metric %s: %s", g.Name, err)
+		return
+	}
+	g.expireRotated()
+	matched := make(map[string]bool, len(files))
+	for _, file := range files {
+		matched[file] = true
+	}
+	g.closePipes(matched)
+	newFiles := make(map[string]os.FileInfo)
+	for _, file := range files {
+		if _, ok := g.tails[file]; ok {
+			continue
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if fi.Mode()&os.ModeNamedPipe != 0 {
+			// retry on the next poll if failed
+			t, err := g.tailPipe(ctx, file, getFileID(fi))
+			if err != nil {
+				logger.Warningf("log metric %s: %s", g.Name, err)
+				continue
+			}
+			g.tails[file] = t
+			continue
+		}
+		if fi.Mode().IsRegular() {
+			newFiles[file] = fi
+		}
+	}
+	g.followRenames(newFiles)
+	for file := range newFiles {
+		if _, ok := g.tails[file]; ok {
+			continue
+		}
+		t, err := g.openTail(file, initial)
+		if err != nil {
+			logger.Warningf("log metric %s: %s", g.Name, err)
+			continue
+		}
+		g.tails[file] = t
+	}
+	for file, t := range g.tails {
+		if t.pipe != nil {
+			continue
+		}
+		if !matched[file] {
+			// read the rest of the removed file, and keep it until the next
+			// poll in case it is renamed to the path matched later
+			g.read(t)
+			g.rotate(t)
+			delete(g.tails, file)
+			continue
+		}
+		if err := g.tail(file, t); err != nil {
+			logger.Warningf("log metric %s: %s", g.Name, err)
+		}
+	}
+}
+
+// followRenames moves the tails of the files renamed by the rotation to the
+// new paths, not to read the renamed files from the beginning
+func (g *LogGenerator) followRenames(newFiles map[string]os.FileInfo) {
+	for file, fi := range newFiles {
+		id := getFileID(fi)
+		if id == (fileID{}) {
+			continue
+		}
+		if t, ok := g.rotated[id]; ok {
+			t.stale = false
+			g.tails[file] = t
+			delete(g.rotated, id)
+			continue
+		}
+		for old, t := range g.tails {
+			if t.pipe != nil || t.id != id {
+				continue
+			}
+			if ofi, err := os.Stat(old); err == nil && getFileID(ofi) == id {
+				// hard link of the same file
+				continue
+			}
+			g.tails[file] = t
+			delete(g.tails, old)
+			break
+		}
+	}
+}
+
+// rotate keeps the tail of the file which is no longer at the path. The file
+// may be renamed to the path found by the next poll.
+func (g *LogGenerator) rotate(t *logTail) {
+	if t.id == (fileID{}) {
+		t.file.Close() // nolint
+		return
+	}
+	if old, ok := g.rotated[t.id]; ok {
+		old.file.Close() // nolint
+	}
+	g.rotated[t.id] = t
+}
+
+// expireRotated reads the rest of the rotated files, and closes the ones
+// which are not found by the last poll
+func (g *LogGenerator) expireRotated() {
+	for id, t := range g.rotated {
+		g.read(t)
+		if t.stale {
+			t.file.Close() // nolint
+			delete(g.rotated, id)
+			continue
+		}
+		t.stale = true
+	}
+}
+
+func (g *LogGenerator) openTail(file string, fromEnd bool) (*logTail, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close() // nolint
+		return nil, err
+	}
+	if fromEnd {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			f.Close() // nolint
+			return nil, err
+		}
+	}
+	return &logTail{file: f, id: getFileID(fi)}, nil
+}
+
+// tail reads the lines appended to the file. Since the file is kept open, the
+// rest of the rotated file is read before opening the new one.
+func (g *LogGenerator) tail(file string, t *logTail) error {
+	g.read(t)
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if id := getFileID(fi); id != t.id {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		g.rotate(&logTail{file: t.file, id: t.id, partial: t.partial})
+		t.file, t.id, t.partial = f, id, ""
+		g.read(t)
+		return nil
+	}
+	offset, err := t.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if fi.Size() < offset {
+		// the file is truncated
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		t.partial = ""
+		g.read(t)
+	}
+	return nil
+}
+
+func (g *LogGenerator) read(t *logTail) {
+	r := bufio.NewReader(t.file)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line until the rest is written
+			t.partial += line
+			if err != io.EOF {
+				logger.Warningf("log metric %s: %s", g.Name, err)
+			}
+			return
+		}
+		g.match(strings.TrimRight(t.partial+line, "\r\n"))
+		t.partial = ""
+	}
+}
+
+func (g *LogGenerator) tailPipe(ctx context.Context, file string, id fileID) (*logTail, error) {
+	// open for writing as well not to block on open and not to get EOF
+	// when the writers close the pipe
+	f, err := os.OpenFile(file, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	g.wg.Go(func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		f.Close() // nolint
+	})
+	g.wg.Go(func() {
+		defer close(done)
+		g.scan(f)
+	})
+	return &logTail{id: id, pipe: f, done: done}, nil
+}
+
+// closePipes closes the named pipes which are removed, replaced or failed to
+// read, so that the next poll opens them again
+func (g *LogGenerator) closePipes(matched map[string]bool) {
+	for file, t := range g.tails {
+		if t.pipe == nil {
+			continue
+		}
+		select {
+		case <-t.done:
+		default:
+			if fi, err := os.Stat(file); matched[file] && err == nil && getFileID(fi) == t.id {
+				continue
+			}
+		}
+		t.pipe.Close() // nolint
+		delete(g.tails, file)
+	}
+}
+
+func (g *LogGenerator) scan(r io.Reader) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, streamPluginMaxLineLen)
+	for s.Scan() {
+		g.match(s.Text())
+	}
+	if err := s.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		logger.Warningf("log metric %s: failed to read: %s", g.Name, err)
+	}
+}
+
+func (g *LogGenerator) match(line string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range g.Metrics {
+		xs := m.Pattern.FindStringSubmatch(line)
+		if xs == nil {
+			continue
+		}
+		if len(xs) == 1 {
+			g.counts[m.Name]++
+			continue
+		}
+		if v, err := strconv.ParseFloat(xs[1], 64); err == nil {
+			g.samples[m.Name] = append(g.samples[m.Name], v)
+		}
+	}
+}
+
+func (g *LogGenerator) closeTails() {
+	for file, t := range g.tails {
+		if t.file != nil {
+			t.file.Close() // nolint
+		}
+		if t.pipe != nil {
+			t.pipe.Close() // nolint
+		}
+		delete(g.tails, file)
+	}
+	for id, t := range g.rotated {
+		t.file.Close() // nolint
+		delete(g.rotated, id)
+	}
+}
+
+// Generate returns the metric values of the lines since the last call
+func (g *LogGenerator) Generate(context.Context) (Values, error) {
+	g.mu.Lock()
+	counts, samples := g.counts, g.samples
+	g.counts, g.samples = make(Values), make(map[string][]float64)
+	g.mu.Unlock()
+
+	prefix := pluginPrefix + g.Name + "."
+	if g.Service != "" {
+		// the service metrics are not prefixed by custom. like the metric plugins
+		prefix = g.Name + "."
+	}
+	values := make(Values)
+	for _, m := range g.Metrics {
+		if m.Pattern.NumSubexp() == 0 {
+			values[prefix+m.Name] = counts[m.Name]
+			continue
+		}
+		xs := samples[m.Name]
+		if len(xs) == 0 {
+			continue
+		}
+		slices.Sort(xs)
+		values[prefix+m.Name+".p50"] = percentile(xs, 0.50)
+		values[prefix+m.Name+".p95"] = percentile(xs, 0.95)
+		values[prefix+m.Name+".max"] = xs[len(xs)-1]
+	}
+	return values, nil
+}
+
+// GetGraphDefs returns nothing since the graphs are created by the metric names
+func (g *LogGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+// percentile returns the nearest-rank percentile of the sorted values
+func percentile(xs []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(xs)))) - 1
+	return xs[max(i, 0)]
+}
+
+func getFileID(fi os.FileInfo) fileID {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+	}
+	return fileID{}
+}
@@ -0,0 +1,278 @@
+package metric
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func appendFile(t *testing.T, file, content string) {
+	t.Helper()
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	defer f.Close() // nolint
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+}
+
+func newLogMetricConfig(file string) *config.LogMetric {
+	return &config.LogMetric{
+		Name: "nginx",
+		File: file,
+		Metrics: []*config.LogMetricPattern{
+			{Name: "status_5xx", Pattern: config.Regexpwrapper{Regexp: regexp.MustCompile(`status=5\d\d`)}},
+			{Name: "request_time", Pattern: config.Regexpwrapper{Regexp: regexp.MustCompile(`request_time=([0-9.]+)`)}},
+		},
+	}
+}
+
+func TestLogGenerator(t *testing.T) {
+	defer func(d time.Duration) { logMetricPollInterval = d }(logMetricPollInterval)
+	logMetricPollInterval = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "access.log")
+	appendFile(t, file, "status=500 request_time=9.9\n")
+
+	g := NewLogGenerator(newLogMetricConfig(filepath.Join(dir, "*.log")))
+	ctx := context.Background()
+	g.Start(ctx)
+	defer g.Shutdown()
+
+	testCases := []struct {
+		name    string
+		prepare func()
+		expect  Values
+	}{
+		{
+			name: "read from the end",
+			expect: Values{
+				"custom.nginx.status_5xx": 0,
+			},
+		},
+		{
+			name: "append",
+			prepare: func() {
+				for range 19 {
+					appendFile(t, file, "status=200 request_time=0.1\n")
+				}
+				appendFile(t, file, "status=200 request_time=0.2\nstatus=503 request_time=0.2\nstatus=502 request_time=")
+			},
+			expect: Values{
+				"custom.nginx.status_5xx":       1,
+				"custom.nginx.request_time.p50": 0.1,
+				"custom.nginx.request_time.p95": 0.2,
+				"custom.nginx.request_time.max": 0.2,
+			},
+		},
+		{
+			name: "rotated",
+			prepare: func() {
+				appendFile(t, file, "3.0\n")
+				if err := os.Rename(file, file+".1"); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, file, "status=504 request_time=1.0\n")
+			},
+			expect: Values{
+				"custom.nginx.status_5xx":       2,
+				"custom.nginx.request_time.p50": 1.0,
+				"custom.nginx.request_time.p95": 3.0,
+				"custom.nginx.request_time.max": 3.0,
+			},
+		},
+		{
+			name: "truncated",
+			prepare: func() {
+				if err := os.Truncate(file, 0); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, file, "status=500\n")
+			},
+			expect: Values{
+				"custom.nginx.status_5xx": 1,
+			},
+		},
+		{
+			name: "new file",
+			prepare: func() {
+				appendFile(t, filepath.Join(dir, "error.log"), "status=500\n")
+			},
+			expect: Values{
+				"custom.nginx.status_5xx": 1,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.prepare != nil {
+				tc.prepare()
+			}
+			time.Sleep(100 * time.Millisecond)
+			values, err := g.Generate(ctx)
+			if err != nil {
+				t.Fatalf("should not raise error: %v", err)
+			}
+			if !reflect.DeepEqual(values, tc.expect) {
+				t.Errorf("values should be %v but got %v", tc.expect, values)
+			}
+		})
+	}
+}
+
+func TestLogGenerator_RotatedFilesMatched(t *testing.T) {
+	defer func(d time.Duration) { logMetricPollInterval = d }(logMetricPollInterval)
+	logMetricPollInterval = 10 * time.Millisecond
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "access.log")
+	appendFile(t, file, "status=500\n")
+
+	// the pattern matches the rotated files as well
+	g := NewLogGenerator(newLogMetricConfig(file + "*"))
+	ctx := context.Background()
+	g.Start(ctx)
+	defer g.Shutdown()
+
+	for i := range 3 {
+		appendFile(t, file, "status=500\nstatus=502")
+		time.Sleep(50 * time.Millisecond)
+		appendFile(t, file, "\n")
+		if err := os.Rename(file, file+"."+strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+		appendFile(t, file, "status=503\n")
+		time.Sleep(100 * time.Millisecond)
+
+		values, err := g.Generate(ctx)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		// the rotated files are not read again from the beginning
+		if expected := (Values{"custom.nginx.status_5xx": 3}); !reflect.DeepEqual(values, expected) {
+			t.Errorf("values should be %v but got %v", expected, values)
+		}
+	}
+}
+
+func TestLogGenerator_NamedPipe(t *testing.T) {
+	defer func(d time.Duration) { logMetricPollInterval = d }(logMetricPollInterval)
+	logMetricPollInterval = 10 * time.Millisecond
+
+	file := filepath.Join(t.TempDir(), "access.pipe")
+	if err := syscall.Mkfifo(file, 0644); err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	g := NewLogGenerator(newLogMetricConfig(file))
+	ctx := context.Background()
+	g.Start(ctx)
+
+	// the writers can open and close the pipe repeatedly
+	for range 2 {
+		w, err := os.OpenFile(file, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		if _, err := w.WriteString("status=500 request_time=0.5\n"); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		w.Close() // nolint
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	values, err := g.Generate(ctx)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := Values{
+		"custom.nginx.status_5xx":       2,
+		"custom.nginx.request_time.p50": 0.5,
+		"custom.nginx.request_time.p95": 0.5,
+		"custom.nginx.request_time.max": 0.5,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("should shutdown")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	testCases := []struct {
+		p      float64
+		expect float64
+	}{
+		{0.5, 5},
+		{0.95, 10},
+		{0.9, 9},
+		{0, 1},
+		{1, 10},
+	}
+	for _, tc := range testCases {
+		if got := percentile(xs, tc.p); got != tc.expect {
+			t.Errorf("percentile(%v) should be %v but got %v", tc.p, tc.expect, got)
+		}
+	}
+}
+
+func TestLogGenerator_NamedPipeRecreated(t *testing.T) {
+	defer func(d time.Duration) { logMetricPollInterval = d }(logMetricPollInterval)
+	logMetricPollInterval = 10 * time.Millisecond
+
+	file := filepath.Join(t.TempDir(), "access.pipe")
+	g := NewLogGenerator(newLogMetricConfig(file))
+	ctx := context.Background()
+	g.Start(ctx)
+	defer g.Shutdown()
+
+	// the pipe is created after start, and recreated later
+	for range 2 {
+		if err := syscall.Mkfifo(file, 0644); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		w, err := os.OpenFile(file, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			t.Fatalf("the pipe should be opened by the generator: %v", err)
+		}
+		if _, err := w.WriteString("status=500\n"); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		w.Close() // nolint
+		time.Sleep(100 * time.Millisecond)
+
+		values, err := g.Generate(ctx)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		if expected := (Values{"custom.nginx.status_5xx": 1}); !reflect.DeepEqual(values, expected) {
+			t.Errorf("values should be %v but got %v", expected, values)
+		}
+		if err := os.Remove(file); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -2,81 +2,210 @@ package metric
 
 import (
 	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mackerelio/golib/logging"
 	mackerel "github.com/mackerelio/mackerel-client-go"
 
 	"github.com/mackerelio/mackerel-container-agent/api"
+	"github.com/mackerelio/mackerel-container-agent/config"
 )
 
 var logger = logging.GetLogger("metric")
 
 // Manager in metric manager
 type Manager struct {
-	collector *collector
-	sender    *sender
+	collector         *collector
+	sender            *sender
+	serviceCollectors map[string]*collector
+	serviceSender     *serviceSender
+	outputs           []*outputSender
+	align             bool
+	maxJitter         time.Duration
+	jitter            atomic.Int64
 }
 
 // NewManager creates metric manager instanace
 func NewManager(generators []Generator, client api.Client) *Manager {
+	hostGenerators, serviceGenerators := splitGenerators(generators)
+	serviceCollectors := make(map[string]*collector, len(serviceGenerators))
+	for service, gs := range serviceGenerators {
+		serviceCollectors[service] = newCollector(gs)
+	}
 	return &Manager{
-		collector: newCollector(generators),
-		sender:    newSender(client),
+		collector:         newCollector(hostGenerators),
+		sender:            newSender(client),
+		serviceCollectors: serviceCollectors,
+		serviceSender:     newServiceSender(client),
 	}
 }
 
+// WithOutputs sets the additional outputs of metric values
+func (m *Manager) WithOutputs(outputs []Output) *Manager {
+	for _, o := range outputs {
+		m.outputs = append(m.outputs, newOutputSender(o))
+	}
+	return m
+}
+
+// WithFilters sets the rules to filter and rename the metrics
+func (m *Manager) WithFilters(conf *config.MetricFilters) *Manager {
+	f := newFilter(conf)
+	m.collector.filter = f
+	for _, c := range m.serviceCollectors {
+		c.filter = f
+	}
+	return m
+}
+
+// WithDerivedMetrics sets the metrics derived from the collected host metric values
+func (m *Manager) WithDerivedMetrics(derivedMetrics []*DerivedMetric) *Manager {
+	m.collector.derivedMetrics = derivedMetrics
+	return m
+}
+
+// WithAlignment aligns the collection to the boundaries of the interval, and
+// stamps the metric values with the boundaries. The collection is delayed by
+// the jitter up to maxJitter determined by the host id, to spread the requests
+// of the agents.
+func (m *Manager) WithAlignment(maxJitter time.Duration) *Manager {
+	m.align, m.maxJitter = true, maxJitter
+	return m
+}
+
 // Run collect and send metrics
 func (m *Manager) Run(ctx context.Context, interval time.Duration) (err error) {
-	t := time.NewTicker(interval)
-	defer t.Stop()
 	errCh := make(chan error)
-loop:
+	tick := time.Now()
 	for {
+		now := time.Now()
+		if m.align {
+			tick = now.Truncate(interval).Add(interval)
+		} else {
+			tick = tick.Add(interval)
+		}
+		jitter := time.Duration(m.jitter.Load())
+		t := time.NewTimer(tick.Sub(now) + jitter)
 		select {
 		case <-ctx.Done():
-			break loop
+			t.Stop()
+			return
 		case <-t.C:
+			tick := tick
 			go func() {
-				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				// the collection should finish before the next one, and is
+				// canceled on shutdown
+				ctx, cancel := context.WithDeadline(ctx, tick.Add(interval+jitter))
 				defer cancel()
-				if err := m.collectAndPostValues(ctx); err != nil {
-					errCh <- err
+				if err := m.collectAndPostValues(ctx, tick); err != nil {
+					select {
+					case errCh <- err:
+					case <-ctx.Done():
+					}
 				}
 			}()
 		case err = <-errCh:
-			break loop
+			t.Stop()
+			return
+		}
+	}
+}
+
+// RunGraphDefs refreshes the graph definitions periodically after the host id
+// is resolved, and posts the changes
+func (m *Manager) RunGraphDefs(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			if !m.sender.hasHostID() {
+				continue
+			}
+			err := m.CollectAndPostGraphDefs(ctx)
+			if api.IsPermanent(err) {
+				return err
+			}
+			if err != nil {
+				// do not break the loop with graph definitions posting error
+				logger.Warningf("failed to post graph definitions: %s", err)
+			}
 		}
 	}
-	return
 }
 
 // SetHostID sets host id
 func (m *Manager) SetHostID(hostID string) {
+	if m.maxJitter > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(hostID)) // nolint
+		m.jitter.Store(int64(h.Sum64() % uint64(m.maxJitter)))
+	}
 	m.sender.setHostID(hostID)
+	for _, o := range m.outputs {
+		o.setHostID(hostID)
+	}
 }
 
-func (m *Manager) collectAndPostValues(ctx context.Context) error {
-	now := time.Now()
-	values, err := m.collector.collect(ctx)
+func (m *Manager) collectAndPostValues(ctx context.Context, now time.Time) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	serviceErrs := make([]error, 0, len(m.serviceCollectors))
+	var mu sync.Mutex
+	for service, c := range m.serviceCollectors {
+		wg.Go(func() {
+			values, timestamps, err := c.collect(ctx)
+			if err == nil && len(values) > 0 {
+				err = m.serviceSender.post(service, toMetricValues(values, timestamps, now))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			serviceErrs = append(serviceErrs, err)
+		})
+	}
+
+	values, timestamps, err := m.collector.collect(ctx)
 	if err != nil {
 		return err
 	}
-	if len(values) == 0 {
-		return nil
+	if len(values) > 0 {
+		metricValues := toMetricValues(values, timestamps, now)
+		for _, o := range m.outputs {
+			wg.Go(func() {
+				o.post(ctx, metricValues)
+			})
+		}
+		if err := m.sender.post(metricValues); err != nil {
+			return err
+		}
 	}
-	var metricValues []*mackerel.MetricValue
+	wg.Wait()
+	return errors.Join(serviceErrs...)
+}
+
+func toMetricValues(values Values, timestamps Timestamps, now time.Time) []*mackerel.MetricValue {
+	metricValues := make([]*mackerel.MetricValue, 0, len(values))
 	for name, value := range values {
+		t, ok := timestamps[name]
+		if !ok {
+			t = now.Unix()
+		}
 		metricValues = append(metricValues, &mackerel.MetricValue{
 			Name:  name,
-			Time:  now.Unix(),
+			Time:  t,
 			Value: value,
 		})
 	}
-	return m.sender.post(metricValues)
+	return metricValues
 }
 
-// CollectAndPostGraphDefs sends graph definitions
+// CollectAndPostGraphDefs sends graph definitions which are new or changed since the last post
 func (m *Manager) CollectAndPostGraphDefs(ctx context.Context) error {
 	graphDefs, err := m.collector.collectGraphDefs(ctx)
 	if err != nil {
@@ -2,9 +2,12 @@ package metric
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
 	"github.com/mackerelio/mackerel-container-agent/api"
 )
 
@@ -42,3 +45,131 @@ func TestManagerRun(t *testing.T) {
 		t.Errorf("graph definitions should have size %d but got: %#v", expected, graphDefs)
 	}
 }
+
+func TestManagerRunGraphDefs(t *testing.T) {
+	client := api.NewMockClient()
+	manager := NewManager(createMockGenerators(), client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 190*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		manager.SetHostID("abcde")
+	}()
+	if err := manager.RunGraphDefs(ctx, 40*time.Millisecond); err != nil {
+		t.Errorf("err should be nil but got: %+v", err)
+	}
+	// the unchanged graph definitions are posted only once
+	graphDefs := client.PostedGraphDefs()
+	if expected := 2; len(graphDefs) != expected {
+		t.Errorf("graph definitions should have size %d but got: %#v", expected, graphDefs)
+	}
+}
+
+func TestManagerRunWithAlignment(t *testing.T) {
+	var delays []time.Duration
+	var mu sync.Mutex
+	client := api.NewMockClient(
+		api.MockPostHostMetricValuesByHostID(func(hostID string, metricValues []*mackerel.MetricValue) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delays = append(delays, time.Since(time.Unix(metricValues[0].Time, 0)))
+			return nil
+		}),
+	)
+	manager := NewManager(createMockGenerators(), client).WithAlignment(500 * time.Millisecond)
+	manager.SetHostID("abcde")
+	jitter := time.Duration(manager.jitter.Load())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	if err := manager.Run(ctx, time.Second); err != nil {
+		t.Errorf("err should be nil but got: %+v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delays) < 2 {
+		t.Fatalf("metric values should be posted at least twice but got: %d", len(delays))
+	}
+	// the values are stamped with the boundary and collected after the jitter
+	for _, d := range delays {
+		if d < jitter || d > jitter+200*time.Millisecond {
+			t.Errorf("metric values should be collected %s after the boundary but got: %s", jitter, d)
+		}
+	}
+}
+
+type blockingGenerator struct {
+	deadlines chan time.Time
+	canceled  chan error
+}
+
+func (g *blockingGenerator) Generate(ctx context.Context) (Values, error) {
+	deadline, _ := ctx.Deadline()
+	g.deadlines <- deadline
+	<-ctx.Done()
+	g.canceled <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func (g *blockingGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+func TestManagerRunCollectionDeadline(t *testing.T) {
+	g := &blockingGenerator{deadlines: make(chan time.Time, 10), canceled: make(chan error, 10)}
+	manager := NewManager([]Generator{g}, api.NewMockClient()).WithAlignment(0)
+	manager.SetHostID("abcde")
+
+	interval := 500 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- manager.Run(ctx, interval) }()
+
+	// the collection is bounded by the next boundary
+	deadline := <-g.deadlines
+	if deadline.Sub(deadline.Truncate(interval)) != 0 {
+		t.Errorf("deadline should be aligned to the interval but got: %s", deadline)
+	}
+	if d := time.Until(deadline); d <= 0 || d > interval {
+		t.Errorf("deadline should be within the interval but got: %s", d)
+	}
+
+	// the collection is canceled on shutdown
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("err should be nil but got: %+v", err)
+	}
+	select {
+	case err := <-g.canceled:
+		if err != context.Canceled {
+			t.Errorf("collection should be canceled but got: %v", err)
+		}
+	case <-time.After(interval / 2):
+		t.Errorf("collection should be canceled on shutdown")
+	}
+}
+
+func TestManagerJitter(t *testing.T) {
+	m1 := NewManager(nil, api.NewMockClient()).WithAlignment(30 * time.Second)
+	m1.SetHostID("abcde")
+	m2 := NewManager(nil, api.NewMockClient()).WithAlignment(30 * time.Second)
+	m2.SetHostID("abcde")
+	m3 := NewManager(nil, api.NewMockClient()).WithAlignment(30 * time.Second)
+	m3.SetHostID("fghij")
+	if j := m1.jitter.Load(); j < 0 || j >= int64(30*time.Second) {
+		t.Errorf("jitter should be less than 30s but got: %s", time.Duration(j))
+	}
+	if m1.jitter.Load() != m2.jitter.Load() {
+		t.Errorf("jitter should be deterministic by host id")
+	}
+	if m1.jitter.Load() == m3.jitter.Load() {
+		t.Errorf("jitter should differ by host id")
+	}
+
+	m4 := NewManager(nil, api.NewMockClient())
+	m4.SetHostID("abcde")
+	if j := m4.jitter.Load(); j != 0 {
+		t.Errorf("jitter should be zero without alignment but got: %s", time.Duration(j))
+	}
+}
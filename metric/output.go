@@ -0,0 +1,121 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+const outputTimeout = 10 * time.Second
+
+// Output interface writes metric values to an additional sink besides Mackerel
+type Output interface {
+	Name() string
+	Write(ctx context.Context, hostID string, values []*mackerel.MetricValue) error
+}
+
+// NewOutput creates a new Output from the config
+func NewOutput(conf *config.Output) (Output, error) {
+	switch conf.Type {
+	case config.OutputTypePrometheusRemoteWrite:
+		return &prometheusOutput{url: conf.URL, headers: conf.Headers, httpClient: newOutputHTTPClient()}, nil
+	case config.OutputTypeOTLP:
+		return &otlpOutput{url: conf.URL, headers: conf.Headers, httpClient: newOutputHTTPClient()}, nil
+	case config.OutputTypeJSONLines:
+		return &jsonLinesOutput{name: "jsonl:" + conf.Path, open: func() (io.WriteCloser, error) {
+			return os.OpenFile(conf.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		}}, nil
+	case config.OutputTypeStdout:
+		return &jsonLinesOutput{name: "stdout", open: func() (io.WriteCloser, error) {
+			return nopWriteCloser{os.Stdout}, nil
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown output type: %q", conf.Type)
+	}
+}
+
+func newOutputHTTPClient() *http.Client {
+	return &http.Client{Timeout: outputTimeout}
+}
+
+func postOutput(ctx context.Context, client *http.Client, url string, headers map[string]string, body io.Reader, setHeader func(http.Header)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	setHeader(req.Header)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, url, body)
+	}
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// outputSender writes the metric values to the output with its own retry queue,
+// so that a failing output does not block Mackerel and the other outputs.
+type outputSender struct {
+	output         Output
+	hostID         string
+	pendingMetrics [][]*mackerel.MetricValue
+	mu             sync.Mutex
+}
+
+func newOutputSender(output Output) *outputSender {
+	return &outputSender{output: output}
+}
+
+func (s *outputSender) post(ctx context.Context, metricValues []*mackerel.MetricValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingMetrics = append(s.pendingMetrics, metricValues)
+	if s.hostID == "" {
+		return
+	}
+	n := min(len(s.pendingMetrics), maxPostBatches)
+	var values []*mackerel.MetricValue
+	for _, ms := range s.pendingMetrics[:n] {
+		values = append(values, ms...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, outputTimeout)
+	defer cancel()
+	if err := s.output.Write(ctx, s.hostID, values); err != nil {
+		logger.Warningf("failed to write metric values to %s but will retry: %s", s.output.Name(), err)
+	} else {
+		m := copy(s.pendingMetrics, s.pendingMetrics[n:])
+		s.pendingMetrics = s.pendingMetrics[:m]
+	}
+	if len(s.pendingMetrics) > maxPendingMetrics {
+		m := copy(s.pendingMetrics, s.pendingMetrics[len(s.pendingMetrics)-maxPendingMetrics:])
+		s.pendingMetrics = s.pendingMetrics[:m]
+	}
+}
+
+func (s *outputSender) setHostID(hostID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hostID = hostID
+}
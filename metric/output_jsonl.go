@@ -0,0 +1,50 @@
+package metric
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+// jsonLinesOutput writes a metric value per line in JSON
+type jsonLinesOutput struct {
+	name string
+	open func() (io.WriteCloser, error)
+}
+
+type jsonLine struct {
+	HostID string  `json:"hostId"`
+	Name   string  `json:"name"`
+	Time   int64   `json:"time"`
+	Value  float64 `json:"value"`
+}
+
+func (o *jsonLinesOutput) Name() string {
+	return o.name
+}
+
+func (o *jsonLinesOutput) Write(_ context.Context, hostID string, values []*mackerel.MetricValue) error {
+	f, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, v := range values {
+		value, ok := outputValue(v)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(jsonLine{HostID: hostID, Name: v.Name, Time: v.Time, Value: value}); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,114 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+// otlpOutput sends the metric values to OTLP/HTTP metrics endpoint in JSON encoding.
+// The metric values are sent as gauges and the host id is set to host.id resource attribute.
+type otlpOutput struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string             `json:"key"`
+	Value otlpAttributeValue `json:"value"`
+}
+
+type otlpAttributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	TimeUnixNano string  `json:"timeUnixNano"`
+	AsDouble     float64 `json:"asDouble"`
+}
+
+func (o *otlpOutput) Name() string {
+	return "otlp:" + o.url
+}
+
+func (o *otlpOutput) Write(ctx context.Context, hostID string, values []*mackerel.MetricValue) error {
+	body, err := json.Marshal(buildOTLPMetricsRequest(hostID, values))
+	if err != nil {
+		return err
+	}
+	return postOutput(ctx, o.httpClient, o.url, o.headers, bytes.NewReader(body), func(h http.Header) {
+		h.Set("Content-Type", "application/json")
+	})
+}
+
+func buildOTLPMetricsRequest(hostID string, values []*mackerel.MetricValue) *otlpMetricsRequest {
+	var metrics []otlpMetric
+	index := make(map[string]int)
+	for _, v := range values {
+		value, ok := outputValue(v)
+		if !ok {
+			continue
+		}
+		i, ok := index[v.Name]
+		if !ok {
+			i = len(metrics)
+			index[v.Name] = i
+			metrics = append(metrics, otlpMetric{Name: v.Name})
+		}
+		metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, otlpDataPoint{
+			TimeUnixNano: strconv.FormatInt(v.Time*1e9, 10),
+			AsDouble:     value,
+		})
+	}
+	attributes := []otlpAttribute{
+		{Key: "service.name", Value: otlpAttributeValue{StringValue: "mackerel-container-agent"}},
+	}
+	if hostID != "" {
+		attributes = append(attributes, otlpAttribute{Key: "host.id", Value: otlpAttributeValue{StringValue: hostID}})
+	}
+	return &otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{Attributes: attributes},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   otlpScope{Name: "mackerel-container-agent"},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
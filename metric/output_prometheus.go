@@ -0,0 +1,127 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+)
+
+var prometheusNameReg = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// prometheusOutput sends the metric values to Prometheus remote write endpoint.
+// The metric name is converted to the Prometheus style and the host id is set to host_id label.
+type prometheusOutput struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+func (o *prometheusOutput) Name() string {
+	return "prometheusRemoteWrite:" + o.url
+}
+
+func (o *prometheusOutput) Write(ctx context.Context, hostID string, values []*mackerel.MetricValue) error {
+	body := snappyEncode(encodeWriteRequest(hostID, values))
+	return postOutput(ctx, o.httpClient, o.url, o.headers, bytes.NewReader(body), func(h http.Header) {
+		h.Set("Content-Type", "application/x-protobuf")
+		h.Set("Content-Encoding", "snappy")
+		h.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	})
+}
+
+func prometheusMetricName(name string) string {
+	name = prometheusNameReg.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// encodeWriteRequest encodes prometheus.WriteRequest in protobuf wire format.
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label { string name = 1; string value = 2; }
+//	Sample { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(hostID string, values []*mackerel.MetricValue) []byte {
+	samples := make(map[string][]*mackerel.MetricValue)
+	var names []string
+	for _, v := range values {
+		if _, ok := outputValue(v); !ok {
+			continue
+		}
+		name := prometheusMetricName(v.Name)
+		if _, ok := samples[name]; !ok {
+			names = append(names, name)
+		}
+		samples[name] = append(samples[name], v)
+	}
+	sort.Strings(names)
+
+	var req []byte
+	for _, name := range names {
+		var ts []byte
+		ts = appendBytesField(ts, 1, encodeLabel("__name__", name))
+		if hostID != "" {
+			ts = appendBytesField(ts, 1, encodeLabel("host_id", hostID))
+		}
+		vs := samples[name]
+		sort.SliceStable(vs, func(i, j int) bool { return vs[i].Time < vs[j].Time })
+		for _, v := range vs {
+			value, _ := outputValue(v)
+			var sample []byte
+			sample = binary.AppendUvarint(sample, 1<<3|1)
+			sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(value))
+			sample = binary.AppendUvarint(sample, 2<<3|0)
+			sample = binary.AppendUvarint(sample, uint64(v.Time*1000))
+			ts = appendBytesField(ts, 2, sample)
+		}
+		req = appendBytesField(req, 1, ts)
+	}
+	return req
+}
+
+func encodeLabel(name, value string) []byte {
+	var label []byte
+	label = appendBytesField(label, 1, []byte(name))
+	label = appendBytesField(label, 2, []byte(value))
+	return label
+}
+
+func appendBytesField(b []byte, field uint64, value []byte) []byte {
+	b = binary.AppendUvarint(b, field<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// snappyEncode encodes the data in the snappy block format with literals only.
+// It does not compress the data but any snappy decoder can decode it.
+func snappyEncode(data []byte) []byte {
+	const maxLiteral = 1 << 16
+	b := binary.AppendUvarint(make([]byte, 0, len(data)+len(data)/maxLiteral*3+16), uint64(len(data)))
+	for len(data) > 0 {
+		n := min(len(data), maxLiteral)
+		if n <= 60 {
+			b = append(b, byte(n-1)<<2)
+		} else {
+			b = append(b, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		b = append(b, data[:n]...)
+		data = data[n:]
+	}
+	return b
+}
+
+func outputValue(v *mackerel.MetricValue) (float64, bool) {
+	value, ok := v.Value.(float64)
+	if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,259 @@
+package metric
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+var outputTestValues = []*mackerel.MetricValue{
+	{Name: "container.cpu.web.usage", Time: 1790812800, Value: 12.5},
+	{Name: "container.memory.web.usage", Time: 1790812800, Value: 1024.0},
+	{Name: "container.cpu.web.usage", Time: 1790812860, Value: 15.0},
+	{Name: "custom.invalid", Time: 1790812860, Value: math.NaN()},
+}
+
+// snappyDecode decodes the snappy block with literals only
+func snappyDecode(t *testing.T, b []byte) []byte {
+	t.Helper()
+	n, l := binary.Uvarint(b)
+	b = b[l:]
+	var data []byte
+	for len(b) > 0 {
+		tag := b[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected tag: %x", tag)
+		}
+		length := int(tag>>2) + 1
+		b = b[1:]
+		if tag>>2 == 61 {
+			length = int(b[0]) | int(b[1])<<8 + 1
+			b = b[2:]
+		}
+		data = append(data, b[:length]...)
+		b = b[length:]
+	}
+	if uint64(len(data)) != n {
+		t.Fatalf("length should be %d but got %d", n, len(data))
+	}
+	return data
+}
+
+// protoFields decodes the fields of a protobuf message
+func protoFields(t *testing.T, b []byte) (fields []uint64, values [][]byte) {
+	t.Helper()
+	for len(b) > 0 {
+		key, l := binary.Uvarint(b)
+		b = b[l:]
+		fields = append(fields, key>>3)
+		switch key & 7 {
+		case 0:
+			_, l := binary.Uvarint(b)
+			values, b = append(values, b[:l]), b[l:]
+		case 1:
+			values, b = append(values, b[:8]), b[8:]
+		case 2:
+			n, l := binary.Uvarint(b)
+			b = b[l:]
+			values, b = append(values, b[:n]), b[n:]
+		default:
+			t.Fatalf("unexpected wire type: %d", key&7)
+		}
+	}
+	return
+}
+
+func TestPrometheusOutput(t *testing.T) {
+	type sample struct {
+		value     float64
+		timestamp int64
+	}
+	got := make(map[string][]sample)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, series := protoFields(t, snappyDecode(t, body))
+		for _, s := range series {
+			fields, values := protoFields(t, s)
+			var name string
+			var samples []sample
+			for i, f := range fields {
+				switch f {
+				case 1:
+					_, lv := protoFields(t, values[i])
+					if string(lv[0]) == "__name__" {
+						name = string(lv[1])
+					} else if string(lv[0]) != "host_id" || string(lv[1]) != "abcde" {
+						t.Errorf("unexpected label: %s=%s", lv[0], lv[1])
+					}
+				case 2:
+					_, sv := protoFields(t, values[i])
+					ts, _ := binary.Uvarint(sv[1])
+					samples = append(samples, sample{math.Float64frombits(binary.LittleEndian.Uint64(sv[0])), int64(ts)})
+				}
+			}
+			got[name] = samples
+		}
+	}))
+	defer ts.Close()
+
+	o, err := NewOutput(&config.Output{
+		Type:    config.OutputTypePrometheusRemoteWrite,
+		URL:     ts.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Write(context.Background(), "abcde", outputTestValues); err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := map[string][]sample{
+		"container_cpu_web_usage":    {{12.5, 1790812800000}, {15.0, 1790812860000}},
+		"container_memory_web_usage": {{1024.0, 1790812800000}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	o.(*prometheusOutput).headers = nil
+	if err := o.Write(context.Background(), "abcde", outputTestValues); err == nil {
+		t.Errorf("should raise error")
+	}
+}
+
+func TestSnappyEncode(t *testing.T) {
+	for _, n := range []int{0, 1, 60, 61, 1 << 16, 1<<16 + 1, 200000} {
+		data := []byte(strings.Repeat("x", n))
+		if got := snappyDecode(t, snappyEncode(data)); string(got) != string(data) {
+			t.Errorf("snappyEncode should be decoded to the data of %d bytes", n)
+		}
+	}
+}
+
+func TestOTLPOutput(t *testing.T) {
+	var got otlpMetricsRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got) // nolint
+	}))
+	defer ts.Close()
+
+	o, err := NewOutput(&config.Output{Type: config.OutputTypeOTLP, URL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Write(context.Background(), "abcde", outputTestValues); err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	expected := otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: otlpResource{Attributes: []otlpAttribute{
+				{Key: "service.name", Value: otlpAttributeValue{StringValue: "mackerel-container-agent"}},
+				{Key: "host.id", Value: otlpAttributeValue{StringValue: "abcde"}},
+			}},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope: otlpScope{Name: "mackerel-container-agent"},
+				Metrics: []otlpMetric{
+					{Name: "container.cpu.web.usage", Gauge: otlpGauge{DataPoints: []otlpDataPoint{
+						{TimeUnixNano: "1790812800000000000", AsDouble: 12.5},
+						{TimeUnixNano: "1790812860000000000", AsDouble: 15.0},
+					}}},
+					{Name: "container.memory.web.usage", Gauge: otlpGauge{DataPoints: []otlpDataPoint{
+						{TimeUnixNano: "1790812800000000000", AsDouble: 1024.0},
+					}}},
+				},
+			}},
+		}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v but got %+v", expected, got)
+	}
+}
+
+func TestJSONLinesOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+	o, err := NewOutput(&config.Output{Type: config.OutputTypeJSONLines, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := o.Write(context.Background(), "abcde", outputTestValues[:2]); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line0 := `{"hostId":"abcde","name":"container.cpu.web.usage","time":1790812800,"value":12.5}`
+	line1 := `{"hostId":"abcde","name":"container.memory.web.usage","time":1790812800,"value":1024}`
+	expected := strings.Repeat(line0+"\n"+line1+"\n", 2)
+	if string(content) != expected {
+		t.Errorf("expected %q but got %q", expected, content)
+	}
+}
+
+type mockOutput struct {
+	err     error
+	written [][]*mackerel.MetricValue
+}
+
+func (o *mockOutput) Name() string {
+	return "mock"
+}
+
+func (o *mockOutput) Write(_ context.Context, _ string, values []*mackerel.MetricValue) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.written = append(o.written, values)
+	return nil
+}
+
+func TestOutputSender(t *testing.T) {
+	o := &mockOutput{}
+	s := newOutputSender(o)
+	ctx := context.Background()
+
+	s.post(ctx, outputTestValues[:1])
+	if len(o.written) != 0 {
+		t.Errorf("should not write before host id is set")
+	}
+
+	s.setHostID("abcde")
+	o.err = errors.New("connection refused")
+	s.post(ctx, outputTestValues[1:2])
+	if len(s.pendingMetrics) != 2 {
+		t.Errorf("should keep pending metrics on error but got %d", len(s.pendingMetrics))
+	}
+
+	o.err = nil
+	s.post(ctx, outputTestValues[2:3])
+	if len(s.pendingMetrics) != 0 {
+		t.Errorf("should drain pending metrics but got %d", len(s.pendingMetrics))
+	}
+	if expected := [][]*mackerel.MetricValue{outputTestValues[:3]}; !reflect.DeepEqual(o.written, expected) {
+		t.Errorf("expected %v but got %v", expected, o.written)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	mackerel "github.com/mackerelio/mackerel-client-go"
 
@@ -21,15 +22,26 @@ const (
 
 type pluginGenerator struct {
 	config.MetricPlugin
+	counter *counter
 }
 
 // NewPluginGenerator creates a new plugin generator
 func NewPluginGenerator(p *config.MetricPlugin) Generator {
-	return &pluginGenerator{*p}
+	return newPluginGenerator(p)
+}
+
+func newPluginGenerator(p *config.MetricPlugin) *pluginGenerator {
+	return &pluginGenerator{MetricPlugin: *p, counter: newCounter(p.Diff)}
 }
 
 // Generate generates metric values
 func (g *pluginGenerator) Generate(ctx context.Context) (Values, error) {
+	values, _, err := g.GenerateWithTimestamps(ctx)
+	return values, err
+}
+
+// GenerateWithTimestamps generates metric values with the timestamps printed by the plugin
+func (g *pluginGenerator) GenerateWithTimestamps(ctx context.Context) (Values, Timestamps, error) {
 	env := append(g.Env, pluginMetaEnvName+"=")
 	logger.Debugf("plugin %s command: %s env: %+v", g.Name, g.Command, env.Masked())
 	stdout, stderr, _, err := cmdutil.RunCommand(ctx, g.Command, g.User, env, g.Timeout)
@@ -38,25 +50,115 @@ func (g *pluginGenerator) Generate(ctx context.Context) (Values, error) {
 		logger.Infof("plugin %s (%s): %q", g.Name, g.Command, stderr)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("plugin %s (%s): %w", g.Name, g.Command, err)
+		return nil, nil, fmt.Errorf("plugin %s (%s): %w", g.Name, g.Command, err)
+	}
+
+	prefix := g.prefix()
+	var values Values
+	var timestamps Timestamps
+	var errs []error
+	if g.Format == config.PluginFormatJSON {
+		values, timestamps, errs, err = parsePluginJSON(stdout, prefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("plugin %s (%s): failed to decode output: %w", g.Name, g.Command, err)
+		}
+	} else {
+		values, timestamps, errs = parsePluginText(stdout, prefix)
+	}
+	if len(errs) > 0 {
+		logger.Warningf("plugin %s (%s): failed to parse %d metrics: %s", g.Name, g.Command, len(errs), summarizeErrors(errs))
 	}
+	g.counter.apply(values, timestamps, prefix, time.Now())
 
-	values := make(Values)
+	return values, timestamps, nil
+}
+
+// prefix returns the prefix of metric names. The service metrics are posted without the prefix.
+func (g *pluginGenerator) prefix() string {
+	if g.MetricPlugin.Service != "" {
+		return ""
+	}
+	return pluginPrefix
+}
+
+// parsePluginText parses the lines of key, value and timestamp separated by
+// whitespaces. The extra fields are ignored. The values with the invalid
+// timestamps are kept without the timestamps.
+func parsePluginText(stdout, prefix string) (Values, Timestamps, []error) {
+	values, timestamps := make(Values), make(Timestamps)
+	var errs []error
 	for line := range strings.SplitSeq(stdout, "\n") {
-		// key, value, timestamp
 		xs := strings.Fields(line)
+		if len(xs) == 0 || strings.HasPrefix(xs[0], "#") {
+			continue
+		}
 		if len(xs) < 3 {
+			errs = append(errs, fmt.Errorf("invalid line: %q", line))
 			continue
 		}
 		value, err := strconv.ParseFloat(xs[1], 64)
 		if err != nil {
-			logger.Warningf("plugin %s (%s): failed to parse value: %s", g.Name, g.Command, err)
+			errs = append(errs, fmt.Errorf("invalid value of %s: %q", xs[0], xs[1]))
+			continue
+		}
+		values[prefix+xs[0]] = value
+		timestamp, err := strconv.ParseFloat(xs[2], 64)
+		if err != nil || timestamp <= 0 {
+			// the value is posted at the collection time
+			errs = append(errs, fmt.Errorf("invalid timestamp of %s: %q", xs[0], xs[2]))
 			continue
 		}
-		values[pluginPrefix+xs[0]] = value
+		timestamps[prefix+xs[0]] = int64(timestamp)
 	}
+	return values, timestamps, errs
+}
+
+type pluginMetric struct {
+	Name  string   `json:"name"`
+	Value *float64 `json:"value"`
+	Time  float64  `json:"time"`
+}
+
+// parsePluginJSON parses the array of metrics. The time is optional.
+func parsePluginJSON(stdout, prefix string) (Values, Timestamps, []error, error) {
+	var metrics []pluginMetric
+	if err := json.Unmarshal([]byte(stdout), &metrics); err != nil {
+		return nil, nil, nil, err
+	}
+	values, timestamps := make(Values), make(Timestamps)
+	var errs []error
+	for i, m := range metrics {
+		switch {
+		case m.Name == "":
+			errs = append(errs, fmt.Errorf("missing name at index %d", i))
+		case m.Value == nil:
+			errs = append(errs, fmt.Errorf("missing value of %s", m.Name))
+		case m.Time < 0:
+			errs = append(errs, fmt.Errorf("invalid timestamp of %s: %v", m.Name, m.Time))
+		default:
+			values[prefix+m.Name] = *m.Value
+			if m.Time > 0 {
+				timestamps[prefix+m.Name] = int64(m.Time)
+			}
+		}
+	}
+	return values, timestamps, errs, nil
+}
+
+const maxReportedErrors = 3
 
-	return values, nil
+func summarizeErrors(errs []error) string {
+	var b strings.Builder
+	for i, err := range errs[:min(len(errs), maxReportedErrors)] {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(err.Error())
+	}
+	if len(errs) > maxReportedErrors {
+		fmt.Fprintf(&b, " and %d more", len(errs)-maxReportedErrors)
+	}
+	return b.String()
 }
 
 type pluginMeta struct {
@@ -71,8 +173,16 @@ type pluginMeta struct {
 	}
 }
 
+// Service returns the service name of the plugin, which is empty for the host metrics
+func (g *pluginGenerator) Service() string {
+	return g.MetricPlugin.Service
+}
+
 // GetGraphDefs gets graph definitions
 func (g *pluginGenerator) GetGraphDefs(ctx context.Context) ([]*mackerel.GraphDefsParam, error) {
+	if g.MetricPlugin.Service != "" {
+		return nil, nil
+	}
 	env := append(g.Env, pluginMetaEnvName+"=1")
 	stdout, stderr, _, err := cmdutil.RunCommand(ctx, g.Command, g.User, env, g.Timeout)
 
@@ -89,9 +199,14 @@ func (g *pluginGenerator) GetGraphDefs(ctx context.Context) ([]*mackerel.GraphDe
 		return nil, nil
 	}
 
+	return parsePluginMeta(g.Name, xs[1])
+}
+
+// parsePluginMeta decodes the plugin meta into the graph definitions
+func parsePluginMeta(name, meta string) ([]*mackerel.GraphDefsParam, error) {
 	var conf pluginMeta
-	if err = json.Unmarshal([]byte(xs[1]), &conf); err != nil {
-		return nil, fmt.Errorf("plugin %s: failed to decode plugin meta: %w", g.Name, err)
+	if err := json.Unmarshal([]byte(meta), &conf); err != nil {
+		return nil, fmt.Errorf("plugin %s: failed to decode plugin meta: %w", name, err)
 	}
 
 	var graphDefs []*mackerel.GraphDefsParam
@@ -0,0 +1,178 @@
+package metric
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/cmdutil"
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+var (
+	streamPluginMinBackoff = time.Second
+	streamPluginMaxBackoff = time.Minute
+	streamPluginMaxLineLen = 1 << 20
+)
+
+// StreamPluginGenerator runs the long-running metric plugin which prints the
+// metric values to stdout continuously. The plugin is restarted with backoff
+// when it exits. The graphs are defined by the plugin meta printed in the
+// stream, that is the # mackerel-agent-plugin line followed by the JSON line,
+// since the plugin is not executed again to get the meta.
+type StreamPluginGenerator struct {
+	*pluginGenerator
+	values     Values
+	timestamps Timestamps
+	errs       []error
+	graphDefs  []*mackerel.GraphDefsParam
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewStreamPluginGenerator creates a new stream plugin generator
+func NewStreamPluginGenerator(p *config.MetricPlugin) *StreamPluginGenerator {
+	return &StreamPluginGenerator{
+		pluginGenerator: newPluginGenerator(p),
+		values:          make(Values),
+		timestamps:      make(Timestamps),
+	}
+}
+
+// Start starts the plugin process in background
+func (g *StreamPluginGenerator) Start(ctx context.Context) {
+	ctx, g.cancel = context.WithCancel(ctx)
+	g.done = make(chan struct{})
+	go func() {
+		defer close(g.done)
+		g.run(ctx)
+	}()
+}
+
+// Shutdown stops the plugin process and waits for it to exit
+func (g *StreamPluginGenerator) Shutdown() {
+	if g.cancel == nil {
+		return
+	}
+	g.cancel()
+	<-g.done
+}
+
+func (g *StreamPluginGenerator) run(ctx context.Context) {
+	backoff := streamPluginMinBackoff
+	for {
+		start := time.Now()
+		if err := g.runOnce(ctx); err != nil {
+			logger.Warningf("plugin %s (%s): %s", g.Name, g.Command, err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// reset the backoff if the plugin has been running for a while
+		if time.Since(start) > streamPluginMaxBackoff {
+			backoff = streamPluginMinBackoff
+		}
+		logger.Infof("plugin %s (%s): restart in %s", g.Name, g.Command, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, streamPluginMaxBackoff)
+	}
+}
+
+func (g *StreamPluginGenerator) runOnce(ctx context.Context) error {
+	env := append(g.Env, pluginMetaEnvName+"=")
+	logger.Debugf("plugin %s command: %s env: %+v", g.Name, g.Command, env.Masked())
+	cmd, stdout, stderr, err := cmdutil.StartCommand(ctx, g.Command, g.User, env)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		s := bufio.NewScanner(stderr)
+		for s.Scan() {
+			logger.Infof("plugin %s (%s): %q", g.Name, g.Command, s.Text())
+		}
+	})
+	g.read(stdout)
+	wg.Wait()
+	return cmd.Wait()
+}
+
+func (g *StreamPluginGenerator) read(r io.Reader) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, streamPluginMaxLineLen)
+	var meta bool
+	for s.Scan() {
+		if meta {
+			meta = false
+			g.readMeta(s.Text())
+			continue
+		}
+		if strings.HasPrefix(s.Text(), pluginMetaHeadline) {
+			meta = true
+			continue
+		}
+		values, timestamps, errs := parsePluginText(s.Text(), g.prefix())
+		g.mu.Lock()
+		maps.Copy(g.values, values)
+		maps.Copy(g.timestamps, timestamps)
+		g.errs = append(g.errs, errs...)
+		g.mu.Unlock()
+	}
+	if err := s.Err(); err != nil {
+		logger.Warningf("plugin %s (%s): failed to read output: %s", g.Name, g.Command, err)
+		// drain the output not to block the plugin until it exits
+		io.Copy(io.Discard, r) // nolint
+	}
+}
+
+func (g *StreamPluginGenerator) readMeta(line string) {
+	graphDefs, err := parsePluginMeta(g.Name, line)
+	if err != nil {
+		logger.Warningf("%s", err)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.graphDefs = graphDefs
+}
+
+// GetGraphDefs returns the graph definitions of the plugin meta in the stream
+func (g *StreamPluginGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	if g.MetricPlugin.Service != "" {
+		return nil, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.graphDefs, nil
+}
+
+// Generate returns the latest metric values received since the last call
+func (g *StreamPluginGenerator) Generate(ctx context.Context) (Values, error) {
+	values, _, err := g.GenerateWithTimestamps(ctx)
+	return values, err
+}
+
+// GenerateWithTimestamps returns the latest metric values received since the
+// last call with the timestamps printed by the plugin
+func (g *StreamPluginGenerator) GenerateWithTimestamps(context.Context) (Values, Timestamps, error) {
+	g.mu.Lock()
+	values, timestamps, errs := g.values, g.timestamps, g.errs
+	g.values, g.timestamps, g.errs = make(Values), make(Timestamps), nil
+	g.mu.Unlock()
+	if len(errs) > 0 {
+		logger.Warningf("plugin %s (%s): failed to parse %d metrics: %s", g.Name, g.Command, len(errs), summarizeErrors(errs))
+	}
+	g.counter.apply(values, timestamps, g.prefix(), time.Now())
+	return values, timestamps, nil
+}
@@ -2,8 +2,12 @@ package metric
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	mackerel "github.com/mackerelio/mackerel-client-go"
 
@@ -106,3 +110,212 @@ func TestPlugin_WithEnvGetGraphDefs(t *testing.T) {
 		t.Errorf("expected: %#v, got: %#v", expected, graphDefs[0].Metrics[0].Name)
 	}
 }
+
+func TestPlugin_GenerateWithTimestamps(t *testing.T) {
+	ctx := context.Background()
+	g := NewPluginGenerator(&config.MetricPlugin{
+		Name:    "json",
+		Command: cmdutil.CommandArgs([]string{"echo", `[{"name":"foo.bar","value":1.5,"time":1700000000},{"name":"foo.baz","value":2}]`}),
+		Format:  config.PluginFormatJSON,
+	}).(TimestampGenerator)
+	values, timestamps, err := g.GenerateWithTimestamps(ctx)
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if expected := (Values{"custom.foo.bar": 1.5, "custom.foo.baz": 2.0}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, values)
+	}
+	if expected := (Timestamps{"custom.foo.bar": 1700000000}); !reflect.DeepEqual(timestamps, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, timestamps)
+	}
+}
+
+func TestParsePluginText(t *testing.T) {
+	testCases := []struct {
+		name       string
+		stdout     string
+		values     Values
+		timestamps Timestamps
+		errs       int
+	}{
+		{
+			name:       "tab separated",
+			stdout:     "foo.bar\t1.5\t1700000000\nfoo.baz\t2\t1700000060\n",
+			values:     Values{"custom.foo.bar": 1.5, "custom.foo.baz": 2.0},
+			timestamps: Timestamps{"custom.foo.bar": 1700000000, "custom.foo.baz": 1700000060},
+		},
+		{
+			name:       "space separated with blank lines",
+			stdout:     "\nfoo.bar 1.5 1700000000\n\n",
+			values:     Values{"custom.foo.bar": 1.5},
+			timestamps: Timestamps{"custom.foo.bar": 1700000000},
+		},
+		{
+			name:       "extra fields",
+			stdout:     "foo.bar\t1.5\t1700000000\textra\tfields\n",
+			values:     Values{"custom.foo.bar": 1.5},
+			timestamps: Timestamps{"custom.foo.bar": 1700000000},
+		},
+		{
+			name:       "invalid lines",
+			stdout:     "foo.bar\t1.5\nfoo.baz\tx\t1700000000\nfoo.quux\t3\t1700000000\n",
+			values:     Values{"custom.foo.quux": 3.0},
+			timestamps: Timestamps{"custom.foo.quux": 1700000000},
+			errs:       2,
+		},
+		{
+			name:       "invalid timestamps",
+			stdout:     "foo.bar\t1.5\t0\nfoo.baz\t2\tnow\nfoo.qux\t3\t1700000000\n",
+			values:     Values{"custom.foo.bar": 1.5, "custom.foo.baz": 2.0, "custom.foo.qux": 3.0},
+			timestamps: Timestamps{"custom.foo.qux": 1700000000},
+			errs:       2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, timestamps, errs := parsePluginText(tc.stdout, pluginPrefix)
+			if !reflect.DeepEqual(values, tc.values) {
+				t.Errorf("values should be %v but got %v", tc.values, values)
+			}
+			if !reflect.DeepEqual(timestamps, tc.timestamps) {
+				t.Errorf("timestamps should be %v but got %v", tc.timestamps, timestamps)
+			}
+			if len(errs) != tc.errs {
+				t.Errorf("should have %d errors but got %v", tc.errs, errs)
+			}
+		})
+	}
+}
+
+func TestParsePluginJSON(t *testing.T) {
+	values, timestamps, errs, err := parsePluginJSON(
+		`[{"name":"foo.bar","value":1,"time":1700000000},{"name":"foo.baz"},{"value":3},{"name":"foo.qux","value":4}]`, "")
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if expected := (Values{"foo.bar": 1.0, "foo.qux": 4.0}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+	if expected := (Timestamps{"foo.bar": 1700000000}); !reflect.DeepEqual(timestamps, expected) {
+		t.Errorf("timestamps should be %v but got %v", expected, timestamps)
+	}
+	if len(errs) != 2 {
+		t.Errorf("should have 2 errors but got %v", errs)
+	}
+
+	if _, _, _, err := parsePluginJSON("foo.bar\t1\t1700000000\n", ""); err == nil {
+		t.Errorf("should raise error")
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	errs := []error{errors.New("a"), errors.New("b"), errors.New("c"), errors.New("d"), errors.New("e")}
+	if got, expected := summarizeErrors(errs), "a, b, c and 2 more"; got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+}
+
+func TestStreamPlugin(t *testing.T) {
+	defer func(d time.Duration) { streamPluginMinBackoff = d }(streamPluginMinBackoff)
+	streamPluginMinBackoff = 10 * time.Millisecond
+
+	g := NewStreamPluginGenerator(&config.MetricPlugin{
+		Name:    "stream",
+		Command: cmdutil.CommandString(`printf 'foo.bar\t1\t1700000000\nfoo.bar\t2\t1700000060\ninvalid\n'; exit 1`),
+	})
+	g.Start(context.Background())
+	defer g.Shutdown()
+
+	ctx := context.Background()
+	var values Values
+	var timestamps Timestamps
+	for range 100 {
+		time.Sleep(10 * time.Millisecond)
+		var err error
+		if values, timestamps, err = g.GenerateWithTimestamps(ctx); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		if len(values) > 0 {
+			break
+		}
+	}
+	if expected := (Values{"custom.foo.bar": 2.0}); !reflect.DeepEqual(values, expected) {
+		t.Errorf("values should be %v but got %v", expected, values)
+	}
+	if expected := (Timestamps{"custom.foo.bar": 1700000060}); !reflect.DeepEqual(timestamps, expected) {
+		t.Errorf("timestamps should be %v but got %v", expected, timestamps)
+	}
+
+	// the plugin is restarted after exit
+	for range 100 {
+		time.Sleep(10 * time.Millisecond)
+		if values, _ = g.Generate(ctx); len(values) > 0 {
+			break
+		}
+	}
+	if len(values) == 0 {
+		t.Errorf("plugin should be restarted")
+	}
+}
+
+func TestStreamPlugin_GetGraphDefs(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "meta")
+	g := NewStreamPluginGenerator(&config.MetricPlugin{
+		Name: "stream",
+		Command: cmdutil.CommandString(`[ "$MACKEREL_AGENT_PLUGIN_META" = 1 ] && touch ` + marker + `
+printf '# mackerel-agent-plugin\n{"graphs":{"foo":{"label":"Foo","unit":"integer","metrics":[{"name":"bar","label":"Bar"}]}}}\n'
+while true; do printf 'foo.bar\t1\t%s\n' "$(date +%s)"; sleep 1; done`),
+	})
+	g.Start(context.Background())
+	defer g.Shutdown()
+
+	ctx := context.Background()
+	var graphDefs []*mackerel.GraphDefsParam
+	for range 100 {
+		time.Sleep(10 * time.Millisecond)
+		var err error
+		if graphDefs, err = g.GetGraphDefs(ctx); err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		if len(graphDefs) > 0 {
+			break
+		}
+	}
+	expected := []*mackerel.GraphDefsParam{
+		{
+			Name:        "custom.foo",
+			DisplayName: "Foo",
+			Unit:        "integer",
+			Metrics: []*mackerel.GraphDefsMetric{
+				{Name: "custom.foo.bar", DisplayName: "Bar"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(graphDefs, expected) {
+		t.Errorf("graph definitions should be %#v but got %#v", expected, graphDefs)
+	}
+	if values, _ := g.Generate(ctx); !reflect.DeepEqual(values, Values{"custom.foo.bar": 1.0}) {
+		t.Errorf("the plugin meta should not be parsed as the values but got %v", values)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("plugin should not be executed to get the plugin meta")
+	}
+}
+
+func TestStreamPlugin_Shutdown(t *testing.T) {
+	g := NewStreamPluginGenerator(&config.MetricPlugin{
+		Name:    "stream",
+		Command: cmdutil.CommandString(`while true; do printf 'foo.bar\t1\t%s\n' "$(date +%s)"; sleep 1; done`),
+	})
+	g.Start(context.Background())
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	g.Shutdown()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("plugin should be stopped but took %s", elapsed)
+	}
+	if values, _ := g.Generate(context.Background()); !reflect.DeepEqual(values, Values{"custom.foo.bar": 1.0}) {
+		t.Errorf("should keep the values received before shutdown but got %v", values)
+	}
+}
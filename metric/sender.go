@@ -1,6 +1,10 @@
 package metric
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
 	"sync"
 
 	mackerel "github.com/mackerelio/mackerel-client-go"
@@ -8,45 +12,157 @@ import (
 	"github.com/mackerelio/mackerel-container-agent/api"
 )
 
+const (
+	maxPendingMetrics  = 60 * 6 // retry for 6 hours
+	maxPostBatches     = 60     // drain one hour of backlog per tick at most
+	maxChunkValues     = 5000
+	maxChunkBytes      = 1 << 20
+	postConcurrency    = 4
+	metricValueJSONLen = 80 // estimated size of a metric value in JSON except the name
+)
+
 type sender struct {
 	client         api.Client
 	hostID         string
 	pendingMetrics [][]*mackerel.MetricValue
 	mu             sync.Mutex
+	graphDefs      map[string]*mackerel.GraphDefsParam
+	graphDefsMu    sync.Mutex
 }
 
 func newSender(client api.Client) *sender {
-	return &sender{client: client}
+	return &sender{client: client, graphDefs: make(map[string]*mackerel.GraphDefsParam)}
 }
 
+type chunk struct {
+	values  []*mackerel.MetricValue
+	batches []int
+}
+
+// post posts the pending metric values. The batches are taken out of the
+// queue while posting, so the lock is not held during the requests, and only
+// the values of the failed chunks are put back.
 func (s *sender) post(metricValues []*mackerel.MetricValue) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.pendingMetrics = append(s.pendingMetrics, metricValues)
-	if s.hostID == "" {
+	s.truncatePending()
+	hostID := s.hostID
+	if hostID == "" {
+		s.mu.Unlock()
 		return nil
 	}
-	var postMetricValues []*mackerel.MetricValue
-	var postIndex int
-	for i, ms := range s.pendingMetrics {
-		postIndex = i
-		postMetricValues = append(postMetricValues, ms...)
-		if i > 1 { // send three oldest metrics at most
-			break
+	n := min(len(s.pendingMetrics), maxPostBatches)
+	batches := slices.Clone(s.pendingMetrics[:n])
+	s.pendingMetrics = slices.Delete(s.pendingMetrics, 0, n)
+	s.mu.Unlock()
+
+	retries, errs := postChunks(batches, func(values []*mackerel.MetricValue) error {
+		return s.client.PostHostMetricValuesByHostID(hostID, values)
+	})
+	var permanentErr error
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, api.ErrCircuitOpen):
+			logger.Debugf("postponed posting metric values: %s", err)
+		case api.IsPermanent(err):
+			permanentErr = err
+		default:
+			logger.Warningf("failed to post metric values but will retry posting: %s", err)
 		}
 	}
-	err := s.client.PostHostMetricValuesByHostID(s.hostID, postMetricValues)
-	if err == nil {
-		n := copy(s.pendingMetrics, s.pendingMetrics[postIndex+1:])
-		s.pendingMetrics = s.pendingMetrics[:n]
-	} else {
-		logger.Warningf("failed to post metric values but will retry posting: %s", err)
+	if len(retries) == 0 {
+		return permanentErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingMetrics = append(retries, s.pendingMetrics...)
+	s.truncatePending()
+	return permanentErr
+}
+
+// postChunks posts the batches split into the chunks in parallel with bounded
+// concurrency. It returns the values of the failed chunks grouped by the
+// batches, and the errors of the failed chunks.
+func postChunks(batches [][]*mackerel.MetricValue, post func([]*mackerel.MetricValue) error) ([][]*mackerel.MetricValue, []error) {
+	chunks := splitChunks(batches, maxChunkValues, maxChunkBytes)
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, postConcurrency)
+	for i, c := range chunks {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			errs[i] = post(c.values)
+		})
+	}
+	wg.Wait()
+
+	failed := make(map[*mackerel.MetricValue]bool)
+	var failedErrs []error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		for _, m := range chunks[i].values {
+			failed[m] = true
+		}
+		failedErrs = append(failedErrs, err)
+	}
+	if len(failed) == 0 {
+		return nil, nil
+	}
+
+	var retries [][]*mackerel.MetricValue
+	for _, ms := range batches {
+		var retry []*mackerel.MetricValue
+		for _, m := range ms {
+			if failed[m] {
+				retry = append(retry, m)
+			}
+		}
+		if len(retry) > 0 {
+			retries = append(retries, retry)
+		}
 	}
-	if len(s.pendingMetrics) > 60*6 { // retry for 6 hours
-		n := copy(s.pendingMetrics, s.pendingMetrics[len(s.pendingMetrics)-60*6:])
+	return retries, failedErrs
+}
+
+func (s *sender) truncatePending() {
+	if len(s.pendingMetrics) > maxPendingMetrics {
+		n := copy(s.pendingMetrics, s.pendingMetrics[len(s.pendingMetrics)-maxPendingMetrics:])
 		s.pendingMetrics = s.pendingMetrics[:n]
 	}
-	return nil
+}
+
+// splitChunks packs the batches into the chunks limited by the number of
+// values and the estimated size of the request body. A batch larger than the
+// limits is split into multiple chunks.
+func splitChunks(batches [][]*mackerel.MetricValue, maxValues, maxBytes int) []*chunk {
+	var chunks []*chunk
+	var current *chunk
+	var size int
+	for i, ms := range batches {
+		for _, m := range ms {
+			n := metricValueSize(m)
+			if current == nil || len(current.values) >= maxValues || (len(current.values) > 0 && size+n > maxBytes) {
+				current = &chunk{}
+				chunks = append(chunks, current)
+				size = 0
+			}
+			current.values = append(current.values, m)
+			if l := len(current.batches); l == 0 || current.batches[l-1] != i {
+				current.batches = append(current.batches, i)
+			}
+			size += n
+		}
+	}
+	return chunks
+}
+
+func metricValueSize(m *mackerel.MetricValue) int {
+	name, _ := json.Marshal(m.Name)
+	return metricValueJSONLen + len(name)
 }
 
 func (s *sender) setHostID(hostID string) {
@@ -55,9 +171,32 @@ func (s *sender) setHostID(hostID string) {
 	s.hostID = hostID
 }
 
+func (s *sender) hasHostID() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hostID != ""
+}
+
+// postGraphDefs posts the graph definitions which are new or changed since the last post
 func (s *sender) postGraphDefs(graphDefs []*mackerel.GraphDefsParam) error {
-	if len(graphDefs) == 0 {
+	s.graphDefsMu.Lock()
+	defer s.graphDefsMu.Unlock()
+	var changed []*mackerel.GraphDefsParam
+	for _, graphDef := range graphDefs {
+		if posted, ok := s.graphDefs[graphDef.Name]; ok && reflect.DeepEqual(posted, graphDef) {
+			continue
+		}
+		changed = append(changed, graphDef)
+	}
+	if len(changed) == 0 {
 		return nil
 	}
-	return s.client.CreateGraphDefs(graphDefs)
+	if err := s.client.CreateGraphDefs(changed); err != nil {
+		return err
+	}
+	for _, graphDef := range changed {
+		s.graphDefs[graphDef.Name] = graphDef
+	}
+	logger.Debugf("posted %d graph definitions", len(changed))
+	return nil
 }
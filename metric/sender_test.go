@@ -0,0 +1,196 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/api"
+)
+
+func createMetricValues(n int, t int64) []*mackerel.MetricValue {
+	ms := make([]*mackerel.MetricValue, n)
+	for i := range ms {
+		ms[i] = &mackerel.MetricValue{Name: fmt.Sprintf("custom.foo.%d", i), Time: t, Value: float64(i)}
+	}
+	return ms
+}
+
+func TestSplitChunks(t *testing.T) {
+	batches := [][]*mackerel.MetricValue{
+		createMetricValues(3, 0),
+		createMetricValues(5, 60),
+		createMetricValues(1, 120),
+	}
+	size := metricValueSize(batches[0][0])
+
+	tests := []struct {
+		maxValues int
+		maxBytes  int
+		expect    [][]int // the number of values and the batch indices
+	}{
+		{100, 100 * size, [][]int{{9, 0, 1, 2}}},
+		{4, 100 * size, [][]int{{4, 0, 1}, {4, 1}, {1, 2}}},
+		{100, 3 * size, [][]int{{3, 0}, {3, 1}, {3, 1, 2}}},
+		{100, 0, [][]int{{1, 0}, {1, 0}, {1, 0}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 2}}},
+	}
+	for _, tc := range tests {
+		chunks := splitChunks(batches, tc.maxValues, tc.maxBytes)
+		got := make([][]int, len(chunks))
+		for i, c := range chunks {
+			got[i] = append([]int{len(c.values)}, c.batches...)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.expect) {
+			t.Errorf("splitChunks(%d, %d) should be %v but got %v", tc.maxValues, tc.maxBytes, tc.expect, got)
+		}
+	}
+}
+
+func TestSenderPost(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		posted  int
+		fail    bool
+		failErr error
+	)
+	client := api.NewMockClient(
+		api.MockPostHostMetricValuesByHostID(func(hostID string, metricValues []*mackerel.MetricValue) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if fail {
+				return failErr
+			}
+			posted += len(metricValues)
+			return nil
+		}),
+	)
+	s := newSender(client)
+
+	// keep the metric values until the host id is resolved
+	for i := range 100 {
+		s.post(createMetricValues(100, int64(i*60))) // nolint
+	}
+	if posted != 0 || len(s.pendingMetrics) != 100 {
+		t.Fatalf("should not post before host id is set: posted %d, pending %d", posted, len(s.pendingMetrics))
+	}
+
+	// drain the backlog by maxPostBatches per tick
+	s.setHostID("abcde")
+	s.post(createMetricValues(100, 6000)) // nolint
+	if expected := 100 * maxPostBatches; posted != expected {
+		t.Errorf("should post %d values but got %d", expected, posted)
+	}
+	if expected := 101 - maxPostBatches; len(s.pendingMetrics) != expected {
+		t.Errorf("pending metrics should be %d but got %d", expected, len(s.pendingMetrics))
+	}
+
+	// keep the metric values while the circuit breaker is open
+	fail, failErr = true, api.ErrCircuitOpen
+	for i := range 3 {
+		if err := s.post(createMetricValues(100, int64(6060+i*60))); err != nil {
+			t.Errorf("should not raise error: %v", err)
+		}
+	}
+	if expected := 100 * maxPostBatches; posted != expected {
+		t.Errorf("should not post while the circuit breaker is open but posted %d", posted)
+	}
+
+	// return the permanent error to stop the manager
+	failErr = &mackerel.APIError{StatusCode: 401}
+	if err := s.post(createMetricValues(100, 6240)); !api.IsPermanent(err) {
+		t.Errorf("should raise permanent error but got %v", err)
+	}
+
+	// drain all the backlog after recovery
+	fail = false
+	for len(s.pendingMetrics) > 0 {
+		s.post(nil) // nolint
+	}
+	if expected := 100 * 105; posted != expected {
+		t.Errorf("should post %d values but got %d", expected, posted)
+	}
+}
+
+func TestSenderPost_PartialFailure(t *testing.T) {
+	var s *sender
+	var mu sync.Mutex
+	var posted int
+	s = newSender(api.NewMockClient(
+		api.MockPostHostMetricValuesByHostID(func(hostID string, metricValues []*mackerel.MetricValue) error {
+			// the lock should be released while posting
+			s.hasHostID()
+			if metricValues[0].Name == fmt.Sprintf("custom.foo.%d", maxChunkValues) {
+				return errors.New("failed")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			posted += len(metricValues)
+			return nil
+		}),
+	))
+	s.setHostID("abcde")
+
+	if err := s.post(createMetricValues(maxChunkValues*2+100, 0)); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if expected := maxChunkValues + 100; posted != expected {
+		t.Errorf("should post %d values but got %d", expected, posted)
+	}
+	if len(s.pendingMetrics) != 1 || len(s.pendingMetrics[0]) != maxChunkValues {
+		t.Fatalf("only the failed chunk should be pending but got %d batches", len(s.pendingMetrics))
+	}
+	if name := s.pendingMetrics[0][0].Name; name != fmt.Sprintf("custom.foo.%d", maxChunkValues) {
+		t.Errorf("the failed chunk should be pending but got %s", name)
+	}
+}
+
+func TestSenderPostGraphDefs(t *testing.T) {
+	var posted [][]*mackerel.GraphDefsParam
+	var fail bool
+	client := api.NewMockClient(
+		api.MockCreateGraphDefs(func(graphDefs []*mackerel.GraphDefsParam) error {
+			if fail {
+				return errors.New("connection refused")
+			}
+			posted = append(posted, graphDefs)
+			return nil
+		}),
+	)
+	s := newSender(client)
+	graphDef := func(name, displayName string) *mackerel.GraphDefsParam {
+		return &mackerel.GraphDefsParam{
+			Name:        name,
+			DisplayName: displayName,
+			Metrics:     []*mackerel.GraphDefsMetric{{Name: name + ".*"}},
+		}
+	}
+
+	if err := s.postGraphDefs([]*mackerel.GraphDefsParam{graphDef("custom.foo", "Foo"), graphDef("custom.bar", "Bar")}); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	// nothing is posted without changes
+	if err := s.postGraphDefs([]*mackerel.GraphDefsParam{graphDef("custom.foo", "Foo"), graphDef("custom.bar", "Bar")}); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	fail = true
+	if err := s.postGraphDefs([]*mackerel.GraphDefsParam{graphDef("custom.foo", "Foo"), graphDef("custom.bar", "Bar 2")}); err == nil {
+		t.Errorf("should raise error")
+	}
+	// the failed graph definitions are posted again
+	fail = false
+	if err := s.postGraphDefs([]*mackerel.GraphDefsParam{graphDef("custom.foo", "Foo"), graphDef("custom.bar", "Bar 2"), graphDef("custom.baz", "Baz")}); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+
+	expected := [][]*mackerel.GraphDefsParam{
+		{graphDef("custom.foo", "Foo"), graphDef("custom.bar", "Bar")},
+		{graphDef("custom.bar", "Bar 2"), graphDef("custom.baz", "Baz")},
+	}
+	if !reflect.DeepEqual(posted, expected) {
+		t.Errorf("posted graph definitions should be %v but got %v", expected, posted)
+	}
+}
@@ -0,0 +1,122 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/api"
+)
+
+// ServiceGenerator interface is implemented by the generators which can target a Mackerel service.
+// The values of the generator are posted as the service metrics when Service returns non-empty name.
+type ServiceGenerator interface {
+	Generator
+	Service() string
+}
+
+type serviceGenerator struct {
+	Generator
+	service string
+}
+
+// NewServiceGenerator wraps the generator to post the values as the service
+// metrics. The generator is returned as is when the service is empty.
+func NewServiceGenerator(service string, g Generator) Generator {
+	if service == "" {
+		return g
+	}
+	return &serviceGenerator{Generator: g, service: service}
+}
+
+// Service returns the service name
+func (g *serviceGenerator) Service() string {
+	return g.service
+}
+
+// GenerateWithTimestamps generates metric values with the timestamps of the wrapped generator
+func (g *serviceGenerator) GenerateWithTimestamps(ctx context.Context) (Values, Timestamps, error) {
+	return generate(ctx, g.Generator)
+}
+
+// GetGraphDefs returns nothing since the graphs of service metrics are not defined by the agent
+func (g *serviceGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+func generatorService(g Generator) string {
+	if sg, ok := g.(ServiceGenerator); ok {
+		return sg.Service()
+	}
+	return ""
+}
+
+// splitGenerators splits the generators into the host ones and the service ones by service name
+func splitGenerators(generators []Generator) ([]Generator, map[string][]Generator) {
+	var hostGenerators []Generator
+	serviceGenerators := make(map[string][]Generator)
+	for _, g := range generators {
+		if service := generatorService(g); service != "" {
+			serviceGenerators[service] = append(serviceGenerators[service], g)
+		} else {
+			hostGenerators = append(hostGenerators, g)
+		}
+	}
+	return hostGenerators, serviceGenerators
+}
+
+// serviceSender posts the service metric values with the queue for each service.
+// Unlike the host metric values, they are posted without waiting for the host id.
+type serviceSender struct {
+	client         api.Client
+	pendingMetrics map[string][][]*mackerel.MetricValue
+	mu             sync.Mutex
+}
+
+func newServiceSender(client api.Client) *serviceSender {
+	return &serviceSender{
+		client:         client,
+		pendingMetrics: make(map[string][][]*mackerel.MetricValue),
+	}
+}
+
+// post posts the pending service metric values like the host metric sender,
+// without holding the lock during the requests
+func (s *serviceSender) post(service string, metricValues []*mackerel.MetricValue) error {
+	s.mu.Lock()
+	pending := append(s.pendingMetrics[service], metricValues)
+	n := min(len(pending), maxPostBatches)
+	batches := slices.Clone(pending[:n])
+	s.pendingMetrics[service] = slices.Delete(pending, 0, n)
+	s.mu.Unlock()
+
+	retries, errs := postChunks(batches, func(values []*mackerel.MetricValue) error {
+		return s.client.PostServiceMetricValues(service, values)
+	})
+	var permanentErr error
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, api.ErrCircuitOpen):
+			logger.Debugf("postponed posting service metric values: %s", err)
+		case api.IsPermanent(err):
+			permanentErr = err
+		default:
+			logger.Warningf("failed to post service metric values of %s but will retry posting: %s", service, err)
+		}
+	}
+	if len(retries) == 0 {
+		return permanentErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rest := append(retries, s.pendingMetrics[service]...)
+	if len(rest) > maxPendingMetrics {
+		rest = rest[len(rest)-maxPendingMetrics:]
+	}
+	s.pendingMetrics[service] = rest
+	return permanentErr
+}
@@ -0,0 +1,149 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/api"
+	"github.com/mackerelio/mackerel-container-agent/cmdutil"
+	"github.com/mackerelio/mackerel-container-agent/config"
+)
+
+func TestSplitGenerators(t *testing.T) {
+	host := NewMockGenerator(Values{"custom.foo.bar": 1.0}, nil, nil, nil)
+	plugin := NewPluginGenerator(&config.MetricPlugin{Name: "host"})
+	service1 := NewServiceGenerator("service1", NewMockGenerator(Values{"queue.depth": 2.0}, nil, nil, nil))
+	service2 := NewPluginGenerator(&config.MetricPlugin{Name: "service", Service: "service2"})
+
+	hostGenerators, serviceGenerators := splitGenerators([]Generator{host, service1, plugin, service2})
+	if expected := []Generator{host, plugin}; !reflect.DeepEqual(hostGenerators, expected) {
+		t.Errorf("host generators should be %v but got %v", expected, hostGenerators)
+	}
+	expected := map[string][]Generator{"service1": {service1}, "service2": {service2}}
+	if !reflect.DeepEqual(serviceGenerators, expected) {
+		t.Errorf("service generators should be %v but got %v", expected, serviceGenerators)
+	}
+	if graphDefs, _ := service2.GetGraphDefs(context.Background()); graphDefs != nil {
+		t.Errorf("service plugin should not define graphs but got %v", graphDefs)
+	}
+}
+
+func TestPlugin_GenerateService(t *testing.T) {
+	g := NewPluginGenerator(&config.MetricPlugin{
+		Name:    "dice",
+		Command: cmdutil.CommandString("../example/dice.sh"),
+		Service: "dice",
+	})
+	values, err := g.Generate(context.Background())
+	if err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if expected := []string{"dice.d20", "dice.d6"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("service metric names should be %v but got %v", expected, names)
+	}
+}
+
+func TestServiceSender(t *testing.T) {
+	var fail error
+	posted := make(map[string]int)
+	client := api.NewMockClient(
+		api.MockPostServiceMetricValues(func(service string, values []*mackerel.MetricValue) error {
+			if fail != nil {
+				return fail
+			}
+			posted[service] += len(values)
+			return nil
+		}),
+	)
+	s := newServiceSender(client)
+
+	fail = errors.New("connection refused")
+	if err := s.post("service1", createMetricValues(3, 0)); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if len(s.pendingMetrics["service1"]) != 1 {
+		t.Errorf("should keep pending metrics on error")
+	}
+
+	fail = nil
+	s.post("service1", createMetricValues(3, 60)) // nolint
+	s.post("service2", createMetricValues(2, 60)) // nolint
+	if expected := map[string]int{"service1": 6, "service2": 2}; !reflect.DeepEqual(posted, expected) {
+		t.Errorf("posted values should be %v but got %v", expected, posted)
+	}
+	if len(s.pendingMetrics["service1"]) != 0 {
+		t.Errorf("should drain pending metrics")
+	}
+
+	fail = &mackerel.APIError{StatusCode: 403}
+	if err := s.post("service1", createMetricValues(1, 120)); !api.IsPermanent(err) {
+		t.Errorf("should raise permanent error but got %v", err)
+	}
+}
+
+func TestServiceSender_PartialFailure(t *testing.T) {
+	var s *serviceSender
+	var mu sync.Mutex
+	var posted int
+	s = newServiceSender(api.NewMockClient(
+		api.MockPostServiceMetricValues(func(service string, values []*mackerel.MetricValue) error {
+			// the lock should be released while posting
+			s.mu.Lock()
+			s.mu.Unlock() // nolint
+			if values[0].Name == fmt.Sprintf("custom.foo.%d", maxChunkValues) {
+				return errors.New("failed")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			posted += len(values)
+			return nil
+		}),
+	))
+
+	if err := s.post("service1", createMetricValues(maxChunkValues*2+100, 0)); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	if expected := maxChunkValues + 100; posted != expected {
+		t.Errorf("should post %d values but got %d", expected, posted)
+	}
+	pending := s.pendingMetrics["service1"]
+	if len(pending) != 1 || len(pending[0]) != maxChunkValues {
+		t.Fatalf("only the failed chunk should be pending but got %d batches", len(pending))
+	}
+	if name := pending[0][0].Name; name != fmt.Sprintf("custom.foo.%d", maxChunkValues) {
+		t.Errorf("the failed chunk should be pending but got %s", name)
+	}
+}
+
+func TestManagerServiceMetrics(t *testing.T) {
+	client := api.NewMockClient()
+	generators := append(createMockGenerators(),
+		NewServiceGenerator("service1", NewMockGenerator(Values{"queue.depth": 2.0}, nil, nil, nil)))
+	manager := NewManager(generators, client)
+	ctx := context.Background()
+
+	// service metrics are posted before host id is resolved
+	if err := manager.collectAndPostValues(ctx, time.Now()); err != nil {
+		t.Errorf("should not raise error: %v", err)
+	}
+	values := client.PostedServiceMetricValues()["service1"]
+	if len(values) != 1 || values[0].Name != "queue.depth" || values[0].Value != 2.0 {
+		t.Errorf("unexpected service metric values: %v", values)
+	}
+	if len(client.PostedMetricValues()) != 0 {
+		t.Errorf("host metric values should not be posted before host id is resolved")
+	}
+}
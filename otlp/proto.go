@@ -0,0 +1,361 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// protoReader reads the fields of a protobuf message in wire format
+type protoReader struct {
+	b []byte
+}
+
+func (r *protoReader) next() (field int, wire int, err error) {
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(key >> 3), int(key & 7), nil
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+	if len(r.b) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v, nil
+}
+
+func (r *protoReader) double() (float64, error) {
+	v, err := r.fixed64()
+	return math.Float64frombits(v), err
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.b)) < n {
+		return nil, errTruncated
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *protoReader) skip(wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		if len(r.b) < 4 {
+			return errTruncated
+		}
+		r.b = r.b[4:]
+	default:
+		err = fmt.Errorf("unsupported wire type: %d", wire)
+	}
+	return err
+}
+
+// readMessage calls the handler for each field, and skips the fields which the handler does not read
+func readMessage(b []byte, handler func(r *protoReader, field, wire int) (bool, error)) error {
+	r := &protoReader{b: b}
+	for len(r.b) > 0 {
+		field, wire, err := r.next()
+		if err != nil {
+			return err
+		}
+		ok, err := handler(r, field, wire)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := r.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readEmbedded reads the embedded message field with the decoder
+func readEmbedded[T any](r *protoReader, wire int, decode func([]byte, *T) error) (*T, error) {
+	if wire != wireBytes {
+		return nil, fmt.Errorf("unexpected wire type: %d", wire)
+	}
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if err := decode(b, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func decodeExportMetricsServiceRequest(b []byte, req *exportMetricsServiceRequest) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		if field != 1 {
+			return false, nil
+		}
+		rm, err := readEmbedded(r, wire, decodeResourceMetrics)
+		if err == nil {
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+		}
+		return true, err
+	})
+}
+
+func decodeResourceMetrics(b []byte, rm *resourceMetrics) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		if field != 2 {
+			return false, nil
+		}
+		sm, err := readEmbedded(r, wire, decodeScopeMetrics)
+		if err == nil {
+			rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+		}
+		return true, err
+	})
+}
+
+func decodeScopeMetrics(b []byte, sm *scopeMetrics) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		if field != 2 {
+			return false, nil
+		}
+		m, err := readEmbedded(r, wire, decodeMetric)
+		if err == nil {
+			sm.Metrics = append(sm.Metrics, m)
+		}
+		return true, err
+	})
+}
+
+func decodeMetric(b []byte, m *metricData) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		var err error
+		switch field {
+		case 1:
+			m.Name, err = readString(r, wire)
+		case 3:
+			m.Unit, err = readString(r, wire)
+		case 5:
+			m.Gauge, err = readEmbedded(r, wire, decodeGauge)
+		case 7:
+			m.Sum, err = readEmbedded(r, wire, decodeSum)
+		case 9:
+			m.Histogram, err = readEmbedded(r, wire, decodeHistogram)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func decodeGauge(b []byte, g *gauge) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		if field != 1 {
+			return false, nil
+		}
+		p, err := readEmbedded(r, wire, decodeNumberDataPoint)
+		if err == nil {
+			g.DataPoints = append(g.DataPoints, p)
+		}
+		return true, err
+	})
+}
+
+func decodeSum(b []byte, s *sum) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		switch {
+		case field == 1:
+			p, err := readEmbedded(r, wire, decodeNumberDataPoint)
+			if err == nil {
+				s.DataPoints = append(s.DataPoints, p)
+			}
+			return true, err
+		case field == 2 && wire == wireVarint:
+			v, err := r.varint()
+			s.AggregationTemporality = int(v)
+			return true, err
+		case field == 3 && wire == wireVarint:
+			v, err := r.varint()
+			s.IsMonotonic = v != 0
+			return true, err
+		default:
+			return false, nil
+		}
+	})
+}
+
+func decodeHistogram(b []byte, h *histogram) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		switch {
+		case field == 1:
+			p, err := readEmbedded(r, wire, decodeHistogramDataPoint)
+			if err == nil {
+				h.DataPoints = append(h.DataPoints, p)
+			}
+			return true, err
+		case field == 2 && wire == wireVarint:
+			v, err := r.varint()
+			h.AggregationTemporality = int(v)
+			return true, err
+		default:
+			return false, nil
+		}
+	})
+}
+
+func decodeNumberDataPoint(b []byte, p *numberDataPoint) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		switch {
+		case field == 7:
+			kv, err := readEmbedded(r, wire, decodeKeyValue)
+			if err == nil {
+				p.Attributes = append(p.Attributes, kv)
+			}
+			return true, err
+		case field == 3 && wire == wireFixed64:
+			v, err := r.fixed64()
+			p.TimeUnixNano = jsonUint64(v)
+			return true, err
+		case field == 4 && wire == wireFixed64:
+			v, err := r.double()
+			p.AsDouble = &v
+			return true, err
+		case field == 6 && wire == wireFixed64:
+			v, err := r.fixed64()
+			i := jsonInt64(int64(v))
+			p.AsInt = &i
+			return true, err
+		default:
+			return false, nil
+		}
+	})
+}
+
+func decodeHistogramDataPoint(b []byte, p *histogramDataPoint) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		if field == 9 {
+			kv, err := readEmbedded(r, wire, decodeKeyValue)
+			if err == nil {
+				p.Attributes = append(p.Attributes, kv)
+			}
+			return true, err
+		}
+		if wire != wireFixed64 {
+			return false, nil
+		}
+		var err error
+		switch field {
+		case 3:
+			var v uint64
+			v, err = r.fixed64()
+			p.TimeUnixNano = jsonUint64(v)
+		case 4:
+			var v uint64
+			v, err = r.fixed64()
+			p.Count = jsonUint64(v)
+		case 5:
+			var v float64
+			v, err = r.double()
+			p.Sum = &v
+		case 11:
+			var v float64
+			v, err = r.double()
+			p.Min = &v
+		case 12:
+			var v float64
+			v, err = r.double()
+			p.Max = &v
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func decodeKeyValue(b []byte, kv *keyValue) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		var err error
+		switch field {
+		case 1:
+			kv.Key, err = readString(r, wire)
+		case 2:
+			var v *anyValue
+			if v, err = readEmbedded(r, wire, decodeAnyValue); err == nil {
+				kv.Value = *v
+			}
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+func decodeAnyValue(b []byte, v *anyValue) error {
+	return readMessage(b, func(r *protoReader, field, wire int) (bool, error) {
+		switch {
+		case field == 1:
+			s, err := readString(r, wire)
+			v.StringValue = &s
+			return true, err
+		case field == 2 && wire == wireVarint:
+			x, err := r.varint()
+			b := x != 0
+			v.BoolValue = &b
+			return true, err
+		case field == 3 && wire == wireVarint:
+			x, err := r.varint()
+			i := jsonInt64(int64(x))
+			v.IntValue = &i
+			return true, err
+		case field == 4 && wire == wireFixed64:
+			x, err := r.double()
+			v.DoubleValue = &x
+			return true, err
+		default:
+			return false, nil
+		}
+	})
+}
+
+func readString(r *protoReader, wire int) (string, error) {
+	if wire != wireBytes {
+		return "", fmt.Errorf("unexpected wire type: %d", wire)
+	}
+	b, err := r.bytes()
+	return string(b), err
+}
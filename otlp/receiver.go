@@ -0,0 +1,222 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mackerelio/golib/logging"
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/metric"
+)
+
+var logger = logging.GetLogger("otlp")
+
+const (
+	httpMetricsPath = "/v1/metrics"
+	grpcExportPath  = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+	maxRequestSize  = 16 << 20
+	shutdownTimeout = 3 * time.Second
+)
+
+// The gRPC status codes
+const (
+	grpcStatusOK              = 0
+	grpcStatusInvalidArgument = 3
+	grpcStatusUnimplemented   = 12
+)
+
+// Receiver receives metrics from OpenTelemetry SDK via OTLP/HTTP and OTLP/gRPC,
+// and works as a metric generator of the received values.
+type Receiver struct {
+	httpAddress string
+	grpcAddress string
+	store       *store
+	servers     []*http.Server
+}
+
+// NewReceiver creates a new OTLP metrics receiver
+func NewReceiver(conf *config.OTLPReceiver) *Receiver {
+	return &Receiver{
+		httpAddress: conf.HTTPAddress,
+		grpcAddress: conf.GRPCAddress,
+		store:       newStore(),
+	}
+}
+
+// Start starts the receiver servers
+func (r *Receiver) Start() error {
+	var servers []*http.Server
+	if r.httpAddress != "" {
+		servers = append(servers, &http.Server{Addr: r.httpAddress, Handler: http.HandlerFunc(r.handleHTTP)})
+	}
+	if r.grpcAddress != "" {
+		protocols := new(http.Protocols)
+		protocols.SetUnencryptedHTTP2(true)
+		servers = append(servers, &http.Server{Addr: r.grpcAddress, Handler: http.HandlerFunc(r.handleGRPC), Protocols: protocols})
+	}
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, s := range servers {
+		l, err := net.Listen("tcp", s.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close() // nolint
+			}
+			return fmt.Errorf("failed to start OTLP receiver: %w", err)
+		}
+		listeners = append(listeners, l)
+	}
+	for i, s := range servers {
+		logger.Infof("start OTLP receiver on %s", s.Addr)
+		go func() {
+			if err := s.Serve(listeners[i]); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorf("OTLP receiver on %s stopped: %s", s.Addr, err)
+			}
+		}()
+	}
+	r.servers = servers
+	return nil
+}
+
+// Shutdown shuts down the receiver servers
+func (r *Receiver) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, s := range r.servers {
+		s.Shutdown(ctx) // nolint
+	}
+}
+
+// Generate generates metric values
+func (r *Receiver) Generate(context.Context) (metric.Values, error) {
+	return r.store.collect(), nil
+}
+
+// GetGraphDefs gets graph definitions of the received metrics
+func (r *Receiver) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return r.store.graphDefs(), nil
+}
+
+func (r *Receiver) handleHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != httpMetricsPath {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := readBody(req.Body, req.Header.Get("Content-Encoding") == "gzip")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var exportReq exportMetricsServiceRequest
+	contentType, _, _ := strings.Cut(req.Header.Get("Content-Type"), ";")
+	switch contentType {
+	case "application/x-protobuf":
+		err = decodeExportMetricsServiceRequest(body, &exportReq)
+	case "application/json":
+		err = json.Unmarshal(body, &exportReq)
+	default:
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.store.add(&exportReq)
+	// respond with empty ExportMetricsServiceResponse
+	w.Header().Set("Content-Type", contentType)
+	if contentType == "application/json" {
+		w.Write([]byte("{}")) // nolint
+	}
+}
+
+func (r *Receiver) handleGRPC(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/grpc")
+	if req.URL.Path != grpcExportPath {
+		writeGRPCStatus(w, grpcStatusUnimplemented, "unknown method "+req.URL.Path)
+		return
+	}
+	if req.Method != http.MethodPost || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	message, err := readGRPCMessage(req.Body, req.Header.Get("Grpc-Encoding"))
+	if err != nil {
+		writeGRPCStatus(w, grpcStatusInvalidArgument, err.Error())
+		return
+	}
+	var exportReq exportMetricsServiceRequest
+	if err := decodeExportMetricsServiceRequest(message, &exportReq); err != nil {
+		writeGRPCStatus(w, grpcStatusInvalidArgument, err.Error())
+		return
+	}
+	r.store.add(&exportReq)
+	// respond with empty ExportMetricsServiceResponse
+	w.Write([]byte{0, 0, 0, 0, 0}) // nolint
+	writeGRPCStatus(w, grpcStatusOK, "")
+}
+
+// readGRPCMessage reads a length-prefixed message of gRPC
+func readGRPCMessage(r io.Reader, encoding string) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxRequestSize {
+		return nil, fmt.Errorf("message too large: %d bytes", n)
+	}
+	message := make([]byte, n)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, err
+	}
+	if header[0] == 0 {
+		return message, nil
+	}
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("unsupported grpc-encoding: %q", encoding)
+	}
+	return readBody(bytes.NewReader(message), true)
+}
+
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", fmt.Sprint(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", message)
+	}
+}
+
+func readBody(r io.Reader, gzipped bool) ([]byte, error) {
+	if gzipped {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close() // nolint
+		r = zr
+	}
+	body, err := io.ReadAll(io.LimitReader(r, maxRequestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxRequestSize {
+		return nil, errors.New("request too large")
+	}
+	return body, nil
+}
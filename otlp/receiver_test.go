@@ -0,0 +1,253 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/metric"
+)
+
+// protobuf encoders for the tests
+func pbBytes(field int, v []byte) []byte {
+	b := binary.AppendUvarint(nil, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func pbString(field int, v string) []byte {
+	return pbBytes(field, []byte(v))
+}
+
+func pbVarint(field int, v uint64) []byte {
+	return binary.AppendUvarint(binary.AppendUvarint(nil, uint64(field)<<3|wireVarint), v)
+}
+
+func pbFixed64(field int, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(binary.AppendUvarint(nil, uint64(field)<<3|wireFixed64), v)
+}
+
+func pbDouble(field int, v float64) []byte {
+	return pbFixed64(field, math.Float64bits(v))
+}
+
+func concat(bs ...[]byte) []byte {
+	return bytes.Join(bs, nil)
+}
+
+// exportRequestProto builds the request with a gauge, a cumulative sum and a delta histogram
+func exportRequestProto() []byte {
+	attr := pbBytes(7, concat(pbString(1, "http.method"), pbBytes(2, pbString(1, "GET"))))
+	gaugeMetric := concat(
+		pbString(1, "process.memory.usage"),
+		pbString(3, "By"),
+		pbBytes(5, pbBytes(1, concat(pbFixed64(3, 1790812800e9), pbFixed64(6, 1024)))),
+	)
+	sumMetric := concat(
+		pbString(1, "http.server.requests"),
+		pbBytes(7, concat(
+			pbBytes(1, concat(attr, pbFixed64(3, 1790812800e9), pbDouble(4, 42))),
+			pbVarint(2, aggregationTemporalityCumulative),
+			pbVarint(3, 1),
+		)),
+	)
+	histogramMetric := concat(
+		pbString(1, "http.server.duration"),
+		pbString(3, "ms"),
+		pbBytes(9, concat(
+			pbBytes(1, concat(
+				pbBytes(9, concat(pbString(1, "http.method"), pbBytes(2, pbString(1, "GET")))),
+				pbFixed64(3, 1790812800e9), pbFixed64(4, 10), pbDouble(5, 123.5),
+				pbBytes(6, []byte{}), pbDouble(12, 50),
+			)),
+			pbVarint(2, aggregationTemporalityDelta),
+		)),
+	)
+	return pbBytes(1, concat(
+		pbBytes(1, pbBytes(1, concat(pbString(1, "service.name"), pbBytes(2, pbString(1, "web"))))),
+		pbBytes(2, concat(
+			pbBytes(1, pbString(1, "io.opentelemetry.sdk")),
+			pbBytes(2, gaugeMetric),
+			pbBytes(2, sumMetric),
+			pbBytes(2, histogramMetric),
+		)),
+	))
+}
+
+const exportRequestJSON = `{
+  "resourceMetrics": [{
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "web"}}]},
+    "scopeMetrics": [{
+      "scope": {"name": "io.opentelemetry.sdk"},
+      "metrics": [
+        {"name": "process.memory.usage", "unit": "By",
+         "gauge": {"dataPoints": [{"timeUnixNano": "1790812800000000000", "asInt": "1024"}]}},
+        {"name": "http.server.requests",
+         "sum": {"aggregationTemporality": 2, "isMonotonic": true,
+                 "dataPoints": [{"attributes": [{"key": "http.method", "value": {"stringValue": "GET"}}],
+                                 "timeUnixNano": "1790812800000000000", "asDouble": 42}]}},
+        {"name": "http.server.duration", "unit": "ms",
+         "histogram": {"aggregationTemporality": 1,
+                       "dataPoints": [{"attributes": [{"key": "http.method", "value": {"stringValue": "GET"}}],
+                                       "timeUnixNano": "1790812800000000000", "count": "10", "sum": 123.5,
+                                       "bucketCounts": [], "max": 50}]}}
+      ]
+    }]
+  }]
+}`
+
+var expectedValues = metric.Values{
+	"custom.otel.process_memory_usage.default":               1024,
+	"custom.otel.http_server_requests.http_method-GET":       42,
+	"custom.otel.http_server_duration_count.http_method-GET": 10,
+	"custom.otel.http_server_duration_sum.http_method-GET":   123.5,
+	"custom.otel.http_server_duration_max.http_method-GET":   50,
+}
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+	return l.Addr().String()
+}
+
+func startReceiver(t *testing.T) *Receiver {
+	t.Helper()
+	r := NewReceiver(&config.OTLPReceiver{HTTPAddress: freeAddress(t), GRPCAddress: freeAddress(t)})
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Shutdown)
+	return r
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(b) // nolint
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReceiverHTTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		gzip        bool
+		status      int
+	}{
+		{"protobuf", "application/x-protobuf", exportRequestProto(), false, http.StatusOK},
+		{"json", "application/json", []byte(exportRequestJSON), false, http.StatusOK},
+		{"gzip", "application/x-protobuf", exportRequestProto(), true, http.StatusOK},
+		{"invalid protobuf", "application/x-protobuf", []byte{0x0a, 0xff}, false, http.StatusBadRequest},
+		{"unsupported", "text/plain", []byte("foo"), false, http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := startReceiver(t)
+			body := tc.body
+			if tc.gzip {
+				body = gzipBytes(t, body)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "http://"+r.httpAddress+httpMetricsPath, bytes.NewReader(body))
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close() // nolint
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status code should be %d but got %d", tc.status, resp.StatusCode)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			values, _ := r.Generate(context.Background())
+			if !reflect.DeepEqual(values, expectedValues) {
+				t.Errorf("expected %v but got %v", expectedValues, values)
+			}
+		})
+	}
+}
+
+func TestReceiverGRPC(t *testing.T) {
+	r := startReceiver(t)
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	tests := []struct {
+		path    string
+		message []byte
+		gzip    bool
+		status  string
+	}{
+		{grpcExportPath, exportRequestProto(), false, "0"},
+		{grpcExportPath, exportRequestProto(), true, "0"},
+		{grpcExportPath, []byte{0x0a, 0xff}, false, "3"},
+		{"/opentelemetry.proto.collector.trace.v1.TraceService/Export", nil, false, "12"},
+	}
+	for _, tc := range tests {
+		message, flag := tc.message, byte(0)
+		if tc.gzip {
+			message, flag = gzipBytes(t, message), 1
+		}
+		frame := binary.BigEndian.AppendUint32([]byte{flag}, uint32(len(message)))
+		req, _ := http.NewRequest(http.MethodPost, "http://"+r.grpcAddress+tc.path, bytes.NewReader(append(frame, message...)))
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("Te", "trailers")
+		if tc.gzip {
+			req.Header.Set("Grpc-Encoding", "gzip")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body) // nolint
+		resp.Body.Close()       // nolint
+		if resp.ProtoMajor != 2 {
+			t.Errorf("should respond in HTTP/2 but got %s", resp.Proto)
+		}
+		if got := resp.Trailer.Get("Grpc-Status"); got != tc.status {
+			t.Errorf("grpc-status should be %s but got %s (%s)", tc.status, got, resp.Trailer.Get("Grpc-Message"))
+		}
+		if tc.status != "0" {
+			continue
+		}
+		values, _ := r.Generate(context.Background())
+		if !reflect.DeepEqual(values, expectedValues) {
+			t.Errorf("expected %v but got %v", expectedValues, values)
+		}
+	}
+}
+
+func TestReceiverStartError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close() // nolint
+	r := NewReceiver(&config.OTLPReceiver{HTTPAddress: l.Addr().String()})
+	if err := r.Start(); err == nil || !strings.Contains(err.Error(), "failed to start OTLP receiver") {
+		t.Errorf("should raise error but got %v", err)
+	}
+}
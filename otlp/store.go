@@ -0,0 +1,183 @@
+package otlp
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+)
+
+const (
+	metricPrefix = "custom.otel."
+
+	// the series are removed when no data point is received for the duration
+	staleDuration = 5 * time.Minute
+)
+
+type series struct {
+	value   float64
+	delta   bool
+	updated time.Time
+}
+
+type graph struct {
+	displayName string
+	unit        string
+}
+
+// store keeps the latest values of the received data points. The values of
+// delta temporality are accumulated until they are collected.
+type store struct {
+	mu     sync.Mutex
+	series map[string]*series
+	graphs map[string]*graph
+	now    func() time.Time
+}
+
+func newStore() *store {
+	return &store{
+		series: make(map[string]*series),
+		graphs: make(map[string]*graph),
+		now:    time.Now,
+	}
+}
+
+func (s *store) add(req *exportMetricsServiceRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				s.addMetric(m)
+			}
+		}
+	}
+}
+
+func (s *store) addMetric(m *metricData) {
+	graphName := metricPrefix + metric.SanitizeMetricKey(m.Name)
+	switch {
+	case m.Gauge != nil:
+		for _, p := range m.Gauge.DataPoints {
+			if v, ok := p.value(); ok {
+				s.set(graphName, m.Name, convertUnit(m.Unit, p.AsInt != nil), p.Attributes, v, false)
+			}
+		}
+	case m.Sum != nil:
+		delta := m.Sum.AggregationTemporality == aggregationTemporalityDelta
+		for _, p := range m.Sum.DataPoints {
+			if v, ok := p.value(); ok {
+				s.set(graphName, m.Name, convertUnit(m.Unit, p.AsInt != nil), p.Attributes, v, delta)
+			}
+		}
+	case m.Histogram != nil:
+		delta := m.Histogram.AggregationTemporality == aggregationTemporalityDelta
+		unit := convertUnit(m.Unit, false)
+		for _, p := range m.Histogram.DataPoints {
+			s.set(graphName+"_count", m.Name+" count", "integer", p.Attributes, float64(p.Count), delta)
+			if p.Sum != nil {
+				s.set(graphName+"_sum", m.Name+" sum", unit, p.Attributes, *p.Sum, delta)
+			}
+			if p.Min != nil {
+				s.set(graphName+"_min", m.Name+" min", unit, p.Attributes, *p.Min, false)
+			}
+			if p.Max != nil {
+				s.set(graphName+"_max", m.Name+" max", unit, p.Attributes, *p.Max, false)
+			}
+		}
+	}
+}
+
+func (s *store) set(graphName, displayName, unit string, attrs []*keyValue, value float64, delta bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	name := graphName + "." + flattenAttributes(attrs)
+	ser, ok := s.series[name]
+	if !ok || ser.delta != delta {
+		ser = &series{delta: delta}
+		s.series[name] = ser
+	}
+	if delta {
+		ser.value += value
+	} else {
+		ser.value = value
+	}
+	ser.updated = s.now()
+	s.graphs[graphName] = &graph{displayName: displayName, unit: unit}
+}
+
+func (s *store) collect() metric.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	values := make(metric.Values, len(s.series))
+	for name, ser := range s.series {
+		if now.Sub(ser.updated) > staleDuration {
+			delete(s.series, name)
+			continue
+		}
+		values[name] = ser.value
+		if ser.delta {
+			ser.value = 0
+		}
+	}
+	return values
+}
+
+func (s *store) graphDefs() []*mackerel.GraphDefsParam {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	graphDefs := make([]*mackerel.GraphDefsParam, 0, len(s.graphs))
+	for name, g := range s.graphs {
+		graphDefs = append(graphDefs, &mackerel.GraphDefsParam{
+			Name:        name,
+			DisplayName: g.displayName,
+			Unit:        g.unit,
+			Metrics: []*mackerel.GraphDefsMetric{
+				{Name: name + ".*", DisplayName: "%1"},
+			},
+		})
+	}
+	sort.Slice(graphDefs, func(i, j int) bool {
+		return graphDefs[i].Name < graphDefs[j].Name
+	})
+	return graphDefs
+}
+
+// flattenAttributes converts the attributes to a metric name segment like key1-value1_key2-value2
+func flattenAttributes(attrs []*keyValue) string {
+	if len(attrs) == 0 {
+		return "default"
+	}
+	kvs := make([]string, len(attrs))
+	for i, kv := range attrs {
+		kvs[i] = kv.Key + "-" + kv.Value.String()
+	}
+	slices.Sort(kvs)
+	return metric.SanitizeMetricKey(strings.Join(kvs, "_"))
+}
+
+// convertUnit converts the UCUM unit of OpenTelemetry to the unit of Mackerel graph
+func convertUnit(unit string, isInt bool) string {
+	switch unit {
+	case "By":
+		return "bytes"
+	case "By/s":
+		return "bytes/sec"
+	case "bit/s":
+		return "bits/sec"
+	case "%":
+		return "percentage"
+	}
+	if isInt {
+		return "integer"
+	}
+	return "float"
+}
@@ -0,0 +1,86 @@
+package otlp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	s := newStore()
+	s.now = func() time.Time { return now }
+
+	var req exportMetricsServiceRequest
+	if err := json.Unmarshal([]byte(exportRequestJSON), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	// accumulate the delta values until collected
+	s.add(&req)
+	s.add(&req)
+	expected := metric.Values{
+		"custom.otel.process_memory_usage.default":               1024,
+		"custom.otel.http_server_requests.http_method-GET":       42,
+		"custom.otel.http_server_duration_count.http_method-GET": 20,
+		"custom.otel.http_server_duration_sum.http_method-GET":   247,
+		"custom.otel.http_server_duration_max.http_method-GET":   50,
+	}
+	if got := s.collect(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	expected["custom.otel.http_server_duration_count.http_method-GET"] = 0
+	expected["custom.otel.http_server_duration_sum.http_method-GET"] = 0
+	if got := s.collect(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	expectedGraphDefs := []*mackerel.GraphDefsParam{
+		{Name: "custom.otel.http_server_duration_count", DisplayName: "http.server.duration count", Unit: "integer",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.otel.http_server_duration_count.*", DisplayName: "%1"}}},
+		{Name: "custom.otel.http_server_duration_max", DisplayName: "http.server.duration max", Unit: "float",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.otel.http_server_duration_max.*", DisplayName: "%1"}}},
+		{Name: "custom.otel.http_server_duration_sum", DisplayName: "http.server.duration sum", Unit: "float",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.otel.http_server_duration_sum.*", DisplayName: "%1"}}},
+		{Name: "custom.otel.http_server_requests", DisplayName: "http.server.requests", Unit: "float",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.otel.http_server_requests.*", DisplayName: "%1"}}},
+		{Name: "custom.otel.process_memory_usage", DisplayName: "process.memory.usage", Unit: "bytes",
+			Metrics: []*mackerel.GraphDefsMetric{{Name: "custom.otel.process_memory_usage.*", DisplayName: "%1"}}},
+	}
+	if got := s.graphDefs(); !reflect.DeepEqual(got, expectedGraphDefs) {
+		t.Errorf("expected %v but got %v", expectedGraphDefs, got)
+	}
+
+	// remove the stale series
+	now = now.Add(staleDuration + time.Second)
+	if got := s.collect(); len(got) != 0 {
+		t.Errorf("stale series should be removed but got %v", got)
+	}
+}
+
+func TestFlattenAttributes(t *testing.T) {
+	str := func(s string) *string { return &s }
+	i := jsonInt64(200)
+	tests := []struct {
+		attrs  []*keyValue
+		expect string
+	}{
+		{nil, "default"},
+		{[]*keyValue{
+			{Key: "http.status_code", Value: anyValue{IntValue: &i}},
+			{Key: "http.method", Value: anyValue{StringValue: str("GET")}},
+		}, "http_method-GET_http_status_code-200"},
+		{[]*keyValue{{Key: "path", Value: anyValue{StringValue: str("/api/v1")}}}, "path-_api_v1"},
+	}
+	for _, tc := range tests {
+		if got := flattenAttributes(tc.attrs); got != tc.expect {
+			t.Errorf("flattenAttributes should be %q but got %q", tc.expect, got)
+		}
+	}
+}
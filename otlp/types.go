@@ -0,0 +1,138 @@
+package otlp
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// The types of OTLP metrics request. They are decoded from both protobuf and
+// JSON encoding, and only the fields used by the receiver are defined.
+
+type exportMetricsServiceRequest struct {
+	ResourceMetrics []*resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	ScopeMetrics []*scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Metrics []*metricData `json:"metrics"`
+}
+
+type metricData struct {
+	Name      string     `json:"name"`
+	Unit      string     `json:"unit"`
+	Gauge     *gauge     `json:"gauge"`
+	Sum       *sum       `json:"sum"`
+	Histogram *histogram `json:"histogram"`
+}
+
+const (
+	aggregationTemporalityDelta      = 1
+	aggregationTemporalityCumulative = 2
+)
+
+type gauge struct {
+	DataPoints []*numberDataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []*numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                `json:"aggregationTemporality"`
+	IsMonotonic            bool               `json:"isMonotonic"`
+}
+
+type histogram struct {
+	DataPoints             []*histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+}
+
+type numberDataPoint struct {
+	Attributes   []*keyValue `json:"attributes"`
+	TimeUnixNano jsonUint64  `json:"timeUnixNano"`
+	AsDouble     *float64    `json:"asDouble"`
+	AsInt        *jsonInt64  `json:"asInt"`
+}
+
+func (p *numberDataPoint) value() (float64, bool) {
+	switch {
+	case p.AsDouble != nil:
+		return *p.AsDouble, true
+	case p.AsInt != nil:
+		return float64(*p.AsInt), true
+	default:
+		return 0, false
+	}
+}
+
+type histogramDataPoint struct {
+	Attributes   []*keyValue `json:"attributes"`
+	TimeUnixNano jsonUint64  `json:"timeUnixNano"`
+	Count        jsonUint64  `json:"count"`
+	Sum          *float64    `json:"sum"`
+	Min          *float64    `json:"min"`
+	Max          *float64    `json:"max"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string    `json:"stringValue"`
+	BoolValue   *bool      `json:"boolValue"`
+	IntValue    *jsonInt64 `json:"intValue"`
+	DoubleValue *float64   `json:"doubleValue"`
+}
+
+func (v anyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return strconv.FormatInt(int64(*v.IntValue), 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// jsonUint64 decodes the 64-bit integer encoded as either a string or a number in JSON
+type jsonUint64 uint64
+
+func (n *jsonUint64) UnmarshalJSON(b []byte) error {
+	s, err := unquoteNumber(b)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	*n = jsonUint64(v)
+	return err
+}
+
+// jsonInt64 decodes the 64-bit integer encoded as either a string or a number in JSON
+type jsonInt64 int64
+
+func (n *jsonInt64) UnmarshalJSON(b []byte) error {
+	s, err := unquoteNumber(b)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	*n = jsonInt64(v)
+	return err
+}
+
+func unquoteNumber(b []byte) (string, error) {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		err := json.Unmarshal(b, &s)
+		return s, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,85 @@
+package cgroup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mackerelio/mackerel-container-agent/check"
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+// DefaultPath represents the default path of the cgroup v2 hierarchy of the agent
+const DefaultPath = "/sys/fs/cgroup"
+
+type cgroupPlatform struct {
+	path     string
+	name     string
+	hostname string
+	metadata *metadata
+}
+
+// NewCgroupPlatform creates a new Platform
+// on this platform, agent reads its own cgroup v2 files and monitors the container which the agent runs in.
+func NewCgroupPlatform(path, hostname string, lookupEnv func(string) (string, bool)) (platform.Platform, error) {
+	if _, err := os.Stat(filepath.Join(path, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not found on %s: %w", path, err)
+	}
+
+	meta := detectMetadata(lookupEnv)
+	name := meta.service
+	if name == "" {
+		name = hostname
+	}
+	if meta.instance != "" {
+		hostname = meta.instance
+	}
+
+	return &cgroupPlatform{
+		path:     path,
+		name:     name,
+		hostname: hostname,
+		metadata: meta,
+	}, nil
+}
+
+// GetMetricGenerators gets metric generators
+func (p *cgroupPlatform) GetMetricGenerators() []metric.Generator {
+	return []metric.Generator{
+		newMetricGenerator(p.path, p.name, hostinfo.NewGenerator()),
+		metric.NewInterfaceGenerator(),
+	}
+}
+
+// GetSpecGenerators gets spec generator
+func (p *cgroupPlatform) GetSpecGenerators() []spec.Generator {
+	return []spec.Generator{
+		newSpecGenerator(p.path, p.name, p.hostname, p.metadata),
+		&spec.CPUGenerator{},
+	}
+}
+
+// GetCheckGenerators gets check generators
+func (p *cgroupPlatform) GetCheckGenerators() []check.Generator {
+	return nil
+}
+
+// GetCustomIdentifier gets custom identifier
+func (p *cgroupPlatform) GetCustomIdentifier(context.Context) (string, error) {
+	return "", nil
+}
+
+// StatusRunning reports p status is running
+func (p *cgroupPlatform) StatusRunning(context.Context) bool {
+	return true
+}
+
+// StatusStopping reports p status is stopping
+// there is no way to know it from the cgroup files.
+func (p *cgroupPlatform) StatusStopping(context.Context) bool {
+	return false
+}
@@ -0,0 +1,81 @@
+package cgroup
+
+import (
+	"testing"
+)
+
+func lookupEnvFunc(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestNewCgroupPlatform(t *testing.T) {
+	dir := newCgroupfs(t, cgroupfsFiles)
+
+	tests := []struct {
+		env      map[string]string
+		name     string
+		hostname string
+		metadata *metadata
+	}{
+		{
+			env:      map[string]string{},
+			name:     "myhost",
+			hostname: "myhost",
+			metadata: &metadata{},
+		},
+		{
+			env: map[string]string{
+				"K_SERVICE":  "myapp",
+				"K_REVISION": "myapp-00001-abc",
+			},
+			name:     "myapp",
+			hostname: "myhost",
+			metadata: &metadata{runtime: "cloud_run", service: "myapp", revision: "myapp-00001-abc"},
+		},
+		{
+			env: map[string]string{
+				"CONTAINER_APP_NAME":         "myapp",
+				"CONTAINER_APP_REVISION":     "myapp--rev1",
+				"CONTAINER_APP_REPLICA_NAME": "myapp--rev1-5d9f8c7b6-abcde",
+			},
+			name:     "myapp",
+			hostname: "myapp--rev1-5d9f8c7b6-abcde",
+			metadata: &metadata{runtime: "container_apps", service: "myapp", revision: "myapp--rev1", instance: "myapp--rev1-5d9f8c7b6-abcde"},
+		},
+		{
+			env: map[string]string{
+				"NOMAD_ALLOC_ID":  "5456bd7a-9fc0-c0dd-6131-cbee77f57577",
+				"NOMAD_TASK_NAME": "web",
+				"NOMAD_JOB_NAME":  "myapp",
+				"NOMAD_REGION":    "global",
+			},
+			name:     "web",
+			hostname: "5456bd7a-9fc0-c0dd-6131-cbee77f57577",
+			metadata: &metadata{runtime: "nomad", service: "web", revision: "myapp", instance: "5456bd7a-9fc0-c0dd-6131-cbee77f57577", region: "global"},
+		},
+	}
+
+	for _, tc := range tests {
+		pform, err := NewCgroupPlatform(dir, "myhost", lookupEnvFunc(tc.env))
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		p := pform.(*cgroupPlatform)
+		if p.name != tc.name {
+			t.Errorf("name should be %q but got %q", tc.name, p.name)
+		}
+		if p.hostname != tc.hostname {
+			t.Errorf("hostname should be %q but got %q", tc.hostname, p.hostname)
+		}
+		if *p.metadata != *tc.metadata {
+			t.Errorf("metadata should be %+v but got %+v", tc.metadata, p.metadata)
+		}
+	}
+
+	if _, err := NewCgroupPlatform(t.TempDir(), "myhost", lookupEnvFunc(nil)); err == nil {
+		t.Errorf("should raise error when cgroup v2 is not found")
+	}
+}
@@ -0,0 +1,116 @@
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readUint reads a file which has a single unsigned integer value
+func readUint(dir, name string) (uint64, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(bytes.TrimSpace(raw)), 10, 64)
+}
+
+// readMax reads a file which has a single unsigned integer value or "max".
+// It returns zero when the value is "max".
+func readMax(dir, name string) (uint64, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	s := string(bytes.TrimSpace(raw))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// readKeyValues reads a flat keyed file such as cpu.stat and memory.stat
+func readKeyValues(dir, name string) (map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		xs := strings.Fields(scanner.Text())
+		if len(xs) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(xs[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[xs[0]] = v
+	}
+	return values, scanner.Err()
+}
+
+// readCPUMax reads cpu.max and returns the quota and the period.
+// The quota is zero when the value is "max".
+func readCPUMax(dir string) (quota, period uint64, err error) {
+	raw, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	if err != nil {
+		return 0, 0, err
+	}
+	xs := strings.Fields(string(raw))
+	if len(xs) != 2 {
+		return 0, 0, fmt.Errorf("invalid format of cpu.max: %q", raw)
+	}
+	if period, err = strconv.ParseUint(xs[1], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if xs[0] == "max" {
+		return 0, period, nil
+	}
+	if quota, err = strconv.ParseUint(xs[0], 10, 64); err != nil {
+		return 0, 0, err
+	}
+	return quota, period, nil
+}
+
+// readIOStat reads io.stat and returns the bytes read and written summed up over the devices
+func readIOStat(dir string) (rbytes, wbytes uint64, err error) {
+	f, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close() // nolint
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+		xs := strings.Fields(scanner.Text())
+		if len(xs) < 2 {
+			continue
+		}
+		for _, kv := range xs[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "rbytes":
+				rbytes += n
+			case "wbytes":
+				wbytes += n
+			}
+		}
+	}
+	return rbytes, wbytes, scanner.Err()
+}
@@ -0,0 +1,53 @@
+package cgroup
+
+// Runtime types detected from the environment variables
+const (
+	runtimeCloudRun      = "cloud_run"
+	runtimeContainerApps = "container_apps"
+	runtimeNomad         = "nomad"
+)
+
+type metadata struct {
+	runtime  string
+	service  string
+	revision string
+	instance string
+	region   string
+}
+
+// detectMetadata reads the metadata of the container runtime from the environment variables.
+// It returns an empty metadata when the runtime is unknown.
+func detectMetadata(lookupEnv func(string) (string, bool)) *metadata {
+	getenv := func(name string) string {
+		v, _ := lookupEnv(name)
+		return v
+	}
+	switch {
+	// https://cloud.google.com/run/docs/container-contract#env-vars
+	case getenv("K_SERVICE") != "":
+		return &metadata{
+			runtime:  runtimeCloudRun,
+			service:  getenv("K_SERVICE"),
+			revision: getenv("K_REVISION"),
+		}
+	// https://learn.microsoft.com/azure/container-apps/environment-variables
+	case getenv("CONTAINER_APP_NAME") != "":
+		return &metadata{
+			runtime:  runtimeContainerApps,
+			service:  getenv("CONTAINER_APP_NAME"),
+			revision: getenv("CONTAINER_APP_REVISION"),
+			instance: getenv("CONTAINER_APP_REPLICA_NAME"),
+		}
+	// https://developer.hashicorp.com/nomad/docs/runtime/environment
+	case getenv("NOMAD_ALLOC_ID") != "":
+		return &metadata{
+			runtime:  runtimeNomad,
+			service:  getenv("NOMAD_TASK_NAME"),
+			revision: getenv("NOMAD_JOB_NAME"),
+			instance: getenv("NOMAD_ALLOC_ID"),
+			region:   getenv("NOMAD_REGION"),
+		}
+	default:
+		return &metadata{}
+	}
+}
@@ -0,0 +1,201 @@
+package cgroup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+)
+
+const customPrefix = "custom.container."
+
+type stats struct {
+	cpuUsageUsec  uint64
+	memoryCurrent uint64
+	inactiveFile  uint64
+	ioReadBytes   uint64
+	ioWriteBytes  uint64
+	pidsCurrent   uint64
+	hasIO         bool
+	hasPids       bool
+}
+
+type metricGenerator struct {
+	path              string
+	name              string
+	hostInfoGenerator hostinfo.Generator
+	hostMemTotal      *float64
+	hostNumCores      *float64
+	prevStats         *stats
+	prevTime          time.Time
+}
+
+func newMetricGenerator(path, name string, hostinfoGenerator hostinfo.Generator) *metricGenerator {
+	return &metricGenerator{
+		path:              path,
+		name:              name,
+		hostInfoGenerator: hostinfoGenerator,
+	}
+}
+
+// Generate generates metric values
+func (g *metricGenerator) Generate(context.Context) (metric.Values, error) {
+	s, err := g.readStats()
+	if err != nil {
+		return nil, err
+	}
+
+	if g.hostMemTotal == nil || g.hostNumCores == nil {
+		memTotal, cpuCores, err := g.hostInfoGenerator.Generate()
+		if err != nil {
+			return nil, err
+		}
+		if g.hostMemTotal == nil {
+			g.hostMemTotal = &memTotal
+		}
+		if g.hostNumCores == nil {
+			g.hostNumCores = &cpuCores
+		}
+	}
+
+	now := time.Now()
+	if g.prevStats == nil || g.prevTime.Before(now.Add(-10*time.Minute)) {
+		g.prevStats = s
+		g.prevTime = now
+		return nil, nil
+	}
+
+	timeDelta := now.Sub(g.prevTime)
+	prev := g.prevStats
+	name := metric.SanitizeMetricKey(g.name)
+	metricValues := make(metric.Values)
+
+	if v, ok := calculateCPUMetrics(prev, s, timeDelta); ok {
+		metricValues["container.cpu."+name+".usage"] = v
+	}
+	metricValues["container.cpu."+name+".limit"] = g.getCPULimit()
+	if v, ok := calculateMemoryUsage(s); ok {
+		metricValues["container.memory."+name+".usage"] = v
+	}
+	metricValues["container.memory."+name+".limit"] = g.getMemoryLimit()
+
+	if s.hasIO && prev.hasIO {
+		if v, ok := calculateRate(prev.ioReadBytes, s.ioReadBytes, timeDelta); ok {
+			metricValues[customPrefix+"io."+name+".read"] = v
+		}
+		if v, ok := calculateRate(prev.ioWriteBytes, s.ioWriteBytes, timeDelta); ok {
+			metricValues[customPrefix+"io."+name+".write"] = v
+		}
+	}
+	if s.hasPids {
+		metricValues[customPrefix+"pids."+name+".current"] = float64(s.pidsCurrent)
+	}
+
+	g.prevStats = s
+	g.prevTime = now
+
+	return metricValues, nil
+}
+
+// GetGraphDefs gets graph definitions
+func (g *metricGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return []*mackerel.GraphDefsParam{
+		{
+			Name:        customPrefix + "io.#",
+			DisplayName: "Container I/O",
+			Unit:        "bytes/sec",
+			Metrics: []*mackerel.GraphDefsMetric{
+				{Name: customPrefix + "io.#.read", DisplayName: "read"},
+				{Name: customPrefix + "io.#.write", DisplayName: "write"},
+			},
+		},
+		{
+			Name:        customPrefix + "pids.#",
+			DisplayName: "Container PIDs",
+			Unit:        "integer",
+			Metrics: []*mackerel.GraphDefsMetric{
+				{Name: customPrefix + "pids.#.current", DisplayName: "current"},
+			},
+		},
+	}, nil
+}
+
+func (g *metricGenerator) readStats() (*stats, error) {
+	cpuStat, err := readKeyValues(g.path, "cpu.stat")
+	if err != nil {
+		return nil, err
+	}
+	memoryCurrent, err := readUint(g.path, "memory.current")
+	if err != nil {
+		return nil, err
+	}
+	s := &stats{
+		cpuUsageUsec:  cpuStat["usage_usec"],
+		memoryCurrent: memoryCurrent,
+	}
+
+	// the following files are missing when the controllers are not enabled
+	if memoryStat, err := readKeyValues(g.path, "memory.stat"); err == nil {
+		s.inactiveFile = memoryStat["inactive_file"]
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if s.ioReadBytes, s.ioWriteBytes, err = readIOStat(g.path); err == nil {
+		s.hasIO = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if s.pidsCurrent, err = readUint(g.path, "pids.current"); err == nil {
+		s.hasPids = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// calculateCPUMetrics reports false when the counter is reset
+func calculateCPUMetrics(prev, curr *stats, delta time.Duration) (float64, bool) {
+	if curr.cpuUsageUsec < prev.cpuUsageUsec {
+		return 0.0, false
+	}
+	return float64((curr.cpuUsageUsec-prev.cpuUsageUsec)*1000) / float64(delta.Nanoseconds()) * 100, true
+}
+
+// calculateMemoryUsage returns the working set, which excludes the inactive page cache.
+// It reports false when the inactive page cache exceeds the usage.
+func calculateMemoryUsage(s *stats) (float64, bool) {
+	if s.inactiveFile > s.memoryCurrent {
+		return 0.0, false
+	}
+	return float64(s.memoryCurrent - s.inactiveFile), true
+}
+
+// calculateRate reports false when the counter is reset
+func calculateRate(prev, curr uint64, delta time.Duration) (float64, bool) {
+	if curr < prev {
+		return 0.0, false
+	}
+	return float64(curr-prev) / delta.Seconds(), true
+}
+
+func (g *metricGenerator) getCPULimit() float64 {
+	quota, period, err := readCPUMax(g.path)
+	if err == nil && quota > 0 && period > 0 {
+		return float64(quota) / float64(period) * 100
+	}
+	return *g.hostNumCores * 100
+}
+
+func (g *metricGenerator) getMemoryLimit() float64 {
+	limit, err := readMax(g.path, "memory.max")
+	if err == nil && limit > 0 {
+		return float64(limit)
+	}
+	return *g.hostMemTotal
+}
@@ -0,0 +1,174 @@
+package cgroup
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+)
+
+func newCgroupfs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeCgroupfs(t, dir, files)
+	return dir
+}
+
+func writeCgroupfs(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var cgroupfsFiles = map[string]string{
+	"cgroup.controllers": "cpuset cpu io memory hugetlb pids rdma misc\n",
+	"cpu.stat":           "usage_usec 16094570\nuser_usec 12345678\nsystem_usec 3748892\nnr_periods 0\nnr_throttled 0\nthrottled_usec 0\n",
+	"cpu.max":            "50000 100000\n",
+	"memory.current":     "12582912\n",
+	"memory.max":         "268435456\n",
+	"memory.stat":        "anon 8388608\nfile 4194304\nactive_file 2097152\ninactive_file 2097152\n",
+	"io.stat":            "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n253:0 rbytes=4096 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n",
+	"pids.current":       "7\n",
+	"pids.max":           "max\n",
+}
+
+func TestGenerateMetric(t *testing.T) {
+	ctx := context.Background()
+	dir := newCgroupfs(t, cgroupfsFiles)
+	generator := newMetricGenerator(dir, "myapp", hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	got, err := generator.Generate(ctx) // Store metrics to generator.prevStats.
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Generate() should return nil at first but got %v", got)
+	}
+
+	got, err = generator.Generate(ctx)
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	expected := metric.Values{
+		"container.cpu.myapp.usage":           0.0, // Result is 0 because use the same data.
+		"container.cpu.myapp.limit":           50.0,
+		"container.memory.myapp.usage":        1.048576e+07,
+		"container.memory.myapp.limit":        268435456.0, // 256MiB
+		"custom.container.io.myapp.read":      0.0,
+		"custom.container.io.myapp.write":     0.0,
+		"custom.container.pids.myapp.current": 7.0,
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Generate() expected %v, got %v", expected, got)
+	}
+}
+
+func TestGenerateMetric_Delta(t *testing.T) {
+	ctx := context.Background()
+	dir := newCgroupfs(t, cgroupfsFiles)
+	generator := newMetricGenerator(dir, "myapp", hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	if _, err := generator.Generate(ctx); err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	generator.prevTime = generator.prevTime.Add(-time.Minute)
+	writeCgroupfs(t, dir, map[string]string{
+		"cpu.stat": "usage_usec 46094570\n",                                             // +30s
+		"io.stat":  "8:0 rbytes=7603200 wbytes=314773504\n253:0 rbytes=4096 wbytes=0\n", // +6000KiB
+	})
+
+	got, err := generator.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	tests := []struct {
+		key    string
+		expect float64
+	}{
+		{"container.cpu.myapp.usage", 50.0},
+		{"custom.container.io.myapp.read", 6144000.0 / 60},
+		{"custom.container.io.myapp.write", 0.0},
+	}
+	for _, tc := range tests {
+		if v := got[tc.key]; math.Abs(v-tc.expect) > tc.expect*0.01 {
+			t.Errorf("%s should be about %f but got %f", tc.key, tc.expect, v)
+		}
+	}
+}
+
+func TestGenerateMetric_Reset(t *testing.T) {
+	ctx := context.Background()
+	dir := newCgroupfs(t, cgroupfsFiles)
+	generator := newMetricGenerator(dir, "myapp", hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	if _, err := generator.Generate(ctx); err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	writeCgroupfs(t, dir, map[string]string{
+		"cpu.stat":       "usage_usec 100\n",
+		"memory.current": "1048576\n",
+		"io.stat":        "8:0 rbytes=4096 wbytes=314773504\n",
+	})
+
+	got, err := generator.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	for _, key := range []string{
+		"container.cpu.myapp.usage",
+		"container.memory.myapp.usage",
+		"custom.container.io.myapp.read",
+	} {
+		if v, ok := got[key]; ok {
+			t.Errorf("%s should be skipped but got %f", key, v)
+		}
+	}
+	if _, ok := got["custom.container.io.myapp.write"]; !ok {
+		t.Errorf("custom.container.io.myapp.write should not be skipped")
+	}
+}
+
+func TestGenerateMetric_Unlimited(t *testing.T) {
+	ctx := context.Background()
+	dir := newCgroupfs(t, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"cpu.stat":           "usage_usec 16094570\n",
+		"cpu.max":            "max 100000\n",
+		"memory.current":     "12582912\n",
+		"memory.max":         "max\n",
+	})
+	generator := newMetricGenerator(dir, "myapp", hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	if _, err := generator.Generate(ctx); err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	got, err := generator.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	expected := metric.Values{
+		"container.cpu.myapp.usage":    0.0,
+		"container.cpu.myapp.limit":    400.0,        // mockNumCores * 100
+		"container.memory.myapp.usage": 12582912.0,   // no memory.stat
+		"container.memory.myapp.limit": 8201183232.0, // mockMemTotal
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Generate() expected %v, got %v", expected, got)
+	}
+}
+
+func TestGenerateMetric_NotFound(t *testing.T) {
+	generator := newMetricGenerator(t.TempDir(), "myapp", hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+	if _, err := generator.Generate(context.Background()); err == nil {
+		t.Errorf("Generate() should raise error")
+	}
+}
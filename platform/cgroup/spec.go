@@ -0,0 +1,56 @@
+package cgroup
+
+import (
+	"context"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+type specGenerator struct {
+	path     string
+	name     string
+	hostname string
+	metadata *metadata
+}
+
+func newSpecGenerator(path, name, hostname string, metadata *metadata) *specGenerator {
+	return &specGenerator{
+		path:     path,
+		name:     name,
+		hostname: hostname,
+		metadata: metadata,
+	}
+}
+
+func (g *specGenerator) Generate(context.Context) (any, error) {
+	spec := &containerSpec{
+		Name:     g.name,
+		Runtime:  g.metadata.runtime,
+		Service:  g.metadata.service,
+		Revision: g.metadata.revision,
+		Instance: g.metadata.instance,
+		Region:   g.metadata.region,
+	}
+
+	if quota, period, err := readCPUMax(g.path); err == nil && quota > 0 && period > 0 {
+		cpu := float64(quota) / float64(period)
+		spec.Limits.CPU = &cpu
+	}
+	if memory, err := readMax(g.path, "memory.max"); err == nil && memory > 0 {
+		spec.Limits.Memory = &memory
+	}
+	if pids, err := readMax(g.path, "pids.max"); err == nil && pids > 0 {
+		spec.Limits.Pids = &pids
+	}
+
+	return &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: string(platform.Cgroup),
+			MetaData: spec,
+		},
+		Hostname: g.hostname,
+	}, nil
+}
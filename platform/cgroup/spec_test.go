@@ -0,0 +1,45 @@
+package cgroup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+func TestGenerateSpec(t *testing.T) {
+	dir := newCgroupfs(t, cgroupfsFiles)
+	meta := &metadata{
+		runtime:  "cloud_run",
+		service:  "myapp",
+		revision: "myapp-00001-abc",
+	}
+	generator := newSpecGenerator(dir, "myapp", "localhost", meta)
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+
+	cpu := 0.5
+	memory := uint64(268435456)
+	expected := &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: "cgroup",
+			MetaData: &containerSpec{
+				Name:     "myapp",
+				Runtime:  "cloud_run",
+				Service:  "myapp",
+				Revision: "myapp-00001-abc",
+				Limits:   limitSpec{CPU: &cpu, Memory: &memory},
+			},
+		},
+		Hostname: "localhost",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Generate() expected %#v, got %#v", expected, got)
+	}
+}
@@ -0,0 +1,17 @@
+package cgroup
+
+type containerSpec struct {
+	Name     string    `json:"name,omitempty"`
+	Runtime  string    `json:"runtime,omitempty"`
+	Service  string    `json:"service,omitempty"`
+	Revision string    `json:"revision,omitempty"`
+	Instance string    `json:"instance,omitempty"`
+	Region   string    `json:"region,omitempty"`
+	Limits   limitSpec `json:"limits"`
+}
+
+type limitSpec struct {
+	CPU    *float64 `json:"cpu,omitempty"`
+	Memory *uint64  `json:"memory,omitempty"`
+	Pids   *uint64  `json:"pids,omitempty"`
+}
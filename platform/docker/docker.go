@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/mackerelio/golib/logging"
+
+	"github.com/mackerelio/mackerel-container-agent/check"
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/docker/engine"
+	"github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+const composeProjectLabel = "com.docker.compose.project"
+
+var logger = logging.GetLogger("docker")
+
+type dockerPlatform struct {
+	client      engine.Client
+	containerID string
+	labels      []string
+	hostname    string
+}
+
+// NewDockerPlatform creates a new Platform
+// on this platform, agent monitors the containers which have all the labels.
+// When labels are not specified, the containers in the same compose project as the agent are monitored.
+func NewDockerPlatform(ctx context.Context, socketPath, containerID string, labels []string, ignoreContainer *regexp.Regexp) (platform.Platform, error) {
+	c, err := engine.NewClient(socketPath, ignoreContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	self, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname := engine.ContainerName(self)
+	if self.Config != nil {
+		if project := self.Config.Labels[composeProjectLabel]; project != "" {
+			hostname = project
+			if len(labels) == 0 {
+				labels = []string{composeProjectLabel + "=" + project}
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("the agent container does not belong to any compose project. please specify labels of the containers to monitor")
+	}
+
+	return &dockerPlatform{
+		client:      c,
+		containerID: containerID,
+		labels:      labels,
+		hostname:    hostname,
+	}, nil
+}
+
+// GetMetricGenerators gets metric generators
+func (p *dockerPlatform) GetMetricGenerators() []metric.Generator {
+	return []metric.Generator{
+		newMetricGenerator(p.client, p.labels, hostinfo.NewGenerator()),
+	}
+}
+
+// GetSpecGenerators gets spec generator
+func (p *dockerPlatform) GetSpecGenerators() []spec.Generator {
+	return []spec.Generator{
+		newSpecGenerator(p.client, p.labels, p.hostname),
+		&spec.CPUGenerator{},
+	}
+}
+
+// GetCheckGenerators gets check generators
+func (p *dockerPlatform) GetCheckGenerators() []check.Generator {
+	return nil
+}
+
+// GetCustomIdentifier gets custom identifier
+func (p *dockerPlatform) GetCustomIdentifier(context.Context) (string, error) {
+	return "", nil
+}
+
+// StatusRunning reports p status is running
+func (p *dockerPlatform) StatusRunning(ctx context.Context) bool {
+	container, err := p.client.GetContainer(ctx, p.containerID)
+	if err != nil {
+		logger.Warningf("failed to get container: %s", err)
+		return false
+	}
+	return container.State != nil && container.State.Running
+}
+
+// StatusStopping reports p status is stopping
+// Docker Engine API does not tell whether the container is being stopped.
+func (p *dockerPlatform) StatusStopping(context.Context) bool {
+	return false
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+
+	"github.com/mackerelio/mackerel-container-agent/platform/docker/engine"
+)
+
+func TestStatusRunning(t *testing.T) {
+	mockClient := engine.NewMockClient()
+	pform := dockerPlatform{client: mockClient, containerID: "agent"}
+
+	tests := []struct {
+		state  *dockerTypes.State
+		expect bool
+	}{
+		{&dockerTypes.State{Status: "running", Running: true}, true},
+		{&dockerTypes.State{Status: "created"}, false},
+		{&dockerTypes.State{Status: "restarting", Restarting: true}, false},
+		{nil, false},
+	}
+
+	for _, tc := range tests {
+		mockClient.ApplyOption(
+			engine.MockGetContainer(
+				func(context.Context, string) (*dockerTypes.InspectResponse, error) {
+					return &dockerTypes.InspectResponse{
+						ContainerJSONBase: &dockerTypes.ContainerJSONBase{State: tc.state},
+					}, nil
+				},
+			),
+		)
+
+		got := pform.StatusRunning(context.Background())
+		if got != tc.expect {
+			t.Errorf("StatusRunning() expected %t, got %t", tc.expect, got)
+		}
+	}
+}
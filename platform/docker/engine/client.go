@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+)
+
+// Client interface gets containers and their stats from Docker Engine API
+type Client interface {
+	GetContainer(context.Context, string) (*dockerTypes.InspectResponse, error)
+	ListContainers(context.Context, []string) ([]*dockerTypes.InspectResponse, error)
+	GetContainerStats(context.Context, string) (*dockerTypes.StatsResponse, error)
+}
+
+const (
+	// DefaultSocketPath represents the default path of Docker Engine API socket
+	DefaultSocketPath = "/var/run/docker.sock"
+
+	containersPath = "/containers"
+)
+
+var timeout = 3 * time.Second
+
+type client struct {
+	url             *url.URL
+	httpClient      *http.Client
+	ignoreContainer *regexp.Regexp
+}
+
+// NewClient creates a new Client which connects to the unix socket
+func NewClient(socketPath string, ignoreContainer *regexp.Regexp) (Client, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("socket path should not be empty")
+	}
+	dialer := &net.Dialer{}
+	return &client{
+		url: &url.URL{Scheme: "http", Host: "docker"},
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy: nil,
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+		ignoreContainer: ignoreContainer,
+	}, nil
+}
+
+// GetContainer gets the container
+func (c *client) GetContainer(ctx context.Context, id string) (*dockerTypes.InspectResponse, error) {
+	req, err := c.newRequest(path.Join(containersPath, id, "json"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var container dockerTypes.InspectResponse
+	if err = decodeBody(resp, &container); err != nil {
+		return nil, err
+	}
+	if container.ContainerJSONBase == nil {
+		return nil, fmt.Errorf("container %s not found", id)
+	}
+	return &container, nil
+}
+
+// ListContainers gets the running containers which have all the labels
+func (c *client) ListContainers(ctx context.Context, labels []string) ([]*dockerTypes.InspectResponse, error) {
+	filters, err := json.Marshal(map[string][]string{"label": labels})
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(path.Join(containersPath, "json"), url.Values{"filters": {string(filters)}})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var summaries []dockerTypes.Summary
+	if err = decodeBody(resp, &summaries); err != nil {
+		return nil, err
+	}
+
+	containers := make([]*dockerTypes.InspectResponse, 0, len(summaries))
+	for _, s := range summaries {
+		if c.ignoreContainer != nil && c.ignoreContainer.MatchString(containerName(s.Names)) {
+			continue
+		}
+		container, err := c.GetContainer(ctx, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// GetContainerStats gets the container stats
+func (c *client) GetContainerStats(ctx context.Context, id string) (*dockerTypes.StatsResponse, error) {
+	req, err := c.newRequest(path.Join(containersPath, id, "stats"), url.Values{
+		"stream":   {"false"},
+		"one-shot": {"true"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var stats dockerTypes.StatsResponse
+	if err = decodeBody(resp, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ContainerName returns the container name without the leading slash
+func ContainerName(container *dockerTypes.InspectResponse) string {
+	return strings.TrimPrefix(container.Name, "/")
+}
+
+func containerName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(names[0], "/")
+}
+
+func (c *client) newRequest(endpoint string, query url.Values) (*http.Request, error) {
+	u := *c.url
+	u.Path = endpoint
+	u.RawQuery = query.Encode()
+	return http.NewRequest("GET", u.String(), nil)
+}
+
+func decodeBody(resp *http.Response, out any) error {
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, resp.Request.URL, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+const (
+	webContainerID   = "3f1a0c1e3d2b4c5a6f7e8d9c0b1a2f3e4d5c6b7a8f9e0d1c2b3a4f5e6d7c8b9a"
+	agentContainerID = "9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c"
+)
+
+func newServer(t *testing.T) string {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			var filters map[string][]string
+			if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if !reflect.DeepEqual(filters["label"], []string{"com.docker.compose.project=myapp"}) {
+				w.Write([]byte("[]")) // nolint
+				return
+			}
+			http.ServeFile(w, r, "testdata/containers.json")
+		case "/containers/" + webContainerID + "/json", "/containers/myapp-web-1/json":
+			http.ServeFile(w, r, "testdata/container_web.json")
+		case "/containers/" + agentContainerID + "/json":
+			http.ServeFile(w, r, "testdata/container_agent.json")
+		case "/containers/" + webContainerID + "/stats":
+			if r.URL.Query().Get("stream") != "false" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			http.ServeFile(w, r, "testdata/stats_web.json")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"No such container"}`)) // nolint
+		}
+	}))
+	ts.Listener.Close() // nolint
+	ts.Listener = l
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return socketPath
+}
+
+func TestGetContainer(t *testing.T) {
+	socketPath := newServer(t)
+	c, err := NewClient(socketPath, nil)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	tests := []struct {
+		id         string
+		name       string
+		raiseError bool
+	}{
+		{webContainerID, "myapp-web-1", false},
+		{"myapp-web-1", "myapp-web-1", false},
+		{agentContainerID, "myapp-mackerel-container-agent-1", false},
+		{"unknown", "", true},
+	}
+
+	for _, tc := range tests {
+		container, err := c.GetContainer(context.Background(), tc.id)
+		if err != nil {
+			if !tc.raiseError {
+				t.Errorf("should not raise error: %v", err)
+			}
+			continue
+		}
+		if tc.raiseError {
+			t.Errorf("should raise error")
+			continue
+		}
+		if got := ContainerName(container); got != tc.name {
+			t.Errorf("container name should be %q but got %q", tc.name, got)
+		}
+	}
+}
+
+func TestListContainers(t *testing.T) {
+	socketPath := newServer(t)
+
+	tests := []struct {
+		labels          []string
+		ignoreContainer *regexp.Regexp
+		expect          []string
+	}{
+		{
+			labels: []string{"com.docker.compose.project=myapp"},
+			expect: []string{"myapp-web-1", "myapp-mackerel-container-agent-1"},
+		},
+		{
+			labels:          []string{"com.docker.compose.project=myapp"},
+			ignoreContainer: regexp.MustCompile(`\Amyapp-mackerel-container-agent-`),
+			expect:          []string{"myapp-web-1"},
+		},
+		{
+			labels: []string{"com.docker.compose.project=unknown"},
+			expect: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		c, err := NewClient(socketPath, tc.ignoreContainer)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		containers, err := c.ListContainers(context.Background(), tc.labels)
+		if err != nil {
+			t.Errorf("should not raise error: %v", err)
+		}
+		names := make([]string, len(containers))
+		for i, c := range containers {
+			names[i] = ContainerName(c)
+		}
+		if !reflect.DeepEqual(names, tc.expect) {
+			t.Errorf("containers should be %v but got %v", tc.expect, names)
+		}
+	}
+}
+
+func TestGetContainerStats(t *testing.T) {
+	socketPath := newServer(t)
+	c, err := NewClient(socketPath, nil)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	stats, err := c.GetContainerStats(context.Background(), webContainerID)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if stats.CPUStats.CPUUsage.TotalUsage != 16094570000 {
+		t.Errorf("unexpected cpu usage: %d", stats.CPUStats.CPUUsage.TotalUsage)
+	}
+	if stats.MemoryStats.Usage != 12582912 {
+		t.Errorf("unexpected memory usage: %d", stats.MemoryStats.Usage)
+	}
+
+	if _, err := c.GetContainerStats(context.Background(), "unknown"); err == nil {
+		t.Errorf("should raise error")
+	}
+}
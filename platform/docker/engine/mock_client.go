@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+)
+
+// MockClient represents a mock client of Docker Engine API
+type MockClient struct {
+	getContainerCallback      func(context.Context, string) (*dockerTypes.InspectResponse, error)
+	listContainersCallback    func(context.Context, []string) ([]*dockerTypes.InspectResponse, error)
+	getContainerStatsCallback func(context.Context, string) (*dockerTypes.StatsResponse, error)
+}
+
+// MockClientOption represents an option of mock client of Docker Engine API
+type MockClientOption func(*MockClient)
+
+// NewMockClient creates a new mock client of Docker Engine API
+func NewMockClient(opts ...MockClientOption) *MockClient {
+	c := &MockClient{}
+	for _, o := range opts {
+		c.ApplyOption(o)
+	}
+	return c
+}
+
+// ApplyOption applies a mock client option
+func (c *MockClient) ApplyOption(opt MockClientOption) {
+	opt(c)
+}
+
+type errCallbackNotFound string
+
+func (err errCallbackNotFound) Error() string {
+	return string(err) + " callback not found"
+}
+
+// GetContainer ...
+func (c *MockClient) GetContainer(ctx context.Context, id string) (*dockerTypes.InspectResponse, error) {
+	if c.getContainerCallback != nil {
+		return c.getContainerCallback(ctx, id)
+	}
+	return nil, errCallbackNotFound("GetContainer")
+}
+
+// MockGetContainer returns an option to set the callback of GetContainer
+func MockGetContainer(callback func(context.Context, string) (*dockerTypes.InspectResponse, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getContainerCallback = callback
+	}
+}
+
+// ListContainers ...
+func (c *MockClient) ListContainers(ctx context.Context, labels []string) ([]*dockerTypes.InspectResponse, error) {
+	if c.listContainersCallback != nil {
+		return c.listContainersCallback(ctx, labels)
+	}
+	return nil, errCallbackNotFound("ListContainers")
+}
+
+// MockListContainers returns an option to set the callback of ListContainers
+func MockListContainers(callback func(context.Context, []string) ([]*dockerTypes.InspectResponse, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.listContainersCallback = callback
+	}
+}
+
+// GetContainerStats ...
+func (c *MockClient) GetContainerStats(ctx context.Context, id string) (*dockerTypes.StatsResponse, error) {
+	if c.getContainerStatsCallback != nil {
+		return c.getContainerStatsCallback(ctx, id)
+	}
+	return nil, errCallbackNotFound("GetContainerStats")
+}
+
+// MockGetContainerStats returns an option to set the callback of GetContainerStats
+func MockGetContainerStats(callback func(context.Context, string) (*dockerTypes.StatsResponse, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getContainerStatsCallback = callback
+	}
+}
@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/docker/engine"
+	"github.com/mackerelio/mackerel-container-agent/platform/internal/dockerstats"
+)
+
+type metricGenerator struct {
+	client            engine.Client
+	labels            []string
+	hostInfoGenerator hostinfo.Generator
+	hostMemTotal      *float64
+	hostNumCores      *float64
+	prevStats         map[string]*dockerTypes.StatsResponse
+	prevTime          time.Time
+}
+
+func newMetricGenerator(client engine.Client, labels []string, hostinfoGenerator hostinfo.Generator) *metricGenerator {
+	return &metricGenerator{
+		client:            client,
+		labels:            labels,
+		hostInfoGenerator: hostinfoGenerator,
+	}
+}
+
+// Generate generates metric values
+func (g *metricGenerator) Generate(ctx context.Context) (metric.Values, error) {
+	containers, err := g.client.ListContainers(ctx, g.labels)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*dockerTypes.StatsResponse, len(containers))
+	for _, c := range containers {
+		s, err := g.client.GetContainerStats(ctx, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		stats[c.ID] = s
+	}
+
+	if g.hostMemTotal == nil || g.hostNumCores == nil {
+		memTotal, cpuCores, err := g.hostInfoGenerator.Generate()
+		if err != nil {
+			return nil, err
+		}
+		if g.hostMemTotal == nil {
+			g.hostMemTotal = &memTotal
+		}
+		if g.hostNumCores == nil {
+			g.hostNumCores = &cpuCores
+		}
+	}
+
+	now := time.Now()
+	if g.prevStats == nil || g.prevTime.Before(now.Add(-10*time.Minute)) {
+		g.prevStats = stats
+		g.prevTime = now
+		return nil, nil
+	}
+
+	timeDelta := now.Sub(g.prevTime)
+	metricValues := make(metric.Values)
+	for _, c := range containers {
+		prev, ok := g.prevStats[c.ID]
+		if !ok {
+			continue
+		}
+		curr := stats[c.ID]
+
+		name := metric.SanitizeMetricKey(engine.ContainerName(c))
+		if v, ok := dockerstats.CPUUsage(prev, curr, timeDelta); ok {
+			metricValues["container.cpu."+name+".usage"] = v
+		}
+		metricValues["container.cpu."+name+".limit"] = g.getCPULimit(c)
+		if v, ok := dockerstats.MemoryUsage(curr); ok {
+			metricValues["container.memory."+name+".usage"] = v
+		}
+		metricValues["container.memory."+name+".limit"] = g.getMemoryLimit(c)
+
+		dockerstats.InterfaceMetrics(name, prev, curr, timeDelta, metricValues)
+	}
+
+	g.prevStats = stats
+	g.prevTime = now
+
+	return metricValues, nil
+}
+
+// GetGraphDefs gets graph definitions
+func (g *metricGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+func (g *metricGenerator) getCPULimit(c *dockerTypes.InspectResponse) float64 {
+	if hc := c.HostConfig; hc != nil {
+		if hc.NanoCPUs > 0 {
+			return float64(hc.NanoCPUs) / 1e9 * 100
+		}
+		if hc.CPUQuota > 0 && hc.CPUPeriod > 0 {
+			return float64(hc.CPUQuota) / float64(hc.CPUPeriod) * 100
+		}
+	}
+	return *g.hostNumCores * 100
+}
+
+func (g *metricGenerator) getMemoryLimit(c *dockerTypes.InspectResponse) float64 {
+	if hc := c.HostConfig; hc != nil && hc.Memory > 0 {
+		return float64(hc.Memory)
+	}
+	return *g.hostMemTotal
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/docker/engine"
+)
+
+func readJSON[T any](t *testing.T, path string) *T {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+	return &v
+}
+
+func newMockClient(t *testing.T) *engine.MockClient {
+	containers := []*dockerTypes.InspectResponse{
+		readJSON[dockerTypes.InspectResponse](t, "engine/testdata/container_web.json"),
+		readJSON[dockerTypes.InspectResponse](t, "engine/testdata/container_agent.json"),
+	}
+	stats := map[string]*dockerTypes.StatsResponse{
+		containers[0].ID: readJSON[dockerTypes.StatsResponse](t, "engine/testdata/stats_web.json"),
+		containers[1].ID: readJSON[dockerTypes.StatsResponse](t, "engine/testdata/stats_agent.json"),
+	}
+	return engine.NewMockClient(
+		engine.MockGetContainer(func(_ context.Context, id string) (*dockerTypes.InspectResponse, error) {
+			for _, c := range containers {
+				if c.ID == id {
+					return c, nil
+				}
+			}
+			return nil, os.ErrNotExist
+		}),
+		engine.MockListContainers(func(context.Context, []string) ([]*dockerTypes.InspectResponse, error) {
+			return containers, nil
+		}),
+		engine.MockGetContainerStats(func(_ context.Context, id string) (*dockerTypes.StatsResponse, error) {
+			return stats[id], nil
+		}),
+	)
+}
+
+func TestGenerateMetric(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient(t)
+	generator := newMetricGenerator(client, []string{"com.docker.compose.project=myapp"}, hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	got, err := generator.Generate(ctx) // Store metrics to generator.prevStats.
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Generate() should return nil at first but got %v", got)
+	}
+
+	got, err = generator.Generate(ctx)
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	expected := metric.Values{
+		"container.cpu.myapp-web-1.usage":                               0.0, // Result is 0 because use the same data.
+		"container.cpu.myapp-web-1.limit":                               50.0,
+		"container.memory.myapp-web-1.usage":                            1.048576e+07,
+		"container.memory.myapp-web-1.limit":                            268435456.0, // 256MiB
+		"interface.myapp-web-1-eth0.rxBytes.delta":                      0.0,
+		"interface.myapp-web-1-eth0.txBytes.delta":                      0.0,
+		"container.cpu.myapp-mackerel-container-agent-1.usage":          0.0,
+		"container.cpu.myapp-mackerel-container-agent-1.limit":          25.0,
+		"container.memory.myapp-mackerel-container-agent-1.usage":       1.6777216e+07,
+		"container.memory.myapp-mackerel-container-agent-1.limit":       8201183232.0, // mockMemTotal
+		"interface.myapp-mackerel-container-agent-1-eth0.rxBytes.delta": 0.0,
+		"interface.myapp-mackerel-container-agent-1-eth0.txBytes.delta": 0.0,
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Generate() expected %v, got %v", expected, got)
+	}
+}
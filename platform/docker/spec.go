@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/docker/engine"
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+type specGenerator struct {
+	client   engine.Client
+	labels   []string
+	hostname string
+}
+
+func newSpecGenerator(client engine.Client, labels []string, hostname string) *specGenerator {
+	return &specGenerator{
+		client:   client,
+		labels:   labels,
+		hostname: hostname,
+	}
+}
+
+func (g *specGenerator) Generate(ctx context.Context) (any, error) {
+	containers, err := g.client.ListContainers(ctx, g.labels)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &projectSpec{
+		Labels: g.labels,
+	}
+	if len(containers) > 0 {
+		spec.Containers = make([]containerSpec, len(containers))
+		for i, c := range containers {
+			spec.Containers[i] = generateContainerSpec(c)
+		}
+	}
+
+	return &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: string(platform.Docker),
+			MetaData: spec,
+		},
+		Hostname: g.hostname,
+	}, nil
+}
+
+func generateContainerSpec(c *dockerTypes.InspectResponse) containerSpec {
+	spec := containerSpec{
+		ID:           c.ID,
+		Name:         engine.ContainerName(c),
+		ImageID:      c.Image,
+		RestartCount: c.RestartCount,
+	}
+
+	if c.Config != nil {
+		spec.Image = c.Config.Image
+		spec.Labels = c.Config.Labels
+	}
+
+	if s := c.State; s != nil {
+		spec.Status = string(s.Status)
+		if s.Health != nil {
+			spec.Health = string(s.Health.Status)
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s.StartedAt); err == nil {
+			spec.StartedAt = &t
+		}
+	}
+
+	if hc := c.HostConfig; hc != nil {
+		if hc.NanoCPUs > 0 {
+			cpu := float64(hc.NanoCPUs) / 1e9
+			spec.Limits.CPU = &cpu
+		}
+		if hc.Memory > 0 {
+			memory := hc.Memory
+			spec.Limits.Memory = &memory
+		}
+	}
+
+	if ns := c.NetworkSettings; ns != nil && len(ns.Networks) > 0 {
+		names := make([]string, 0, len(ns.Networks))
+		for name := range ns.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			n := ns.Networks[name]
+			if n == nil {
+				continue
+			}
+			spec.Networks = append(spec.Networks, networkSpec{
+				Name:        name,
+				IPAddress:   n.IPAddress,
+				IPv6Address: n.GlobalIPv6Address,
+				MacAddress:  n.MacAddress,
+			})
+		}
+	}
+
+	return spec
+}
@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+func TestGenerateSpec(t *testing.T) {
+	client := newMockClient(t)
+	labels := []string{"com.docker.compose.project=myapp"}
+	generator := newSpecGenerator(client, labels, "myapp")
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+
+	cpu := 0.5
+	memory := int64(268435456)
+	webStartedAt := time.Date(2026, time.October, 1, 0, 0, 1, 123456789, time.UTC)
+	agentStartedAt := time.Date(2026, time.October, 1, 0, 0, 2, 123456789, time.UTC)
+	expected := &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: "docker",
+			MetaData: &projectSpec{
+				Labels: labels,
+				Containers: []containerSpec{
+					{
+						ID:      "3f1a0c1e3d2b4c5a6f7e8d9c0b1a2f3e4d5c6b7a8f9e0d1c2b3a4f5e6d7c8b9a",
+						Name:    "myapp-web-1",
+						Image:   "nginx:1.27",
+						ImageID: "sha256:5f1c4a1b3d2e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a",
+						Labels: map[string]string{
+							"com.docker.compose.project": "myapp",
+							"com.docker.compose.service": "web",
+						},
+						Status:       "running",
+						Health:       "healthy",
+						StartedAt:    &webStartedAt,
+						Limits:       limitSpec{CPU: &cpu, Memory: &memory},
+						Networks:     []networkSpec{{Name: "myapp_default", IPAddress: "172.18.0.2", MacAddress: "02:42:ac:12:00:02"}},
+						RestartCount: 1,
+					},
+					{
+						ID:      "9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c",
+						Name:    "myapp-mackerel-container-agent-1",
+						Image:   "mackerel/mackerel-container-agent:latest",
+						ImageID: "sha256:0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b",
+						Labels: map[string]string{
+							"com.docker.compose.project": "myapp",
+							"com.docker.compose.service": "mackerel-container-agent",
+						},
+						Status:    "running",
+						StartedAt: &agentStartedAt,
+						Networks:  []networkSpec{{Name: "myapp_default", IPAddress: "172.18.0.3", MacAddress: "02:42:ac:12:00:03"}},
+					},
+				},
+			},
+		},
+		Hostname: "myapp",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Generate() expected %#v, got %#v", expected, got)
+	}
+}
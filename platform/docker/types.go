@@ -0,0 +1,34 @@
+package docker
+
+import "time"
+
+type projectSpec struct {
+	Labels     []string        `json:"labels,omitempty"`
+	Containers []containerSpec `json:"containers,omitempty"`
+}
+
+type containerSpec struct {
+	ID           string            `json:"id,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Image        string            `json:"image,omitempty"`
+	ImageID      string            `json:"image_id,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Status       string            `json:"status,omitempty"`
+	Health       string            `json:"health,omitempty"`
+	StartedAt    *time.Time        `json:"started_at,omitempty"`
+	Limits       limitSpec         `json:"limits"`
+	Networks     []networkSpec     `json:"networks,omitempty"`
+	RestartCount int               `json:"restart_count,omitempty"`
+}
+
+type limitSpec struct {
+	CPU    *float64 `json:"cpu,omitempty"`
+	Memory *int64   `json:"memory,omitempty"`
+}
+
+type networkSpec struct {
+	Name        string `json:"name,omitempty"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	IPv6Address string `json:"ipv6_address,omitempty"`
+	MacAddress  string `json:"mac_address,omitempty"`
+}
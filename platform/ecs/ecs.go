@@ -7,10 +7,12 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	ecsTypes "github.com/mackerelio/mackerel-container-agent/internal/amazon-ecs-agent/agent/handlers/v2"
 
 	"github.com/mackerelio/golib/logging"
 
+	"github.com/mackerelio/mackerel-container-agent/check"
 	"github.com/mackerelio/mackerel-container-agent/metric"
 	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
 	"github.com/mackerelio/mackerel-container-agent/platform"
@@ -102,9 +104,22 @@ func (p *ecsPlatform) GetSpecGenerators() []spec.Generator {
 	}
 }
 
+// GetCheckGenerators gets check generators
+func (p *ecsPlatform) GetCheckGenerators() []check.Generator {
+	return nil
+}
+
 // GetCustomIdentifier gets custom identifier
-func (p *ecsPlatform) GetCustomIdentifier(context.Context) (string, error) {
-	return "", nil
+// the task ARN is unique across the regions and the accounts, and suffixed like the other platforms.
+func (p *ecsPlatform) GetCustomIdentifier(ctx context.Context) (string, error) {
+	meta, err := p.client.GetTaskMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, err := arn.Parse(meta.TaskARN); err != nil {
+		return "", fmt.Errorf("invalid task ARN %q: %w", meta.TaskARN, err)
+	}
+	return meta.TaskARN + ".ecs", nil
 }
 
 // StatusRunning reports p status is running
@@ -117,10 +132,24 @@ func (p *ecsPlatform) StatusRunning(ctx context.Context) bool {
 	return isRunning(meta.KnownStatus)
 }
 
+// StatusStopping reports p status is stopping
+func (p *ecsPlatform) StatusStopping(ctx context.Context) bool {
+	meta, err := p.client.GetTaskMetadata(ctx)
+	if err != nil {
+		logger.Warningf("failed to get metadata: %s", err)
+		return false
+	}
+	return isStopping(meta.DesiredStatus)
+}
+
 func isRunning(status string) bool {
 	return status == "RUNNING"
 }
 
+func isStopping(desiredStatus string) bool {
+	return desiredStatus == "STOPPED"
+}
+
 func resolveProvider(executionEnv string) (provider, error) {
 	switch executionEnv {
 	case executionEnvFargate:
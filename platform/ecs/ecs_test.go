@@ -3,6 +3,8 @@ package ecs
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -32,6 +34,24 @@ func TestIsRunning(t *testing.T) {
 	}
 }
 
+func TestIsStopping(t *testing.T) {
+	tests := []struct {
+		desiredStatus string
+		expect        bool
+	}{
+		{"STOPPED", true},
+		{"RUNNING", false},
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		got := isStopping(tc.desiredStatus)
+		if got != tc.expect {
+			t.Errorf("isStopping() expected %t, got %t", tc.expect, got)
+		}
+	}
+}
+
 func TestResolveProvider(t *testing.T) {
 	tests := []struct {
 		executionEnv string
@@ -221,3 +241,31 @@ func TestGetTaskMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestGetCustomIdentifier(t *testing.T) {
+	invalidARNPath := filepath.Join(t.TempDir(), "metadata_invalid_arn.json")
+	if err := os.WriteFile(invalidARNPath, []byte(`{"TaskARN":"task-id"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		metadataPath string
+		expect       string
+		raiseError   bool
+	}{
+		{"taskmetadata/testdata/metadata_ec2_bridge.json", "arn:aws:ecs:ap-northeast-1:999999999999:task/task-id.ecs", false},
+		{invalidARNPath, "", true},
+		{"taskmetadata/testdata/not_found.json", "", true},
+	}
+
+	for _, tc := range tests {
+		pform := ecsPlatform{client: &mockTaskMetadataEndpointClient{metadataPath: tc.metadataPath}}
+		got, err := pform.GetCustomIdentifier(context.Background())
+		if (err != nil) != tc.raiseError {
+			t.Errorf("GetCustomIdentifier() raise error = %v, expected raise error = %t", err, tc.raiseError)
+		}
+		if got != tc.expect {
+			t.Errorf("GetCustomIdentifier() expected %q, got %q", tc.expect, got)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/mackerelio/mackerel-container-agent/metric"
 	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/internal/dockerstats"
 )
 
 // TaskStatsGetter interface fetch ECS task stats
@@ -80,12 +81,16 @@ func (g *metricGenerator) Generate(ctx context.Context) (metric.Values, error) {
 		}
 
 		name := metric.SanitizeMetricKey(c.Name)
-		metricValues["container.cpu."+name+".usage"] = calculateCPUMetrics(prev, curr, timeDelta)
+		if v, ok := dockerstats.CPUUsage(prev, curr, timeDelta); ok {
+			metricValues["container.cpu."+name+".usage"] = v
+		}
 		metricValues["container.cpu."+name+".limit"] = g.getCPULimit(meta)
-		metricValues["container.memory."+name+".usage"] = calculateMemoryMetrics(curr)
+		if v, ok := dockerstats.MemoryUsage(curr); ok {
+			metricValues["container.memory."+name+".usage"] = v
+		}
 		metricValues["container.memory."+name+".limit"] = g.getMemoryLimit(&c, meta)
 
-		calculateInterfaceMetrics(name, prev, curr, timeDelta, metricValues)
+		dockerstats.InterfaceMetrics(name, prev, curr, timeDelta, metricValues)
 	}
 
 	g.prevStats = stats
@@ -115,24 +120,3 @@ func (g *metricGenerator) getCPULimit(meta *ecsTypes.TaskResponse) float64 {
 	}
 	return *g.hostNumCores * 100
 }
-
-func calculateCPUMetrics(prev, curr *dockerTypes.StatsResponse, timeDelta time.Duration) float64 {
-	// calculate used cpu cores. (1core == 100.0)
-	return float64(curr.CPUStats.CPUUsage.TotalUsage-prev.CPUStats.CPUUsage.TotalUsage) / float64(timeDelta.Nanoseconds()) * 100
-}
-
-func calculateMemoryMetrics(stats *dockerTypes.StatsResponse) float64 {
-	return float64(stats.MemoryStats.Usage - stats.MemoryStats.Stats["cache"])
-}
-
-func calculateInterfaceMetrics(name string, prev, curr *dockerTypes.StatsResponse, timeDelta time.Duration, metricValues metric.Values) {
-	for ifn, pv := range prev.Networks {
-		cv, ok := curr.Networks[ifn]
-		if !ok {
-			continue
-		}
-		prefix := "interface." + name + "-" + metric.SanitizeMetricKey(ifn)
-		metricValues[prefix+".rxBytes.delta"] = float64(cv.RxBytes-pv.RxBytes) / timeDelta.Seconds()
-		metricValues[prefix+".txBytes.delta"] = float64(cv.TxBytes-pv.TxBytes) / timeDelta.Seconds()
-	}
-}
@@ -0,0 +1,53 @@
+// Package dockerstats calculates metric values from the container stats of Docker Engine API.
+package dockerstats
+
+import (
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+)
+
+// CPUUsage calculates used cpu cores. (1core == 100.0)
+// It reports false when the counter is reset by the restart of the container.
+func CPUUsage(prev, curr *dockerTypes.StatsResponse, timeDelta time.Duration) (float64, bool) {
+	if curr.CPUStats.CPUUsage.TotalUsage < prev.CPUStats.CPUUsage.TotalUsage {
+		return 0.0, false
+	}
+	return float64(curr.CPUStats.CPUUsage.TotalUsage-prev.CPUStats.CPUUsage.TotalUsage) / float64(timeDelta.Nanoseconds()) * 100, true
+}
+
+// MemoryUsage calculates used memory bytes excluding page cache.
+// It reports false when the page cache exceeds the usage.
+func MemoryUsage(stats *dockerTypes.StatsResponse) (float64, bool) {
+	if stats.MemoryStats.Stats["cache"] > stats.MemoryStats.Usage {
+		return 0.0, false
+	}
+	return float64(stats.MemoryStats.Usage - stats.MemoryStats.Stats["cache"]), true
+}
+
+// InterfaceMetrics calculates received and transmitted bytes per second of each network interface.
+func InterfaceMetrics(name string, prev, curr *dockerTypes.StatsResponse, timeDelta time.Duration, metricValues metric.Values) {
+	for ifn, pv := range prev.Networks {
+		cv, ok := curr.Networks[ifn]
+		if !ok {
+			continue
+		}
+		prefix := "interface." + name + "-" + metric.SanitizeMetricKey(ifn)
+		if v, ok := calculateRate(pv.RxBytes, cv.RxBytes, timeDelta); ok {
+			metricValues[prefix+".rxBytes.delta"] = v
+		}
+		if v, ok := calculateRate(pv.TxBytes, cv.TxBytes, timeDelta); ok {
+			metricValues[prefix+".txBytes.delta"] = v
+		}
+	}
+}
+
+// calculateRate reports false when the counter is reset by the restart of the container
+func calculateRate(prev, curr uint64, timeDelta time.Duration) (float64, bool) {
+	if curr < prev {
+		return 0.0, false
+	}
+	return float64(curr-prev) / timeDelta.Seconds(), true
+}
@@ -0,0 +1,110 @@
+package dockerstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types/container"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+)
+
+func newStats(cpu, rx, tx uint64) *dockerTypes.StatsResponse {
+	return &dockerTypes.StatsResponse{
+		CPUStats: dockerTypes.CPUStats{CPUUsage: dockerTypes.CPUUsage{TotalUsage: cpu}},
+		Networks: map[string]dockerTypes.NetworkStats{
+			"eth0": {RxBytes: rx, TxBytes: tx},
+		},
+	}
+}
+
+func TestCPUUsage(t *testing.T) {
+	testCases := []struct {
+		name       string
+		prev, curr *dockerTypes.StatsResponse
+		expect     float64
+		ok         bool
+	}{
+		{
+			name:   "usage",
+			prev:   newStats(1_000_000_000, 0, 0),
+			curr:   newStats(1_500_000_000, 0, 0),
+			expect: 50,
+			ok:     true,
+		},
+		{
+			name: "counter reset",
+			prev: newStats(1_500_000_000, 0, 0),
+			curr: newStats(100_000_000, 0, 0),
+			ok:   false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, ok := CPUUsage(tc.prev, tc.curr, time.Second); got != tc.expect || ok != tc.ok {
+				t.Errorf("expected (%v, %v) but got (%v, %v)", tc.expect, tc.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	testCases := []struct {
+		name         string
+		usage, cache uint64
+		expect       float64
+		ok           bool
+	}{
+		{"usage", 3000, 1000, 2000, true},
+		{"cache greater than usage", 1000, 3000, 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := &dockerTypes.StatsResponse{
+				MemoryStats: dockerTypes.MemoryStats{Usage: tc.usage, Stats: map[string]uint64{"cache": tc.cache}},
+			}
+			if got, ok := MemoryUsage(stats); got != tc.expect || ok != tc.ok {
+				t.Errorf("expected (%v, %v) but got (%v, %v)", tc.expect, tc.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestInterfaceMetrics(t *testing.T) {
+	testCases := []struct {
+		name       string
+		prev, curr *dockerTypes.StatsResponse
+		expect     metric.Values
+	}{
+		{
+			name: "delta",
+			prev: newStats(0, 1000, 2000),
+			curr: newStats(0, 3000, 6000),
+			expect: metric.Values{
+				"interface.web-eth0.rxBytes.delta": 1000,
+				"interface.web-eth0.txBytes.delta": 2000,
+			},
+		},
+		{
+			name: "counter reset",
+			prev: newStats(0, 3000, 6000),
+			curr: newStats(0, 100, 8000),
+			expect: metric.Values{
+				"interface.web-eth0.txBytes.delta": 1000,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := make(metric.Values)
+			InterfaceMetrics("web", tc.prev, tc.curr, 2*time.Second, got)
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected %v but got %v", tc.expect, got)
+			}
+			if _, ok := got["interface.web-eth0.rxBytes.delta"]; ok && tc.name == "counter reset" {
+				t.Errorf("the delta of the reset counter should be skipped")
+			}
+		})
+	}
+}
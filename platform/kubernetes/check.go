@@ -0,0 +1,191 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kubernetesTypes "k8s.io/api/core/v1"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/check"
+	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/platform/kubernetes/kubelet"
+)
+
+const (
+	defaultReadinessCheckName           = "pod-readiness"
+	defaultReadinessWarningGracePeriod  = time.Minute
+	defaultReadinessCriticalGracePeriod = 5 * time.Minute
+)
+
+var checkStatusSeverity = map[mackerel.CheckStatus]int{
+	mackerel.CheckStatusOK:       0,
+	mackerel.CheckStatusUnknown:  1,
+	mackerel.CheckStatusWarning:  2,
+	mackerel.CheckStatusCritical: 3,
+}
+
+type readinessCheckGenerator struct {
+	client              kubelet.Client
+	name                string
+	memo                string
+	warningGracePeriod  time.Duration
+	criticalGracePeriod time.Duration
+	unreadySince        map[string]time.Time
+	restartCounts       map[string]int32
+	lastResult          *check.Result
+}
+
+func newReadinessCheckGenerator(client kubelet.Client, conf *config.PodReadinessCheck) *readinessCheckGenerator {
+	g := &readinessCheckGenerator{
+		client:              client,
+		name:                conf.Name,
+		memo:                conf.Memo,
+		warningGracePeriod:  defaultReadinessWarningGracePeriod,
+		criticalGracePeriod: defaultReadinessCriticalGracePeriod,
+	}
+	if g.name == "" {
+		g.name = defaultReadinessCheckName
+	}
+	// keep the warning grace period not greater than the critical one
+	switch warning, critical := conf.WarningGracePeriodSeconds, conf.CriticalGracePeriodSeconds; {
+	case warning != nil && critical != nil:
+		g.warningGracePeriod = time.Duration(*warning) * time.Second
+		g.criticalGracePeriod = time.Duration(*critical) * time.Second
+	case warning != nil:
+		g.warningGracePeriod = time.Duration(*warning) * time.Second
+		g.criticalGracePeriod = max(g.criticalGracePeriod, g.warningGracePeriod)
+	case critical != nil:
+		g.criticalGracePeriod = time.Duration(*critical) * time.Second
+		g.warningGracePeriod = min(g.warningGracePeriod, g.criticalGracePeriod)
+	}
+	return g
+}
+
+// Config gets check generator config
+func (g *readinessCheckGenerator) Config() mackerel.CheckConfig {
+	return mackerel.CheckConfig{Name: g.name, Memo: g.memo}
+}
+
+// Generate generates check report
+func (g *readinessCheckGenerator) Generate(ctx context.Context) (*check.Result, error) {
+	pod, err := g.client.GetPod(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := mackerel.CheckStatusOK
+	var messages []string
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type != kubernetesTypes.PodReady && c.Type != kubernetesTypes.ContainersReady {
+			continue
+		}
+		if c.Status == kubernetesTypes.ConditionTrue {
+			continue
+		}
+		since := c.LastTransitionTime.Time
+		if since.IsZero() {
+			since = now
+		}
+		duration := now.Sub(since)
+		s := g.statusFromDuration(duration)
+		if s == mackerel.CheckStatusOK {
+			continue
+		}
+		status = worseStatus(status, s)
+		msg := fmt.Sprintf("condition %s is %s for %s", c.Type, c.Status, duration.Round(time.Second))
+		if c.Reason != "" {
+			msg += " (" + c.Reason + ")"
+		}
+		messages = append(messages, msg)
+	}
+
+	containers := make(map[string]bool, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		containers[c.Name] = true
+	}
+	unreadySince := make(map[string]time.Time)
+	restartCounts := make(map[string]int32)
+	for _, cs := range pod.Status.ContainerStatuses {
+		if !containers[cs.Name] { // ignored container
+			continue
+		}
+		restartCounts[cs.Name] = cs.RestartCount
+		if prev, ok := g.restartCounts[cs.Name]; ok && cs.RestartCount > prev {
+			status = worseStatus(status, mackerel.CheckStatusWarning)
+			messages = append(messages, fmt.Sprintf("container %s restarted %d time(s) (restart count: %d)", cs.Name, cs.RestartCount-prev, cs.RestartCount))
+		}
+		if cs.Ready {
+			continue
+		}
+		since, ok := g.unreadySince[cs.Name]
+		if !ok {
+			since = now
+		}
+		unreadySince[cs.Name] = since
+		duration := now.Sub(since)
+		s := g.statusFromDuration(duration)
+		if s == mackerel.CheckStatusOK {
+			continue
+		}
+		status = worseStatus(status, s)
+		msg := fmt.Sprintf("container %s is not ready for %s", cs.Name, duration.Round(time.Second))
+		if reason := containerStateReason(&cs.State); reason != "" {
+			msg += " (" + reason + ")"
+		}
+		messages = append(messages, msg)
+	}
+	g.unreadySince = unreadySince
+	g.restartCounts = restartCounts
+
+	message := "pod and containers are ready"
+	if len(messages) > 0 {
+		message = strings.Join(messages, "\n")
+	}
+	newResult := check.NewResult(g.name, message, status, now)
+
+	lastResult := g.lastResult
+	g.lastResult = newResult
+	if lastResult == nil {
+		return newResult, nil
+	}
+	if lastResult.Status() == mackerel.CheckStatusOK && newResult.Status() == mackerel.CheckStatusOK {
+		// do not report ok -> ok
+		return nil, nil
+	}
+	return newResult, nil
+}
+
+func (g *readinessCheckGenerator) statusFromDuration(d time.Duration) mackerel.CheckStatus {
+	switch {
+	case d >= g.criticalGracePeriod:
+		return mackerel.CheckStatusCritical
+	case d >= g.warningGracePeriod:
+		return mackerel.CheckStatusWarning
+	default:
+		return mackerel.CheckStatusOK
+	}
+}
+
+func worseStatus(a, b mackerel.CheckStatus) mackerel.CheckStatus {
+	if checkStatusSeverity[b] > checkStatusSeverity[a] {
+		return b
+	}
+	return a
+}
+
+func containerStateReason(state *kubernetesTypes.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return state.Waiting.Reason
+	case state.Terminated != nil:
+		return state.Terminated.Reason
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kubernetesTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/config"
+	"github.com/mackerelio/mackerel-container-agent/platform/kubernetes/kubelet"
+)
+
+func TestReadinessCheckGenerator(t *testing.T) {
+	now := time.Now()
+	newPod := func(conditions []kubernetesTypes.PodCondition, statuses []kubernetesTypes.ContainerStatus) *kubernetesTypes.Pod {
+		return &kubernetesTypes.Pod{
+			Spec: kubernetesTypes.PodSpec{
+				Containers: []kubernetesTypes.Container{{Name: "app"}, {Name: "mackerel-container-agent"}},
+			},
+			Status: kubernetesTypes.PodStatus{
+				Conditions:        conditions,
+				ContainerStatuses: statuses,
+			},
+		}
+	}
+	ready := []kubernetesTypes.PodCondition{
+		{Type: kubernetesTypes.PodReady, Status: kubernetesTypes.ConditionTrue},
+		{Type: kubernetesTypes.ContainersReady, Status: kubernetesTypes.ConditionTrue},
+	}
+	unready := func(since time.Time) []kubernetesTypes.PodCondition {
+		return []kubernetesTypes.PodCondition{
+			{Type: kubernetesTypes.PodReady, Status: kubernetesTypes.ConditionFalse, LastTransitionTime: metav1.NewTime(since), Reason: "ContainersNotReady"},
+			{Type: kubernetesTypes.ContainersReady, Status: kubernetesTypes.ConditionTrue},
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		pods           []*kubernetesTypes.Pod
+		expectStatuses []mackerel.CheckStatus
+		expectMessages []string
+	}{
+		{
+			name: "ready",
+			pods: []*kubernetesTypes.Pod{
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "app", Ready: true}, {Name: "mackerel-container-agent", Ready: true},
+				}),
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "app", Ready: true}, {Name: "mackerel-container-agent", Ready: true},
+				}),
+			},
+			expectStatuses: []mackerel.CheckStatus{mackerel.CheckStatusOK, ""},
+		},
+		{
+			name: "pod not ready within grace period",
+			pods: []*kubernetesTypes.Pod{
+				newPod(unready(now.Add(-10*time.Second)), nil),
+			},
+			expectStatuses: []mackerel.CheckStatus{mackerel.CheckStatusOK},
+		},
+		{
+			name: "pod not ready beyond warning grace period",
+			pods: []*kubernetesTypes.Pod{
+				newPod(unready(now.Add(-2*time.Minute)), nil),
+			},
+			expectStatuses: []mackerel.CheckStatus{mackerel.CheckStatusWarning},
+			expectMessages: []string{"condition Ready is False"},
+		},
+		{
+			name: "pod not ready beyond critical grace period",
+			pods: []*kubernetesTypes.Pod{
+				newPod(unready(now.Add(-10*time.Minute)), nil),
+			},
+			expectStatuses: []mackerel.CheckStatus{mackerel.CheckStatusCritical},
+			expectMessages: []string{"(ContainersNotReady)"},
+		},
+		{
+			name: "container restarted",
+			pods: []*kubernetesTypes.Pod{
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "app", Ready: true, RestartCount: 1},
+				}),
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "app", Ready: true, RestartCount: 3},
+				}),
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "app", Ready: true, RestartCount: 3},
+				}),
+			},
+			expectStatuses: []mackerel.CheckStatus{mackerel.CheckStatusOK, mackerel.CheckStatusWarning, mackerel.CheckStatusOK},
+			expectMessages: []string{"", "container app restarted 2 time(s)", ""},
+		},
+		{
+			name: "ignored container",
+			pods: []*kubernetesTypes.Pod{
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "ignored", Ready: true, RestartCount: 1},
+				}),
+				newPod(ready, []kubernetesTypes.ContainerStatus{
+					{Name: "ignored", Ready: false, RestartCount: 2},
+				}),
+			},
+			expectStatuses: []mackerel.CheckStatus{mackerel.CheckStatusOK, ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var i int
+			client := kubelet.NewMockClient(
+				kubelet.MockGetPod(func(context.Context) (*kubernetesTypes.Pod, error) {
+					return tc.pods[i], nil
+				}),
+			)
+			warning, critical := 60, 300
+			g := newReadinessCheckGenerator(client, &config.PodReadinessCheck{
+				WarningGracePeriodSeconds:  &warning,
+				CriticalGracePeriodSeconds: &critical,
+			})
+			for i = range tc.pods {
+				r, err := g.Generate(context.Background())
+				if err != nil {
+					t.Fatalf("Generate() should not raise error: %v", err)
+				}
+				if tc.expectStatuses[i] == "" {
+					if r != nil {
+						t.Errorf("Generate() should return nil but got %#v", r)
+					}
+					continue
+				}
+				if r == nil {
+					t.Fatalf("Generate() should not return nil")
+				}
+				if r.Status() != tc.expectStatuses[i] {
+					t.Errorf("status should be %s but got %s (message: %q)", tc.expectStatuses[i], r.Status(), r.Message())
+				}
+				if i < len(tc.expectMessages) && !strings.Contains(r.Message(), tc.expectMessages[i]) {
+					t.Errorf("message should contain %q but got %q", tc.expectMessages[i], r.Message())
+				}
+			}
+		})
+	}
+}
+
+func TestNewReadinessCheckGenerator_GracePeriods(t *testing.T) {
+	zero, short, long := 0, 30, 600
+	testCases := []struct {
+		warning, critical *int
+		expectWarning     time.Duration
+		expectCritical    time.Duration
+	}{
+		{nil, nil, time.Minute, 5 * time.Minute},
+		{&zero, nil, 0, 5 * time.Minute},
+		{nil, &zero, 0, 0},
+		{&long, nil, 10 * time.Minute, 10 * time.Minute},
+		{nil, &short, 30 * time.Second, 30 * time.Second},
+		{&zero, &short, 0, 30 * time.Second},
+	}
+	for _, tc := range testCases {
+		g := newReadinessCheckGenerator(nil, &config.PodReadinessCheck{
+			WarningGracePeriodSeconds:  tc.warning,
+			CriticalGracePeriodSeconds: tc.critical,
+		})
+		if g.warningGracePeriod != tc.expectWarning || g.criticalGracePeriod != tc.expectCritical {
+			t.Errorf("grace periods should be %s and %s but got %s and %s",
+				tc.expectWarning, tc.expectCritical, g.warningGracePeriod, g.criticalGracePeriod)
+		}
+	}
+}
+
+func TestReadinessCheckGenerator_ContainerNotReady(t *testing.T) {
+	pod := &kubernetesTypes.Pod{
+		Spec: kubernetesTypes.PodSpec{
+			Containers: []kubernetesTypes.Container{{Name: "app"}},
+		},
+		Status: kubernetesTypes.PodStatus{
+			ContainerStatuses: []kubernetesTypes.ContainerStatus{
+				{
+					Name:  "app",
+					Ready: false,
+					State: kubernetesTypes.ContainerState{
+						Waiting: &kubernetesTypes.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+					},
+				},
+			},
+		},
+	}
+	client := kubelet.NewMockClient(
+		kubelet.MockGetPod(func(context.Context) (*kubernetesTypes.Pod, error) {
+			return pod, nil
+		}),
+	)
+	g := newReadinessCheckGenerator(client, &config.PodReadinessCheck{})
+	if g.Config().Name != defaultReadinessCheckName {
+		t.Errorf("name should be %q but got %q", defaultReadinessCheckName, g.Config().Name)
+	}
+
+	r, err := g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	if r.Status() != mackerel.CheckStatusOK {
+		t.Errorf("status should be OK within the grace period but got %s", r.Status())
+	}
+
+	g.unreadySince["app"] = time.Now().Add(-3 * time.Minute)
+	r, err = g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	if r.Status() != mackerel.CheckStatusWarning {
+		t.Errorf("status should be WARNING but got %s", r.Status())
+	}
+	if expect := "container app is not ready for 3m0s (CrashLoopBackOff)"; r.Message() != expect {
+		t.Errorf("message should be %q but got %q", expect, r.Message())
+	}
+}
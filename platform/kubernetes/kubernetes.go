@@ -15,6 +15,8 @@ import (
 
 	"github.com/mackerelio/golib/logging"
 
+	"github.com/mackerelio/mackerel-container-agent/check"
+	"github.com/mackerelio/mackerel-container-agent/config"
 	"github.com/mackerelio/mackerel-container-agent/metric"
 	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
 	"github.com/mackerelio/mackerel-container-agent/platform"
@@ -31,11 +33,12 @@ var (
 )
 
 type kubernetesPlatform struct {
-	client kubelet.Client
+	client         kubelet.Client
+	readinessCheck *config.PodReadinessCheck
 }
 
 // NewKubernetesPlatform creates a new Platform
-func NewKubernetesPlatform(kubeletHost, kubeletPort string, useReadOnlyPort, insecureTLS bool, namespace, podName string, ignoreContainer *regexp.Regexp) (platform.Platform, error) {
+func NewKubernetesPlatform(kubeletHost, kubeletPort string, useReadOnlyPort, insecureTLS bool, namespace, podName string, ignoreContainer *regexp.Regexp, readinessCheck *config.PodReadinessCheck) (platform.Platform, error) {
 	var caCert, token []byte
 
 	baseURL := &url.URL{
@@ -72,12 +75,12 @@ func NewKubernetesPlatform(kubeletHost, kubeletPort string, useReadOnlyPort, ins
 	if err != nil {
 		return nil, err
 	}
-	return &kubernetesPlatform{client: c}, nil
+	return &kubernetesPlatform{client: c, readinessCheck: readinessCheck}, nil
 }
 
 // NewEKSOnFargatePlatform creates a new Platform
 // on this platform, agent accesses Kubelet via Kubernetes API (/api/v1/nodes/{nodeName}/proxy)
-func NewEKSOnFargatePlatform(kubernetesHost, kubernetesPort string, namespace, podName string, nodeName string, ignoreContainer *regexp.Regexp) (platform.Platform, error) {
+func NewEKSOnFargatePlatform(kubernetesHost, kubernetesPort string, namespace, podName string, nodeName string, ignoreContainer *regexp.Regexp, readinessCheck *config.PodReadinessCheck) (platform.Platform, error) {
 	var caCert, token []byte
 	var err error
 
@@ -110,7 +113,7 @@ func NewEKSOnFargatePlatform(kubernetesHost, kubernetesPort string, namespace, p
 	if err != nil {
 		return nil, err
 	}
-	return &kubernetesPlatform{client: c}, nil
+	return &kubernetesPlatform{client: c, readinessCheck: readinessCheck}, nil
 }
 
 // GetMetricGenerators gets metric generators
@@ -129,6 +132,16 @@ func (p *kubernetesPlatform) GetSpecGenerators() []spec.Generator {
 	}
 }
 
+// GetCheckGenerators gets check generators
+func (p *kubernetesPlatform) GetCheckGenerators() []check.Generator {
+	if p.readinessCheck == nil {
+		return nil
+	}
+	return []check.Generator{
+		newReadinessCheckGenerator(p.client, p.readinessCheck),
+	}
+}
+
 // GetCustomIdentifier gets custom identifier
 func (p *kubernetesPlatform) GetCustomIdentifier(ctx context.Context) (string, error) {
 	pod, err := p.client.GetPod(ctx)
@@ -148,6 +161,16 @@ func (p *kubernetesPlatform) StatusRunning(ctx context.Context) bool {
 	return strings.EqualFold("running", string(meta.Status.Phase))
 }
 
+// StatusStopping reports p status is stopping
+func (p *kubernetesPlatform) StatusStopping(ctx context.Context) bool {
+	meta, err := p.client.GetPod(ctx)
+	if err != nil {
+		logger.Warningf("failed to get metadata: %s", err)
+		return false
+	}
+	return meta.DeletionTimestamp != nil
+}
+
 func createHTTPClient(caCert []byte, insecureTLS bool) *http.Client {
 	dt := http.DefaultTransport.(*http.Transport)
 	tp := &http.Transport{
@@ -10,13 +10,14 @@ import (
 	"testing"
 
 	kubernetesTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/mackerelio/mackerel-container-agent/platform/kubernetes/kubelet"
 )
 
 func TestStatusRunning(t *testing.T) {
 	mockClient := kubelet.NewMockClient()
-	pform := kubernetesPlatform{mockClient}
+	pform := kubernetesPlatform{client: mockClient}
 
 	tests := []struct {
 		status string
@@ -48,6 +49,38 @@ func TestStatusRunning(t *testing.T) {
 	}
 }
 
+func TestStatusStopping(t *testing.T) {
+	mockClient := kubelet.NewMockClient()
+	pform := kubernetesPlatform{client: mockClient}
+	now := metav1.Now()
+
+	tests := []struct {
+		deletionTimestamp *metav1.Time
+		expect            bool
+	}{
+		{&now, true},
+		{nil, false},
+	}
+
+	for _, tc := range tests {
+		ctx := context.Background()
+		mockClient.ApplyOption(
+			kubelet.MockGetPod(
+				func(context.Context) (*kubernetesTypes.Pod, error) {
+					return &kubernetesTypes.Pod{
+						ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: tc.deletionTimestamp},
+					}, nil
+				},
+			),
+		)
+
+		got := pform.StatusStopping(ctx)
+		if got != tc.expect {
+			t.Errorf("StatusStopping() expected %t, got %t", tc.expect, got)
+		}
+	}
+}
+
 func TestCreateHTTPClient(t *testing.T) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
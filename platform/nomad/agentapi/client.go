@@ -0,0 +1,144 @@
+package agentapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"time"
+)
+
+// Client interface gets the allocation and its stats from Nomad agent API
+type Client interface {
+	GetAllocation(context.Context) (*Allocation, error)
+	GetAllocationStats(context.Context) (*AllocResourceUsage, error)
+}
+
+const (
+	// DefaultAddress represents the default address of Nomad agent API
+	DefaultAddress = "http://127.0.0.1:4646"
+
+	allocationPath       = "/v1/allocation"
+	clientAllocationPath = "/v1/client/allocation"
+	tokenHeader          = "X-Nomad-Token"
+)
+
+var timeout = 3 * time.Second
+
+type client struct {
+	url             *url.URL
+	httpClient      *http.Client
+	allocID         string
+	token           string
+	ignoreContainer *regexp.Regexp
+}
+
+// NewClient creates a new Client
+func NewClient(address, allocID, token string, ignoreContainer *regexp.Regexp) (Client, error) {
+	if allocID == "" {
+		return nil, fmt.Errorf("allocation id should not be empty")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	dt := http.DefaultTransport.(*http.Transport)
+	return &client{
+		url: u,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dt.DialContext,
+				MaxIdleConns:          dt.MaxIdleConns,
+				IdleConnTimeout:       dt.IdleConnTimeout,
+				TLSHandshakeTimeout:   dt.TLSHandshakeTimeout,
+				ExpectContinueTimeout: dt.ExpectContinueTimeout,
+			},
+		},
+		allocID:         allocID,
+		token:           token,
+		ignoreContainer: ignoreContainer,
+	}, nil
+}
+
+// GetAllocation gets the allocation
+func (c *client) GetAllocation(ctx context.Context) (*Allocation, error) {
+	req, err := c.newRequest(path.Join(allocationPath, c.allocID))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var alloc Allocation
+	if err = decodeBody(resp, &alloc); err != nil {
+		return nil, err
+	}
+	if c.ignoreContainer != nil {
+		for name := range alloc.TaskStates {
+			if c.ignoreContainer.MatchString(name) {
+				delete(alloc.TaskStates, name)
+			}
+		}
+		if alloc.AllocatedResources != nil {
+			for name := range alloc.AllocatedResources.Tasks {
+				if c.ignoreContainer.MatchString(name) {
+					delete(alloc.AllocatedResources.Tasks, name)
+				}
+			}
+		}
+	}
+	return &alloc, nil
+}
+
+// GetAllocationStats gets the resource usage of the allocation
+func (c *client) GetAllocationStats(ctx context.Context) (*AllocResourceUsage, error) {
+	req, err := c.newRequest(path.Join(clientAllocationPath, c.allocID, "stats"))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var stats AllocResourceUsage
+	if err = decodeBody(resp, &stats); err != nil {
+		return nil, err
+	}
+	if c.ignoreContainer != nil {
+		for name := range stats.Tasks {
+			if c.ignoreContainer.MatchString(name) {
+				delete(stats.Tasks, name)
+			}
+		}
+	}
+	return &stats, nil
+}
+
+func (c *client) newRequest(endpoint string) (*http.Request, error) {
+	u := *c.url
+	u.Path = path.Join(c.url.Path, endpoint)
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set(tokenHeader, c.token)
+	}
+	return req, nil
+}
+
+func decodeBody(resp *http.Response, out any) error {
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, resp.Request.URL, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,136 @@
+package agentapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+const allocID = "5456bd7a-9fc0-c0dd-6131-cbee77f57577"
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tokenHeader) != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Permission denied")) // nolint
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/allocation/" + allocID:
+			http.ServeFile(w, r, "testdata/allocation.json")
+		case "/v1/client/allocation/" + allocID + "/stats":
+			http.ServeFile(w, r, "testdata/stats.json")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("alloc not found")) // nolint
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestGetAllocation(t *testing.T) {
+	ts := newServer(t)
+
+	tests := []struct {
+		allocID         string
+		token           string
+		ignoreContainer *regexp.Regexp
+		expect          []string
+		raiseError      bool
+	}{
+		{
+			allocID: allocID,
+			token:   "secret",
+			expect:  []string{"mackerel-container-agent", "nginx"},
+		},
+		{
+			allocID:         allocID,
+			token:           "secret",
+			ignoreContainer: regexp.MustCompile(`\Amackerel-container-agent\z`),
+			expect:          []string{"nginx"},
+		},
+		{
+			allocID:    allocID,
+			token:      "",
+			raiseError: true,
+		},
+		{
+			allocID:    "unknown",
+			token:      "secret",
+			raiseError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		c, err := NewClient(ts.URL, tc.allocID, tc.token, tc.ignoreContainer)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		alloc, err := c.GetAllocation(context.Background())
+		if err != nil {
+			if !tc.raiseError {
+				t.Errorf("should not raise error: %v", err)
+			}
+			continue
+		}
+		if tc.raiseError {
+			t.Errorf("should raise error")
+			continue
+		}
+		if alloc.ID != allocID {
+			t.Errorf("allocation id should be %q but got %q", allocID, alloc.ID)
+		}
+		var states, resources []string
+		for name := range alloc.TaskStates {
+			states = append(states, name)
+		}
+		for name := range alloc.AllocatedResources.Tasks {
+			resources = append(resources, name)
+		}
+		slices.Sort(states)
+		slices.Sort(resources)
+		if !slices.Equal(states, tc.expect) {
+			t.Errorf("task states should be %v but got %v", tc.expect, states)
+		}
+		if !slices.Equal(resources, tc.expect) {
+			t.Errorf("allocated resources should be %v but got %v", tc.expect, resources)
+		}
+	}
+}
+
+func TestGetAllocationStats(t *testing.T) {
+	ts := newServer(t)
+	c, err := NewClient(ts.URL, allocID, "secret", regexp.MustCompile(`\Amackerel-container-agent\z`))
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	stats, err := c.GetAllocationStats(context.Background())
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if len(stats.Tasks) != 1 {
+		t.Fatalf("stats should have 1 task but got %d", len(stats.Tasks))
+	}
+	task, ok := stats.Tasks["nginx"]
+	if !ok {
+		t.Fatalf("stats should have nginx task")
+	}
+	if got := task.ResourceUsage.CPUStats.Percent; got != 12.5 {
+		t.Errorf("unexpected cpu percent: %f", got)
+	}
+	if got := task.ResourceUsage.MemoryStats.RSS; got != 10485760 {
+		t.Errorf("unexpected memory rss: %d", got)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(DefaultAddress, "", "", nil); err == nil {
+		t.Errorf("should raise error when allocation id is empty")
+	}
+}
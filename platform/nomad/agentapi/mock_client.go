@@ -0,0 +1,64 @@
+package agentapi
+
+import (
+	"context"
+)
+
+// MockClient represents a mock client of Nomad agent API
+type MockClient struct {
+	getAllocationCallback      func(context.Context) (*Allocation, error)
+	getAllocationStatsCallback func(context.Context) (*AllocResourceUsage, error)
+}
+
+// MockClientOption represents an option of mock client of Nomad agent API
+type MockClientOption func(*MockClient)
+
+// NewMockClient creates a new mock client of Nomad agent API
+func NewMockClient(opts ...MockClientOption) *MockClient {
+	c := &MockClient{}
+	for _, o := range opts {
+		c.ApplyOption(o)
+	}
+	return c
+}
+
+// ApplyOption applies a mock client option
+func (c *MockClient) ApplyOption(opt MockClientOption) {
+	opt(c)
+}
+
+type errCallbackNotFound string
+
+func (err errCallbackNotFound) Error() string {
+	return string(err) + " callback not found"
+}
+
+// GetAllocation ...
+func (c *MockClient) GetAllocation(ctx context.Context) (*Allocation, error) {
+	if c.getAllocationCallback != nil {
+		return c.getAllocationCallback(ctx)
+	}
+	return nil, errCallbackNotFound("GetAllocation")
+}
+
+// MockGetAllocation returns an option to set the callback of GetAllocation
+func MockGetAllocation(callback func(context.Context) (*Allocation, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getAllocationCallback = callback
+	}
+}
+
+// GetAllocationStats ...
+func (c *MockClient) GetAllocationStats(ctx context.Context) (*AllocResourceUsage, error) {
+	if c.getAllocationStatsCallback != nil {
+		return c.getAllocationStatsCallback(ctx)
+	}
+	return nil, errCallbackNotFound("GetAllocationStats")
+}
+
+// MockGetAllocationStats returns an option to set the callback of GetAllocationStats
+func MockGetAllocationStats(callback func(context.Context) (*AllocResourceUsage, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getAllocationStatsCallback = callback
+	}
+}
@@ -0,0 +1,112 @@
+package agentapi
+
+// Allocation represents the response of GET /v1/allocation/:alloc_id
+type Allocation struct {
+	ID                 string
+	Name               string
+	Namespace          string
+	NodeID             string
+	NodeName           string
+	JobID              string
+	TaskGroup          string
+	ClientStatus       string
+	DesiredStatus      string
+	Job                *Job
+	AllocatedResources *AllocatedResources
+	TaskStates         map[string]*TaskState
+	CreateTime         int64
+}
+
+// Job represents the job of the allocation
+type Job struct {
+	ID          string
+	Name        string
+	Region      string
+	Datacenters []string
+	TaskGroups  []*TaskGroup
+}
+
+// TaskGroup represents the task group of the job
+type TaskGroup struct {
+	Name  string
+	Tasks []*Task
+}
+
+// Task represents the task of the task group
+type Task struct {
+	Name   string
+	Driver string
+	Config map[string]any
+}
+
+// AllocatedResources represents the resources allocated to the allocation
+type AllocatedResources struct {
+	Tasks map[string]*AllocatedTaskResources
+}
+
+// AllocatedTaskResources represents the resources allocated to the task
+type AllocatedTaskResources struct {
+	CPU    AllocatedCPUResources    `json:"Cpu"`
+	Memory AllocatedMemoryResources `json:"Memory"`
+}
+
+// AllocatedCPUResources represents the CPU resources allocated to the task
+type AllocatedCPUResources struct {
+	CPUShares     int64 `json:"CpuShares"`
+	ReservedCores []uint16
+}
+
+// AllocatedMemoryResources represents the memory resources allocated to the task
+type AllocatedMemoryResources struct {
+	MemoryMB    int64
+	MemoryMaxMB int64
+}
+
+// TaskState represents the state of the task
+type TaskState struct {
+	State      string
+	Failed     bool
+	Restarts   uint64
+	StartedAt  string
+	FinishedAt string
+}
+
+// AllocResourceUsage represents the response of GET /v1/client/allocation/:alloc_id/stats
+type AllocResourceUsage struct {
+	ResourceUsage *ResourceUsage
+	Tasks         map[string]*TaskResourceUsage
+	Timestamp     int64
+}
+
+// TaskResourceUsage represents the resource usage of the task
+type TaskResourceUsage struct {
+	ResourceUsage *ResourceUsage
+	Timestamp     int64
+}
+
+// ResourceUsage represents the CPU and memory usage
+type ResourceUsage struct {
+	MemoryStats *MemoryStats
+	CPUStats    *CPUStats `json:"CpuStats"`
+}
+
+// MemoryStats represents the memory usage
+type MemoryStats struct {
+	RSS      uint64
+	Cache    uint64
+	Swap     uint64
+	Usage    uint64
+	MaxUsage uint64
+	Measured []string
+}
+
+// CPUStats represents the CPU usage
+type CPUStats struct {
+	SystemMode       float64
+	UserMode         float64
+	TotalTicks       float64
+	ThrottledPeriods uint64
+	ThrottledTime    uint64
+	Percent          float64
+	Measured         []string
+}
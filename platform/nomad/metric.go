@@ -0,0 +1,116 @@
+package nomad
+
+import (
+	"context"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad/agentapi"
+)
+
+type metricGenerator struct {
+	client            agentapi.Client
+	hostInfoGenerator hostinfo.Generator
+	hostMemTotal      *float64
+	hostNumCores      *float64
+}
+
+func newMetricGenerator(client agentapi.Client, hostinfoGenerator hostinfo.Generator) *metricGenerator {
+	return &metricGenerator{
+		client:            client,
+		hostInfoGenerator: hostinfoGenerator,
+	}
+}
+
+// Generate generates metric values
+func (g *metricGenerator) Generate(ctx context.Context) (metric.Values, error) {
+	alloc, err := g.client.GetAllocation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := g.client.GetAllocationStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.hostMemTotal == nil || g.hostNumCores == nil {
+		memTotal, cpuCores, err := g.hostInfoGenerator.Generate()
+		if err != nil {
+			return nil, err
+		}
+		if g.hostMemTotal == nil {
+			g.hostMemTotal = &memTotal
+		}
+		if g.hostNumCores == nil {
+			g.hostNumCores = &cpuCores
+		}
+	}
+
+	metricValues := make(metric.Values)
+	for taskName, task := range stats.Tasks {
+		usage := task.ResourceUsage
+		if usage == nil {
+			continue
+		}
+		var resources *agentapi.AllocatedTaskResources
+		if alloc.AllocatedResources != nil {
+			resources = alloc.AllocatedResources.Tasks[taskName]
+		}
+
+		name := metric.SanitizeMetricKey(taskName)
+		if usage.CPUStats != nil {
+			// Nomad calculates the CPU percentage, which is 100 per core
+			metricValues["container.cpu."+name+".usage"] = usage.CPUStats.Percent
+			metricValues["container.cpu."+name+".limit"] = g.getCPULimit(resources, usage.CPUStats)
+		}
+		if usage.MemoryStats != nil {
+			metricValues["container.memory."+name+".usage"] = calculateMemoryUsage(usage.MemoryStats)
+			metricValues["container.memory."+name+".limit"] = g.getMemoryLimit(resources)
+		}
+	}
+
+	return metricValues, nil
+}
+
+// GetGraphDefs gets graph definitions
+func (g *metricGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+func calculateMemoryUsage(stats *agentapi.MemoryStats) float64 {
+	for _, m := range stats.Measured {
+		if m == "RSS" {
+			return float64(stats.RSS)
+		}
+	}
+	return float64(stats.Usage)
+}
+
+// getCPULimit returns the CPU limit in percentage.
+// The CPU resource of Nomad is allocated in MHz, so it is converted into the number of cores
+// by the MHz per core derived from the ticks and the percentage of the current usage.
+func (g *metricGenerator) getCPULimit(resources *agentapi.AllocatedTaskResources, stats *agentapi.CPUStats) float64 {
+	if resources != nil {
+		if n := len(resources.CPU.ReservedCores); n > 0 {
+			return float64(n) * 100
+		}
+		if resources.CPU.CPUShares > 0 && stats.Percent > 0 && stats.TotalTicks > 0 {
+			return float64(resources.CPU.CPUShares) * stats.Percent / stats.TotalTicks
+		}
+	}
+	return *g.hostNumCores * 100
+}
+
+func (g *metricGenerator) getMemoryLimit(resources *agentapi.AllocatedTaskResources) float64 {
+	if resources != nil {
+		if resources.Memory.MemoryMaxMB > 0 {
+			return float64(resources.Memory.MemoryMaxMB) * 1024 * 1024
+		}
+		if resources.Memory.MemoryMB > 0 {
+			return float64(resources.Memory.MemoryMB) * 1024 * 1024
+		}
+	}
+	return *g.hostMemTotal
+}
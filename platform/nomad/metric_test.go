@@ -0,0 +1,81 @@
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad/agentapi"
+)
+
+func readJSON[T any](t *testing.T, path string) *T {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+	return &v
+}
+
+func newMockClient(t *testing.T) *agentapi.MockClient {
+	alloc := readJSON[agentapi.Allocation](t, "agentapi/testdata/allocation.json")
+	stats := readJSON[agentapi.AllocResourceUsage](t, "agentapi/testdata/stats.json")
+	return agentapi.NewMockClient(
+		agentapi.MockGetAllocation(func(context.Context) (*agentapi.Allocation, error) {
+			return alloc, nil
+		}),
+		agentapi.MockGetAllocationStats(func(context.Context) (*agentapi.AllocResourceUsage, error) {
+			return stats, nil
+		}),
+	)
+}
+
+func TestGenerateMetric(t *testing.T) {
+	client := newMockClient(t)
+	generator := newMetricGenerator(client, hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	expected := metric.Values{
+		"container.cpu.nginx.usage":                       12.5,
+		"container.cpu.nginx.limit":                       20.0, // 500MHz of 2500MHz per core
+		"container.memory.nginx.usage":                    10485760.0,
+		"container.memory.nginx.limit":                    536870912.0, // memory_max 512MiB
+		"container.cpu.mackerel-container-agent.usage":    2.0,
+		"container.cpu.mackerel-container-agent.limit":    100.0, // a reserved core
+		"container.memory.mackerel-container-agent.usage": 16777216.0,
+		"container.memory.mackerel-container-agent.limit": 134217728.0, // 128MiB
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Generate() expected %v, got %v", expected, got)
+	}
+}
+
+func TestGenerateMetric_NoAllocatedResources(t *testing.T) {
+	client := newMockClient(t)
+	client.ApplyOption(agentapi.MockGetAllocation(func(context.Context) (*agentapi.Allocation, error) {
+		return &agentapi.Allocation{}, nil
+	}))
+	generator := newMetricGenerator(client, hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	if v := got["container.cpu.nginx.limit"]; v != 400.0 {
+		t.Errorf("cpu limit should be the host cores but got %f", v)
+	}
+	if v := got["container.memory.nginx.limit"]; v != 8201183232.0 {
+		t.Errorf("memory limit should be the host memory but got %f", v)
+	}
+}
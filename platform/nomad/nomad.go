@@ -0,0 +1,82 @@
+package nomad
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/mackerelio/golib/logging"
+
+	"github.com/mackerelio/mackerel-container-agent/check"
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad/agentapi"
+	"github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+var logger = logging.GetLogger("nomad")
+
+type nomadPlatform struct {
+	client     agentapi.Client
+	datacenter string
+}
+
+// NewNomadPlatform creates a new Platform
+// on this platform, agent runs as a sidecar task and monitors the tasks in the same allocation.
+func NewNomadPlatform(address, allocID, token, datacenter string, ignoreContainer *regexp.Regexp) (platform.Platform, error) {
+	c, err := agentapi.NewClient(address, allocID, token, ignoreContainer)
+	if err != nil {
+		return nil, err
+	}
+	return &nomadPlatform{client: c, datacenter: datacenter}, nil
+}
+
+// GetMetricGenerators gets metric generators
+func (p *nomadPlatform) GetMetricGenerators() []metric.Generator {
+	return []metric.Generator{
+		newMetricGenerator(p.client, hostinfo.NewGenerator()),
+		metric.NewInterfaceGenerator(),
+	}
+}
+
+// GetSpecGenerators gets spec generator
+func (p *nomadPlatform) GetSpecGenerators() []spec.Generator {
+	return []spec.Generator{
+		newSpecGenerator(p.client, p.datacenter),
+		&spec.CPUGenerator{},
+	}
+}
+
+// GetCheckGenerators gets check generators
+func (p *nomadPlatform) GetCheckGenerators() []check.Generator {
+	return nil
+}
+
+// GetCustomIdentifier gets custom identifier
+func (p *nomadPlatform) GetCustomIdentifier(ctx context.Context) (string, error) {
+	alloc, err := p.client.GetAllocation(ctx)
+	if err != nil {
+		return "", err
+	}
+	return alloc.ID + ".nomad", nil
+}
+
+// StatusRunning reports p status is running
+func (p *nomadPlatform) StatusRunning(ctx context.Context) bool {
+	alloc, err := p.client.GetAllocation(ctx)
+	if err != nil {
+		logger.Warningf("failed to get allocation: %s", err)
+		return false
+	}
+	return alloc.ClientStatus == "running"
+}
+
+// StatusStopping reports p status is stopping
+func (p *nomadPlatform) StatusStopping(ctx context.Context) bool {
+	alloc, err := p.client.GetAllocation(ctx)
+	if err != nil {
+		logger.Warningf("failed to get allocation: %s", err)
+		return false
+	}
+	return alloc.DesiredStatus == "stop" || alloc.DesiredStatus == "evict"
+}
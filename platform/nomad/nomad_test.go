@@ -0,0 +1,82 @@
+package nomad
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad/agentapi"
+)
+
+func TestGetCustomIdentifier(t *testing.T) {
+	pform := nomadPlatform{client: newMockClient(t)}
+
+	got, err := pform.GetCustomIdentifier(context.Background())
+	if err != nil {
+		t.Fatalf("GetCustomIdentifier() should not raise error: %v", err)
+	}
+	expected := "5456bd7a-9fc0-c0dd-6131-cbee77f57577.nomad"
+	if got != expected {
+		t.Errorf("GetCustomIdentifier() expected %q, got %q", expected, got)
+	}
+}
+
+func TestStatusRunning(t *testing.T) {
+	mockClient := agentapi.NewMockClient()
+	pform := nomadPlatform{client: mockClient}
+
+	tests := []struct {
+		alloc  *agentapi.Allocation
+		err    error
+		expect bool
+	}{
+		{&agentapi.Allocation{ClientStatus: "running"}, nil, true},
+		{&agentapi.Allocation{ClientStatus: "pending"}, nil, false},
+		{&agentapi.Allocation{ClientStatus: "complete"}, nil, false},
+		{nil, errors.New("alloc not found"), false},
+	}
+
+	for _, tc := range tests {
+		mockClient.ApplyOption(
+			agentapi.MockGetAllocation(
+				func(context.Context) (*agentapi.Allocation, error) {
+					return tc.alloc, tc.err
+				},
+			),
+		)
+
+		got := pform.StatusRunning(context.Background())
+		if got != tc.expect {
+			t.Errorf("StatusRunning() expected %t, got %t", tc.expect, got)
+		}
+	}
+}
+
+func TestStatusStopping(t *testing.T) {
+	mockClient := agentapi.NewMockClient()
+	pform := nomadPlatform{client: mockClient}
+
+	tests := []struct {
+		desiredStatus string
+		expect        bool
+	}{
+		{"run", false},
+		{"stop", true},
+		{"evict", true},
+	}
+
+	for _, tc := range tests {
+		mockClient.ApplyOption(
+			agentapi.MockGetAllocation(
+				func(context.Context) (*agentapi.Allocation, error) {
+					return &agentapi.Allocation{DesiredStatus: tc.desiredStatus}, nil
+				},
+			),
+		)
+
+		got := pform.StatusStopping(context.Background())
+		if got != tc.expect {
+			t.Errorf("StatusStopping() expected %t, got %t", tc.expect, got)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package nomad
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/nomad/agentapi"
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+type specGenerator struct {
+	client     agentapi.Client
+	datacenter string
+}
+
+func newSpecGenerator(client agentapi.Client, datacenter string) *specGenerator {
+	return &specGenerator{
+		client:     client,
+		datacenter: datacenter,
+	}
+}
+
+func (g *specGenerator) Generate(ctx context.Context) (any, error) {
+	alloc, err := g.client.GetAllocation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &allocationSpec{
+		ID:            alloc.ID,
+		Name:          alloc.Name,
+		Namespace:     alloc.Namespace,
+		Job:           alloc.JobID,
+		Group:         alloc.TaskGroup,
+		Datacenter:    g.datacenter,
+		NodeID:        alloc.NodeID,
+		NodeName:      alloc.NodeName,
+		ClientStatus:  alloc.ClientStatus,
+		DesiredStatus: alloc.DesiredStatus,
+	}
+	if alloc.CreateTime > 0 {
+		createdAt := time.Unix(0, alloc.CreateTime).UTC()
+		spec.CreatedAt = &createdAt
+	}
+
+	tasks := make(map[string]*agentapi.Task)
+	if job := alloc.Job; job != nil {
+		spec.Region = job.Region
+		if spec.Datacenter == "" && len(job.Datacenters) == 1 {
+			spec.Datacenter = job.Datacenters[0]
+		}
+		for _, tg := range job.TaskGroups {
+			if tg.Name != alloc.TaskGroup {
+				continue
+			}
+			for _, t := range tg.Tasks {
+				tasks[t.Name] = t
+			}
+		}
+	}
+
+	names := make([]string, 0, len(alloc.TaskStates))
+	for name := range alloc.TaskStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		spec.Tasks = append(spec.Tasks, generateTaskSpec(name, alloc, tasks[name]))
+	}
+
+	return &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: string(platform.Nomad),
+			MetaData: spec,
+		},
+		Hostname: alloc.Name,
+	}, nil
+}
+
+func generateTaskSpec(name string, alloc *agentapi.Allocation, task *agentapi.Task) taskSpec {
+	spec := taskSpec{
+		Name: name,
+	}
+
+	if task != nil {
+		spec.Driver = task.Driver
+		if image, ok := task.Config["image"].(string); ok {
+			spec.Image = image
+		}
+	}
+
+	if s := alloc.TaskStates[name]; s != nil {
+		spec.State = s.State
+		spec.Failed = s.Failed
+		spec.Restarts = s.Restarts
+		if t, err := time.Parse(time.RFC3339Nano, s.StartedAt); err == nil && !t.IsZero() {
+			spec.StartedAt = &t
+		}
+	}
+
+	if alloc.AllocatedResources != nil {
+		if r := alloc.AllocatedResources.Tasks[name]; r != nil {
+			if len(r.CPU.ReservedCores) > 0 {
+				spec.Limits.Cores = r.CPU.ReservedCores
+			} else if r.CPU.CPUShares > 0 {
+				cpu := r.CPU.CPUShares
+				spec.Limits.CPU = &cpu
+			}
+			if r.Memory.MemoryMB > 0 {
+				memory := r.Memory.MemoryMB * 1024 * 1024
+				spec.Limits.Memory = &memory
+			}
+			if r.Memory.MemoryMaxMB > 0 {
+				memoryMax := r.Memory.MemoryMaxMB * 1024 * 1024
+				spec.Limits.MemoryMax = &memoryMax
+			}
+		}
+	}
+
+	return spec
+}
@@ -0,0 +1,86 @@
+package nomad
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+func TestGenerateSpec(t *testing.T) {
+	client := newMockClient(t)
+	generator := newSpecGenerator(client, "")
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+
+	cpu := int64(500)
+	nginxMemory := int64(268435456)
+	nginxMemoryMax := int64(536870912)
+	agentMemory := int64(134217728)
+	createdAt := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	nginxStartedAt := time.Date(2026, time.October, 1, 0, 0, 1, 123456789, time.UTC)
+	agentStartedAt := time.Date(2026, time.October, 1, 0, 0, 2, 123456789, time.UTC)
+	expected := &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: "nomad",
+			MetaData: &allocationSpec{
+				ID:            "5456bd7a-9fc0-c0dd-6131-cbee77f57577",
+				Name:          "myapp.web[0]",
+				Namespace:     "default",
+				Job:           "myapp",
+				Group:         "web",
+				Datacenter:    "dc1",
+				Region:        "global",
+				NodeID:        "fb2170a8-257d-3c64-b14d-bc06cc94e34c",
+				NodeName:      "nomad-client-1",
+				ClientStatus:  "running",
+				DesiredStatus: "run",
+				CreatedAt:     &createdAt,
+				Tasks: []taskSpec{
+					{
+						Name:      "mackerel-container-agent",
+						Driver:    "docker",
+						Image:     "mackerel/mackerel-container-agent:latest",
+						State:     "running",
+						StartedAt: &agentStartedAt,
+						Limits:    limitSpec{Cores: []uint16{1}, Memory: &agentMemory},
+					},
+					{
+						Name:      "nginx",
+						Driver:    "docker",
+						Image:     "nginx:1.27",
+						State:     "running",
+						Restarts:  1,
+						StartedAt: &nginxStartedAt,
+						Limits:    limitSpec{CPU: &cpu, Memory: &nginxMemory, MemoryMax: &nginxMemoryMax},
+					},
+				},
+			},
+		},
+		Hostname: "myapp.web[0]",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Generate() expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestGenerateSpec_Datacenter(t *testing.T) {
+	client := newMockClient(t)
+	generator := newSpecGenerator(client, "dc2")
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	spec := got.(*agentSpec.CloudHostname).Cloud.MetaData.(*allocationSpec)
+	if spec.Datacenter != "dc2" {
+		t.Errorf("datacenter should be %q but got %q", "dc2", spec.Datacenter)
+	}
+}
@@ -0,0 +1,37 @@
+package nomad
+
+import "time"
+
+type allocationSpec struct {
+	ID            string     `json:"id,omitempty"`
+	Name          string     `json:"name,omitempty"`
+	Namespace     string     `json:"namespace,omitempty"`
+	Job           string     `json:"job,omitempty"`
+	Group         string     `json:"group,omitempty"`
+	Datacenter    string     `json:"datacenter,omitempty"`
+	Region        string     `json:"region,omitempty"`
+	NodeID        string     `json:"node_id,omitempty"`
+	NodeName      string     `json:"node_name,omitempty"`
+	ClientStatus  string     `json:"client_status,omitempty"`
+	DesiredStatus string     `json:"desired_status,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	Tasks         []taskSpec `json:"tasks,omitempty"`
+}
+
+type taskSpec struct {
+	Name      string     `json:"name,omitempty"`
+	Driver    string     `json:"driver,omitempty"`
+	Image     string     `json:"image,omitempty"`
+	State     string     `json:"state,omitempty"`
+	Failed    bool       `json:"failed,omitempty"`
+	Restarts  uint64     `json:"restarts,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Limits    limitSpec  `json:"limits"`
+}
+
+type limitSpec struct {
+	CPU       *int64   `json:"cpu,omitempty"` // MHz
+	Cores     []uint16 `json:"cores,omitempty"`
+	Memory    *int64   `json:"memory,omitempty"`
+	MemoryMax *int64   `json:"memory_max,omitempty"`
+}
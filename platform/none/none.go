@@ -3,6 +3,7 @@ package none
 import (
 	"context"
 
+	"github.com/mackerelio/mackerel-container-agent/check"
 	"github.com/mackerelio/mackerel-container-agent/metric"
 	"github.com/mackerelio/mackerel-container-agent/platform"
 	"github.com/mackerelio/mackerel-container-agent/spec"
@@ -23,6 +24,10 @@ func (p *nonePlatform) GetSpecGenerators() []spec.Generator {
 	return []spec.Generator{}
 }
 
+func (p *nonePlatform) GetCheckGenerators() []check.Generator {
+	return []check.Generator{}
+}
+
 func (p *nonePlatform) GetCustomIdentifier(context.Context) (string, error) {
 	return "", nil
 }
@@ -30,3 +35,7 @@ func (p *nonePlatform) GetCustomIdentifier(context.Context) (string, error) {
 func (p *nonePlatform) StatusRunning(context.Context) bool {
 	return true
 }
+
+func (p *nonePlatform) StatusStopping(context.Context) bool {
+	return false
+}
@@ -3,6 +3,7 @@ package platform
 import (
 	"context"
 
+	"github.com/mackerelio/mackerel-container-agent/check"
 	"github.com/mackerelio/mackerel-container-agent/metric"
 	"github.com/mackerelio/mackerel-container-agent/spec"
 )
@@ -11,6 +12,8 @@ import (
 type Platform interface {
 	GetMetricGenerators() []metric.Generator
 	GetSpecGenerators() []spec.Generator
+	GetCheckGenerators() []check.Generator
 	GetCustomIdentifier(context.Context) (string, error)
 	StatusRunning(context.Context) bool
+	StatusStopping(context.Context) bool
 }
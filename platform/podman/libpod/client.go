@@ -0,0 +1,173 @@
+package libpod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Client interface gets the pod, its containers and their stats from Podman REST API
+type Client interface {
+	GetPod(context.Context) (*PodInspect, error)
+	GetContainers(context.Context) ([]*ContainerInspect, error)
+	GetContainerStats(context.Context, []string) (map[string]*ContainerStats, error)
+}
+
+const (
+	// DefaultSocketPath represents the default path of Podman REST API socket
+	DefaultSocketPath = "/run/podman/podman.sock"
+
+	apiPrefix      = "/v4.0.0/libpod"
+	podsPath       = "/pods"
+	containersPath = "/containers"
+)
+
+var timeout = 3 * time.Second
+
+type client struct {
+	url             *url.URL
+	httpClient      *http.Client
+	podName         string
+	ignoreContainer *regexp.Regexp
+}
+
+// NewClient creates a new Client which connects to the unix socket
+func NewClient(socketPath, podName string, ignoreContainer *regexp.Regexp) (Client, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("socket path should not be empty")
+	}
+	dialer := &net.Dialer{}
+	return &client{
+		url: &url.URL{Scheme: "http", Host: "podman"},
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy: nil,
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+		podName:         podName,
+		ignoreContainer: ignoreContainer,
+	}, nil
+}
+
+// GetPod gets the pod
+func (c *client) GetPod(ctx context.Context) (*PodInspect, error) {
+	req, err := c.newRequest(path.Join(podsPath, c.podName, "json"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var pod PodInspect
+	if err = decodeBody(resp, &pod); err != nil {
+		return nil, err
+	}
+	return &pod, nil
+}
+
+// GetContainers gets the containers in the pod except the infra container
+func (c *client) GetContainers(ctx context.Context) ([]*ContainerInspect, error) {
+	pod, err := c.GetPod(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]*ContainerInspect, 0, len(pod.Containers))
+	for _, pc := range pod.Containers {
+		if pc.ID == pod.InfraContainerID {
+			continue
+		}
+		if c.ignoreContainer != nil && c.ignoreContainer.MatchString(pc.Name) {
+			continue
+		}
+		container, err := c.getContainer(ctx, pc.ID)
+		if err != nil {
+			return nil, err
+		}
+		if container.IsInfra {
+			continue
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+func (c *client) getContainer(ctx context.Context, id string) (*ContainerInspect, error) {
+	req, err := c.newRequest(path.Join(containersPath, id, "json"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var container ContainerInspect
+	if err = decodeBody(resp, &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// GetContainerStats gets the stats of the containers keyed by container id
+func (c *client) GetContainerStats(ctx context.Context, ids []string) (map[string]*ContainerStats, error) {
+	res := make(map[string]*ContainerStats, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	req, err := c.newRequest(path.Join(containersPath, "stats"), url.Values{
+		"containers": ids,
+		"stream":     {"false"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var report containerStatsReport
+	if err = decodeBody(resp, &report); err != nil {
+		return nil, err
+	}
+	if report.Error != nil {
+		return nil, fmt.Errorf("failed to get container stats: %v", report.Error)
+	}
+	for _, s := range report.Stats {
+		res[s.ContainerID] = &s
+	}
+	return res, nil
+}
+
+// ContainerName returns the container name without the leading slash
+func ContainerName(container *ContainerInspect) string {
+	return strings.TrimPrefix(container.Name, "/")
+}
+
+func (c *client) newRequest(endpoint string, query url.Values) (*http.Request, error) {
+	u := *c.url
+	u.Path = path.Join(apiPrefix, endpoint)
+	u.RawQuery = query.Encode()
+	return http.NewRequest("GET", u.String(), nil)
+}
+
+func decodeBody(resp *http.Response, out any) error {
+	defer resp.Body.Close() // nolint
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("got status code %d (url: %s, body: %q)", resp.StatusCode, resp.Request.URL, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
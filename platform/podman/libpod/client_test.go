@@ -0,0 +1,147 @@
+package libpod
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+const (
+	webContainerID   = "4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f"
+	agentContainerID = "8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b"
+)
+
+func newServer(t *testing.T) string {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "podman.sock")
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case apiPrefix + "/pods/myapp/json":
+			http.ServeFile(w, r, "testdata/pod.json")
+		case apiPrefix + "/containers/" + webContainerID + "/json":
+			http.ServeFile(w, r, "testdata/container_web.json")
+		case apiPrefix + "/containers/" + agentContainerID + "/json":
+			http.ServeFile(w, r, "testdata/container_agent.json")
+		case apiPrefix + "/containers/stats":
+			query := r.URL.Query()
+			if query.Get("stream") != "false" || !slices.Equal(query["containers"], []string{webContainerID, agentContainerID}) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			http.ServeFile(w, r, "testdata/stats.json")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cause":"no such pod","message":"no such pod","response":404}`)) // nolint
+		}
+	}))
+	ts.Listener.Close() // nolint
+	ts.Listener = l
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return socketPath
+}
+
+func TestGetPod(t *testing.T) {
+	socketPath := newServer(t)
+
+	tests := []struct {
+		podName    string
+		state      string
+		raiseError bool
+	}{
+		{"myapp", "Running", false},
+		{"unknown", "", true},
+	}
+
+	for _, tc := range tests {
+		c, err := NewClient(socketPath, tc.podName, nil)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		pod, err := c.GetPod(context.Background())
+		if err != nil {
+			if !tc.raiseError {
+				t.Errorf("should not raise error: %v", err)
+			}
+			continue
+		}
+		if tc.raiseError {
+			t.Errorf("should raise error")
+			continue
+		}
+		if pod.State != tc.state {
+			t.Errorf("pod state should be %q but got %q", tc.state, pod.State)
+		}
+	}
+}
+
+func TestGetContainers(t *testing.T) {
+	socketPath := newServer(t)
+
+	tests := []struct {
+		ignoreContainer *regexp.Regexp
+		expect          []string
+	}{
+		{
+			expect: []string{"myapp-web", "myapp-mackerel-container-agent"},
+		},
+		{
+			ignoreContainer: regexp.MustCompile(`\Amyapp-mackerel-container-agent\z`),
+			expect:          []string{"myapp-web"},
+		},
+	}
+
+	for _, tc := range tests {
+		c, err := NewClient(socketPath, "myapp", tc.ignoreContainer)
+		if err != nil {
+			t.Fatalf("should not raise error: %v", err)
+		}
+		containers, err := c.GetContainers(context.Background())
+		if err != nil {
+			t.Errorf("should not raise error: %v", err)
+		}
+		names := make([]string, len(containers))
+		for i, c := range containers {
+			names[i] = ContainerName(c)
+		}
+		if !reflect.DeepEqual(names, tc.expect) {
+			t.Errorf("containers should be %v but got %v", tc.expect, names)
+		}
+	}
+}
+
+func TestGetContainerStats(t *testing.T) {
+	socketPath := newServer(t)
+	c, err := NewClient(socketPath, "myapp", nil)
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+
+	stats, err := c.GetContainerStats(context.Background(), []string{webContainerID, agentContainerID})
+	if err != nil {
+		t.Fatalf("should not raise error: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats should have 2 containers but got %d", len(stats))
+	}
+	if got := stats[webContainerID].CPUNano; got != 16094570000 {
+		t.Errorf("unexpected cpu usage: %d", got)
+	}
+	if got := stats[agentContainerID].MemUsage; got != 16777216 {
+		t.Errorf("unexpected memory usage: %d", got)
+	}
+
+	if _, err := c.GetContainerStats(context.Background(), []string{"unknown"}); err == nil {
+		t.Errorf("should raise error")
+	}
+}
@@ -0,0 +1,80 @@
+package libpod
+
+import (
+	"context"
+)
+
+// MockClient represents a mock client of Podman REST API
+type MockClient struct {
+	getPodCallback            func(context.Context) (*PodInspect, error)
+	getContainersCallback     func(context.Context) ([]*ContainerInspect, error)
+	getContainerStatsCallback func(context.Context, []string) (map[string]*ContainerStats, error)
+}
+
+// MockClientOption represents an option of mock client of Podman REST API
+type MockClientOption func(*MockClient)
+
+// NewMockClient creates a new mock client of Podman REST API
+func NewMockClient(opts ...MockClientOption) *MockClient {
+	c := &MockClient{}
+	for _, o := range opts {
+		c.ApplyOption(o)
+	}
+	return c
+}
+
+// ApplyOption applies a mock client option
+func (c *MockClient) ApplyOption(opt MockClientOption) {
+	opt(c)
+}
+
+type errCallbackNotFound string
+
+func (err errCallbackNotFound) Error() string {
+	return string(err) + " callback not found"
+}
+
+// GetPod ...
+func (c *MockClient) GetPod(ctx context.Context) (*PodInspect, error) {
+	if c.getPodCallback != nil {
+		return c.getPodCallback(ctx)
+	}
+	return nil, errCallbackNotFound("GetPod")
+}
+
+// MockGetPod returns an option to set the callback of GetPod
+func MockGetPod(callback func(context.Context) (*PodInspect, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getPodCallback = callback
+	}
+}
+
+// GetContainers ...
+func (c *MockClient) GetContainers(ctx context.Context) ([]*ContainerInspect, error) {
+	if c.getContainersCallback != nil {
+		return c.getContainersCallback(ctx)
+	}
+	return nil, errCallbackNotFound("GetContainers")
+}
+
+// MockGetContainers returns an option to set the callback of GetContainers
+func MockGetContainers(callback func(context.Context) ([]*ContainerInspect, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getContainersCallback = callback
+	}
+}
+
+// GetContainerStats ...
+func (c *MockClient) GetContainerStats(ctx context.Context, ids []string) (map[string]*ContainerStats, error) {
+	if c.getContainerStatsCallback != nil {
+		return c.getContainerStatsCallback(ctx, ids)
+	}
+	return nil, errCallbackNotFound("GetContainerStats")
+}
+
+// MockGetContainerStats returns an option to set the callback of GetContainerStats
+func MockGetContainerStats(callback func(context.Context, []string) (map[string]*ContainerStats, error)) MockClientOption {
+	return func(c *MockClient) {
+		c.getContainerStatsCallback = callback
+	}
+}
@@ -0,0 +1,84 @@
+package libpod
+
+import "time"
+
+// PodInspect represents the response of GET /libpod/pods/{name}/json
+type PodInspect struct {
+	ID               string `json:"Id"`
+	Name             string
+	Namespace        string
+	Created          time.Time
+	State            string
+	Hostname         string
+	Labels           map[string]string
+	InfraContainerID string `json:"InfraContainerID"`
+	Containers       []PodContainer
+}
+
+// PodContainer represents a container in the pod
+type PodContainer struct {
+	ID    string `json:"Id"`
+	Name  string
+	State string
+}
+
+// ContainerInspect represents the response of GET /libpod/containers/{name}/json
+type ContainerInspect struct {
+	ID           string `json:"Id"`
+	Name         string
+	Created      time.Time
+	Image        string
+	ImageName    string
+	State        *ContainerState
+	Config       *ContainerConfig
+	HostConfig   *HostConfig
+	Pod          string
+	IsInfra      bool
+	RestartCount int32
+}
+
+// ContainerState represents the state of the container
+type ContainerState struct {
+	Status    string
+	Running   bool
+	StartedAt time.Time
+	Health    *HealthCheckResults `json:"Health,omitempty"`
+}
+
+// HealthCheckResults represents the result of the container health check
+type HealthCheckResults struct {
+	Status string
+}
+
+// ContainerConfig represents the configuration of the container
+type ContainerConfig struct {
+	Image  string
+	Labels map[string]string
+}
+
+// HostConfig represents the resource limits of the container
+type HostConfig struct {
+	Memory    int64
+	NanoCPUs  int64 `json:"NanoCpus"`
+	CPUPeriod int64 `json:"CpuPeriod"`
+	CPUQuota  int64 `json:"CpuQuota"`
+}
+
+// ContainerStats represents the stats of the container
+type ContainerStats struct {
+	ContainerID string
+	Name        string
+	CPUNano     uint64
+	MemUsage    uint64
+	MemLimit    uint64
+	NetInput    uint64
+	NetOutput   uint64
+	BlockInput  uint64
+	BlockOutput uint64
+	PIDs        uint64
+}
+
+type containerStatsReport struct {
+	Error any
+	Stats []ContainerStats
+}
@@ -0,0 +1,123 @@
+package podman
+
+import (
+	"context"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/podman/libpod"
+)
+
+type metricGenerator struct {
+	client            libpod.Client
+	hostInfoGenerator hostinfo.Generator
+	hostMemTotal      *float64
+	hostNumCores      *float64
+	prevStats         map[string]*libpod.ContainerStats
+	prevTime          time.Time
+}
+
+func newMetricGenerator(client libpod.Client, hostinfoGenerator hostinfo.Generator) *metricGenerator {
+	return &metricGenerator{
+		client:            client,
+		hostInfoGenerator: hostinfoGenerator,
+	}
+}
+
+// Generate generates metric values
+func (g *metricGenerator) Generate(ctx context.Context) (metric.Values, error) {
+	containers, err := g.client.GetContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(containers))
+	for i, c := range containers {
+		ids[i] = c.ID
+	}
+	stats, err := g.client.GetContainerStats(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.hostMemTotal == nil || g.hostNumCores == nil {
+		memTotal, cpuCores, err := g.hostInfoGenerator.Generate()
+		if err != nil {
+			return nil, err
+		}
+		if g.hostMemTotal == nil {
+			g.hostMemTotal = &memTotal
+		}
+		if g.hostNumCores == nil {
+			g.hostNumCores = &cpuCores
+		}
+	}
+
+	now := time.Now()
+	if g.prevStats == nil || g.prevTime.Before(now.Add(-10*time.Minute)) {
+		g.prevStats = stats
+		g.prevTime = now
+		return nil, nil
+	}
+
+	timeDelta := now.Sub(g.prevTime)
+	metricValues := make(metric.Values)
+	for _, c := range containers {
+		prev, ok := g.prevStats[c.ID]
+		if !ok {
+			continue
+		}
+		curr, ok := stats[c.ID]
+		if !ok {
+			continue
+		}
+
+		name := metric.SanitizeMetricKey(libpod.ContainerName(c))
+		if v, ok := calculateCPUMetrics(prev, curr, timeDelta); ok {
+			metricValues["container.cpu."+name+".usage"] = v
+		}
+		metricValues["container.cpu."+name+".limit"] = g.getCPULimit(c)
+		metricValues["container.memory."+name+".usage"] = float64(curr.MemUsage)
+		metricValues["container.memory."+name+".limit"] = g.getMemoryLimit(c)
+	}
+
+	g.prevStats = stats
+	g.prevTime = now
+
+	return metricValues, nil
+}
+
+// GetGraphDefs gets graph definitions
+func (g *metricGenerator) GetGraphDefs(context.Context) ([]*mackerel.GraphDefsParam, error) {
+	return nil, nil
+}
+
+// calculateCPUMetrics reports false when the counter is reset by the restart of the container
+func calculateCPUMetrics(prev, curr *libpod.ContainerStats, timeDelta time.Duration) (float64, bool) {
+	if curr.CPUNano < prev.CPUNano {
+		return 0.0, false
+	}
+	return float64(curr.CPUNano-prev.CPUNano) / float64(timeDelta.Nanoseconds()) * 100, true
+}
+
+func (g *metricGenerator) getCPULimit(c *libpod.ContainerInspect) float64 {
+	if hc := c.HostConfig; hc != nil {
+		if hc.NanoCPUs > 0 {
+			return float64(hc.NanoCPUs) / 1e9 * 100
+		}
+		if hc.CPUQuota > 0 && hc.CPUPeriod > 0 {
+			return float64(hc.CPUQuota) / float64(hc.CPUPeriod) * 100
+		}
+	}
+	return *g.hostNumCores * 100
+}
+
+func (g *metricGenerator) getMemoryLimit(c *libpod.ContainerInspect) float64 {
+	if hc := c.HostConfig; hc != nil && hc.Memory > 0 {
+		return float64(hc.Memory)
+	}
+	return *g.hostMemTotal
+}
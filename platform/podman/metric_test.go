@@ -0,0 +1,108 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform/podman/libpod"
+)
+
+func readJSON[T any](t *testing.T, path string) *T {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+	return &v
+}
+
+func newMockClient(t *testing.T) *libpod.MockClient {
+	pod := readJSON[libpod.PodInspect](t, "libpod/testdata/pod.json")
+	containers := []*libpod.ContainerInspect{
+		readJSON[libpod.ContainerInspect](t, "libpod/testdata/container_web.json"),
+		readJSON[libpod.ContainerInspect](t, "libpod/testdata/container_agent.json"),
+	}
+	report := readJSON[struct{ Stats []libpod.ContainerStats }](t, "libpod/testdata/stats.json")
+	return libpod.NewMockClient(
+		libpod.MockGetPod(func(context.Context) (*libpod.PodInspect, error) {
+			return pod, nil
+		}),
+		libpod.MockGetContainers(func(context.Context) ([]*libpod.ContainerInspect, error) {
+			return containers, nil
+		}),
+		libpod.MockGetContainerStats(func(_ context.Context, ids []string) (map[string]*libpod.ContainerStats, error) {
+			stats := make(map[string]*libpod.ContainerStats, len(ids))
+			for _, s := range report.Stats {
+				stats[s.ContainerID] = &s
+			}
+			return stats, nil
+		}),
+	)
+}
+
+func TestGenerateMetric(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient(t)
+	generator := newMetricGenerator(client, hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	got, err := generator.Generate(ctx) // Store metrics to generator.prevStats.
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Generate() should return nil at first but got %v", got)
+	}
+
+	got, err = generator.Generate(ctx)
+	if err != nil {
+		t.Errorf("Generate() should not raise error: %v", err)
+	}
+	expected := metric.Values{
+		"container.cpu.myapp-web.usage":                         0.0, // Result is 0 because use the same data.
+		"container.cpu.myapp-web.limit":                         50.0,
+		"container.memory.myapp-web.usage":                      1.048576e+07,
+		"container.memory.myapp-web.limit":                      268435456.0, // 256MiB
+		"container.cpu.myapp-mackerel-container-agent.usage":    0.0,
+		"container.cpu.myapp-mackerel-container-agent.limit":    25.0,
+		"container.memory.myapp-mackerel-container-agent.usage": 1.6777216e+07,
+		"container.memory.myapp-mackerel-container-agent.limit": 8201183232.0, // mockMemTotal
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Generate() expected %v, got %v", expected, got)
+	}
+}
+
+func TestGenerateMetric_Reset(t *testing.T) {
+	ctx := context.Background()
+	client := newMockClient(t)
+	generator := newMetricGenerator(client, hostinfo.NewMockGenerator(8201183232.0, 4.0, nil))
+
+	if _, err := generator.Generate(ctx); err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	for _, s := range generator.prevStats {
+		if s.Name == "myapp-web" {
+			s.CPUNano += 1_000_000_000_000 // the counter is reset by the restart
+		}
+	}
+
+	got, err := generator.Generate(ctx)
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+	if v, ok := got["container.cpu.myapp-web.usage"]; ok {
+		t.Errorf("container.cpu.myapp-web.usage should be skipped but got %f", v)
+	}
+	if _, ok := got["container.cpu.myapp-mackerel-container-agent.usage"]; !ok {
+		t.Errorf("container.cpu.myapp-mackerel-container-agent.usage should not be skipped")
+	}
+}
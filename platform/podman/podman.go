@@ -0,0 +1,75 @@
+package podman
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/mackerelio/golib/logging"
+
+	"github.com/mackerelio/mackerel-container-agent/check"
+	"github.com/mackerelio/mackerel-container-agent/metric"
+	"github.com/mackerelio/mackerel-container-agent/metric/hostinfo"
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/podman/libpod"
+	"github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+var logger = logging.GetLogger("podman")
+
+type podmanPlatform struct {
+	client libpod.Client
+}
+
+// NewPodmanPlatform creates a new Platform
+// on this platform, agent runs in the pod and monitors the other containers in the same pod.
+func NewPodmanPlatform(socketPath, podName string, ignoreContainer *regexp.Regexp) (platform.Platform, error) {
+	c, err := libpod.NewClient(socketPath, podName, ignoreContainer)
+	if err != nil {
+		return nil, err
+	}
+	return &podmanPlatform{client: c}, nil
+}
+
+// GetMetricGenerators gets metric generators
+func (p *podmanPlatform) GetMetricGenerators() []metric.Generator {
+	return []metric.Generator{
+		newMetricGenerator(p.client, hostinfo.NewGenerator()),
+		metric.NewInterfaceGenerator(),
+	}
+}
+
+// GetSpecGenerators gets spec generator
+func (p *podmanPlatform) GetSpecGenerators() []spec.Generator {
+	return []spec.Generator{
+		newSpecGenerator(p.client),
+		&spec.CPUGenerator{},
+	}
+}
+
+// GetCheckGenerators gets check generators
+func (p *podmanPlatform) GetCheckGenerators() []check.Generator {
+	return nil
+}
+
+// GetCustomIdentifier gets custom identifier
+func (p *podmanPlatform) GetCustomIdentifier(context.Context) (string, error) {
+	return "", nil
+}
+
+// StatusRunning reports p status is running
+func (p *podmanPlatform) StatusRunning(ctx context.Context) bool {
+	pod, err := p.client.GetPod(ctx)
+	if err != nil {
+		logger.Warningf("failed to get pod: %s", err)
+		return false
+	}
+	// a pod is degraded when some of its containers are not running
+	return strings.EqualFold(pod.State, "Running") || strings.EqualFold(pod.State, "Degraded")
+}
+
+// StatusStopping reports p status is stopping
+// Podman REST API does not tell whether the pod is being stopped.
+func (p *podmanPlatform) StatusStopping(context.Context) bool {
+	return false
+}
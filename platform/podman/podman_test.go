@@ -0,0 +1,41 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mackerelio/mackerel-container-agent/platform/podman/libpod"
+)
+
+func TestStatusRunning(t *testing.T) {
+	mockClient := libpod.NewMockClient()
+	pform := podmanPlatform{client: mockClient}
+
+	tests := []struct {
+		pod    *libpod.PodInspect
+		err    error
+		expect bool
+	}{
+		{&libpod.PodInspect{State: "Running"}, nil, true},
+		{&libpod.PodInspect{State: "Degraded"}, nil, true},
+		{&libpod.PodInspect{State: "Created"}, nil, false},
+		{&libpod.PodInspect{State: "Exited"}, nil, false},
+		{nil, errors.New("no such pod"), false},
+	}
+
+	for _, tc := range tests {
+		mockClient.ApplyOption(
+			libpod.MockGetPod(
+				func(context.Context) (*libpod.PodInspect, error) {
+					return tc.pod, tc.err
+				},
+			),
+		)
+
+		got := pform.StatusRunning(context.Background())
+		if got != tc.expect {
+			t.Errorf("StatusRunning() expected %t, got %t", tc.expect, got)
+		}
+	}
+}
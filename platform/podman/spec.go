@@ -0,0 +1,99 @@
+package podman
+
+import (
+	"context"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	"github.com/mackerelio/mackerel-container-agent/platform"
+	"github.com/mackerelio/mackerel-container-agent/platform/podman/libpod"
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+type specGenerator struct {
+	client libpod.Client
+}
+
+func newSpecGenerator(client libpod.Client) *specGenerator {
+	return &specGenerator{
+		client: client,
+	}
+}
+
+func (g *specGenerator) Generate(ctx context.Context) (any, error) {
+	pod, err := g.client.GetPod(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := g.client.GetContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &podSpec{
+		ID:       pod.ID,
+		Name:     pod.Name,
+		State:    pod.State,
+		Hostname: pod.Hostname,
+		Labels:   pod.Labels,
+	}
+	if !pod.Created.IsZero() {
+		created := pod.Created
+		spec.Created = &created
+	}
+	if len(containers) > 0 {
+		spec.Containers = make([]containerSpec, len(containers))
+		for i, c := range containers {
+			spec.Containers[i] = generateContainerSpec(c)
+		}
+	}
+
+	return &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: string(platform.Podman),
+			MetaData: spec,
+		},
+		Hostname: pod.Name,
+	}, nil
+}
+
+func generateContainerSpec(c *libpod.ContainerInspect) containerSpec {
+	spec := containerSpec{
+		ID:           c.ID,
+		Name:         libpod.ContainerName(c),
+		Image:        c.ImageName,
+		ImageID:      c.Image,
+		RestartCount: c.RestartCount,
+	}
+
+	if c.Config != nil {
+		spec.Labels = c.Config.Labels
+	}
+
+	if s := c.State; s != nil {
+		spec.Status = s.Status
+		if s.Health != nil {
+			spec.Health = s.Health.Status
+		}
+		if !s.StartedAt.IsZero() {
+			startedAt := s.StartedAt
+			spec.StartedAt = &startedAt
+		}
+	}
+
+	if hc := c.HostConfig; hc != nil {
+		if hc.NanoCPUs > 0 {
+			cpu := float64(hc.NanoCPUs) / 1e9
+			spec.Limits.CPU = &cpu
+		} else if hc.CPUQuota > 0 && hc.CPUPeriod > 0 {
+			cpu := float64(hc.CPUQuota) / float64(hc.CPUPeriod)
+			spec.Limits.CPU = &cpu
+		}
+		if hc.Memory > 0 {
+			memory := hc.Memory
+			spec.Limits.Memory = &memory
+		}
+	}
+
+	return spec
+}
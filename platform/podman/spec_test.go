@@ -0,0 +1,70 @@
+package podman
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	mackerel "github.com/mackerelio/mackerel-client-go"
+
+	agentSpec "github.com/mackerelio/mackerel-container-agent/spec"
+)
+
+func TestGenerateSpec(t *testing.T) {
+	client := newMockClient(t)
+	generator := newSpecGenerator(client)
+
+	got, err := generator.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate() should not raise error: %v", err)
+	}
+
+	webCPU := 0.5
+	agentCPU := 0.25
+	memory := int64(268435456)
+	created := time.Date(2026, time.October, 1, 0, 0, 0, 123456789, time.UTC)
+	webStartedAt := time.Date(2026, time.October, 1, 0, 0, 1, 123456789, time.UTC)
+	agentStartedAt := time.Date(2026, time.October, 1, 0, 0, 2, 123456789, time.UTC)
+	expected := &agentSpec.CloudHostname{
+		Cloud: &mackerel.Cloud{
+			Provider: "podman",
+			MetaData: &podSpec{
+				ID:       "b7c9e2f4a6d8c0e2f4a6b8d0c2e4f6a8b0d2c4e6f8a0b2d4c6e8f0a2b4d6c8e0",
+				Name:     "myapp",
+				State:    "Running",
+				Hostname: "myapp",
+				Labels:   map[string]string{"app": "myapp"},
+				Created:  &created,
+				Containers: []containerSpec{
+					{
+						ID:           "4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f",
+						Name:         "myapp-web",
+						Image:        "docker.io/library/nginx:1.27",
+						ImageID:      "5f1c4a1b3d2e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a",
+						Labels:       map[string]string{"app": "web"},
+						Status:       "running",
+						Health:       "healthy",
+						StartedAt:    &webStartedAt,
+						Limits:       limitSpec{CPU: &webCPU, Memory: &memory},
+						RestartCount: 1,
+					},
+					{
+						ID:        "8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b",
+						Name:      "myapp-mackerel-container-agent",
+						Image:     "docker.io/mackerel/mackerel-container-agent:latest",
+						ImageID:   "0a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b",
+						Labels:    map[string]string{},
+						Status:    "running",
+						StartedAt: &agentStartedAt,
+						Limits:    limitSpec{CPU: &agentCPU},
+					},
+				},
+			},
+		},
+		Hostname: "myapp",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Generate() expected %#v, got %#v", expected, got)
+	}
+}
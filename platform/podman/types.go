@@ -0,0 +1,31 @@
+package podman
+
+import "time"
+
+type podSpec struct {
+	ID         string            `json:"id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	State      string            `json:"state,omitempty"`
+	Hostname   string            `json:"hostname,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Created    *time.Time        `json:"created,omitempty"`
+	Containers []containerSpec   `json:"containers,omitempty"`
+}
+
+type containerSpec struct {
+	ID           string            `json:"id,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Image        string            `json:"image,omitempty"`
+	ImageID      string            `json:"image_id,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Status       string            `json:"status,omitempty"`
+	Health       string            `json:"health,omitempty"`
+	StartedAt    *time.Time        `json:"started_at,omitempty"`
+	Limits       limitSpec         `json:"limits"`
+	RestartCount int32             `json:"restart_count,omitempty"`
+}
+
+type limitSpec struct {
+	CPU    *float64 `json:"cpu,omitempty"`
+	Memory *int64   `json:"memory,omitempty"`
+}
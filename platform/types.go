@@ -11,6 +11,10 @@ const (
 	Fargate      Type = "fargate"
 	Kubernetes   Type = "kubernetes"
 	EKSOnFargate Type = "eks_fargate"
+	Docker       Type = "docker"
+	Podman       Type = "podman"
+	Cgroup       Type = "cgroup"
+	Nomad        Type = "nomad"
 	None         Type = "none"
 	// experimental
 	ECSAnywhere Type = "ecs_anywhere"
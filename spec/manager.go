@@ -53,6 +53,9 @@ loop:
 			break loop
 		case <-time.After(d):
 			err := m.collectAndPost(ctx)
+			if api.IsPermanent(err) {
+				return err
+			}
 			if err != nil {
 				// do not break the loop with spec posting error
 				logger.Warningf("failed to update host spec: %s", err)